@@ -0,0 +1,96 @@
+package vecty
+
+import "reflect"
+
+// provision is a value provided by a component to its descendants.
+type provision struct {
+	value interface{}
+	// consumers are the descendants which have consumed the value.
+	consumers map[Component]struct{}
+}
+
+// Provide provides a value for the given key to all descendants of the
+// component, which may read it using Consume. This allows data such as a
+// theme, the current user or the locale to be made available to deep
+// descendants without passing it through the properties of every component in
+// between.
+//
+// As with context.WithValue, the key should be of an unexported type defined in
+// the package providing the value, to avoid collisions.
+//
+// Provide is typically called from the component's Render method, but may be
+// called at any time. When the provided value changes, every descendant which
+// consumed it is re-rendered, even if e.g. the components in between skip
+// rendering. Values of types which are not comparable are always considered
+// changed.
+func (c *Core) Provide(key, value interface{}) {
+	if serverProvide(c, key, value) {
+		return
+	}
+	p, ok := c.provisions[key]
+	if !ok {
+		if c.provisions == nil {
+			c.provisions = make(map[interface{}]*provision)
+		}
+		c.provisions[key] = &provision{value: value, consumers: make(map[Component]struct{})}
+		return
+	}
+	if sameValue(p.value, value) {
+		return
+	}
+	p.value = value
+	for consumer := range p.consumers {
+		if consumer.Context().unmounted {
+			delete(p.consumers, consumer)
+			continue
+		}
+		if consumer.Context().prevRender == nil {
+			// The consumer has not finished rendering, e.g. because it is
+			// being rendered to a string, or rendered for the first time.
+			continue
+		}
+		Rerender(consumer)
+	}
+}
+
+// Consume returns the value provided for the given key by the nearest ancestor
+// of the component which called Provide with the key, or nil if there is none.
+//
+// When called from the component's Render method, the component subscribes to
+// the value and is re-rendered whenever the ancestor provides a different
+// value.
+func (c *Core) Consume(key interface{}) interface{} {
+	if value, ok := serverConsume(c, key); ok {
+		return value
+	}
+	for ancestor := c.parent; ancestor != nil; ancestor = ancestor.Context().parent {
+		if p, ok := ancestor.Context().provisions[key]; ok {
+			if c.component != nil {
+				p.consumers[c.component] = struct{}{}
+			}
+			return p.value
+		}
+	}
+	return nil
+}
+
+// sameValue reports whether the provided values a and b are equal. Values which
+// are not comparable are never equal, including values of comparable types
+// which contain incomparable values, such as a struct with an interface{}
+// field holding a slice.
+func sameValue(a, b interface{}) (equal bool) {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb || !ta.Comparable() {
+		return false
+	}
+	// Comparing incomparable values held by interfaces within a and b panics.
+	defer func() {
+		if recover() != nil {
+			equal = false
+		}
+	}()
+	return a == b
+}
@@ -0,0 +1,101 @@
+// +build !js
+
+package vecty
+
+import (
+	"reflect"
+	"testing"
+)
+
+// themeKey is the key of a provided value.
+type themeKey struct{}
+
+// TestContext tests that values provided by an ancestor are consumed
+// by descendants, and that consumers are re-rendered when the value changes,
+// even if the components in between skip rendering.
+func TestContext(t *testing.T) {
+	theme := "light"
+	renders := 0
+	consumer := &componentFunc{}
+	consumer.render = func() ComponentOrHTML {
+		renders++
+		v, _ := consumer.Consume(themeKey{}).(string)
+		return Tag("p", Text(v))
+	}
+	inner := &componentFunc{}
+	inner.render = func() ComponentOrHTML {
+		inner.Provide(themeKey{}, "inner")
+		return Tag("section", &componentFunc{render: func() ComponentOrHTML {
+			return Text(consumer.Consume(themeKey{}).(string))
+		}})
+	}
+	middle := &componentFunc{
+		render:     func() ComponentOrHTML { return Tag("div", consumer, inner) },
+		skipRender: func(prev Component) bool { return true },
+	}
+	root := &componentFunc{}
+	root.render = func() ComponentOrHTML {
+		root.Provide(themeKey{}, theme)
+		return Tag("body", middle)
+	}
+	renderBody(root)
+	checkHTML(t, memBody(), `<div><p>light</p><section>light</section></div>`)
+
+	// The consumer is re-rendered despite middle skipping rendering.
+	theme = "dark"
+	Rerender(root)
+	RunAnimationFrames()
+	RunAnimationFrames()
+	checkHTML(t, memBody(), `<div><p>dark</p><section>light</section></div>`)
+	if renders != 2 {
+		t.Fatalf("got %d renders, want 2", renders)
+	}
+
+	// Providing an equal value does not re-render consumers.
+	Rerender(root)
+	RunAnimationFrames()
+	RunAnimationFrames()
+	if renders != 2 {
+		t.Fatalf("got %d renders, want 2", renders)
+	}
+
+	if v := root.Consume(themeKey{}); v != nil {
+		t.Fatalf("got %v, want nil value without a provider", v)
+	}
+}
+
+// TestSameValue tests that values are equal only if they are comparable, and
+// that comparing values of comparable types which hold incomparable values
+// does not panic.
+func TestSameValue(t *testing.T) {
+	type holder struct{ v interface{} }
+	tests := []struct {
+		name string
+		a, b interface{}
+		want bool
+	}{
+		{name: "nil", a: nil, b: nil, want: true},
+		{name: "nil_value", a: nil, b: 0, want: false},
+		{name: "equal", a: "a", b: "a", want: true},
+		{name: "unequal", a: "a", b: "b", want: false},
+		{name: "different_types", a: 1, b: int64(1), want: false},
+		{name: "incomparable_type", a: []int{1}, b: []int{1}, want: false},
+		{name: "comparable_fields", a: holder{1}, b: holder{1}, want: true},
+		{name: "incomparable_fields", a: holder{[]int{1}}, b: holder{[]int{1}}, want: false},
+		{name: "mixed_fields", a: holder{1}, b: holder{[]int{1}}, want: false},
+	}
+	for _, tst := range tests {
+		t.Run(tst.name, func(t *testing.T) {
+			if got := sameValue(tst.a, tst.b); got != tst.want {
+				t.Fatalf("sameValue(%#v, %#v) = %v want %v", tst.a, tst.b, got, tst.want)
+			}
+			if tst.a == nil || tst.b == nil || reflect.TypeOf(tst.a) != reflect.TypeOf(tst.b) {
+				return
+			}
+			// Memo compares properties of the same type using propEqual.
+			if got := propEqual(reflect.ValueOf(tst.a), reflect.ValueOf(tst.b)); got != tst.want {
+				t.Fatalf("propEqual(%#v, %#v) = %v want %v", tst.a, tst.b, got, tst.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+// Package css defines scoped style rules in Go, which are applied to elements
+// through generated class names:
+//
+// 	var button = css.New(
+// 		css.Decl("color", "white"),
+// 		css.Decl("background", "steelblue"),
+// 		css.Hover(css.Decl("background", "navy")),
+// 		css.Media("(max-width: 600px)", css.Decl("width", "100%")),
+// 	)
+//
+// 	func (b *Button) Render() vecty.ComponentOrHTML {
+// 		return elem.Button(vecty.Markup(button), vecty.Text(b.Label))
+// 	}
+//
+// Class names are derived from the rules, such that they do not collide with
+// each other nor with hand-written class names, and are identical when the
+// same program renders on a server and in the browser. In the browser, the
+// rules of a class are added to the document (see vecty.AddStyles) when its
+// name is first used, and only once.
+//
+// When rendering on a server with vecty.RenderToString, the rules used by the
+// rendered HTML are returned by Text, or as a <style> element for the page's
+// head by HTML, which are then adopted by the browser instead of being added
+// again:
+//
+// 	body, err := vecty.RenderToString(page)
+// 	if err != nil {
+// 		return err
+// 	}
+// 	fmt.Fprintf(w, "<html><head>%s</head>%s</html>", css.HTML(body), body)
+//
+package css
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hexops/vecty"
+)
+
+// Style is part of a style rule: a Declaration, or a nested rule created by
+// e.g. Pseudo or Media.
+type Style interface {
+	collect(r *rules, media []string, selector string)
+}
+
+// Declaration is a CSS declaration, e.g. "color: red".
+type Declaration struct {
+	Property, Value string
+}
+
+// Decl returns the declaration of the given CSS property.
+func Decl(property, value string) Declaration {
+	return Declaration{Property: property, Value: value}
+}
+
+func (d Declaration) collect(r *rules, media []string, selector string) {
+	r.declare(media, selector, d)
+}
+
+// nested is a nested rule, which applies its styles with an additional
+// selector or media query.
+type nested struct {
+	selector, media string
+	styles          []Style
+}
+
+func (n nested) collect(r *rules, media []string, selector string) {
+	if n.media != "" {
+		media = append(media[:len(media):len(media)], n.media)
+	}
+	selector += n.selector
+	for _, s := range n.styles {
+		s.collect(r, media, selector)
+	}
+}
+
+// Pseudo returns styles which apply to the element when it matches the
+// pseudo-class, or to the pseudo-element, e.g. ":first-child" or "::before".
+// The selector is appended to that of the enclosing rule, and so may also be
+// e.g. ":not(:last-child)" or " > li".
+func Pseudo(selector string, styles ...Style) Style {
+	return nested{selector: selector, styles: styles}
+}
+
+// Hover returns styles which apply while the pointer is over the element.
+func Hover(styles ...Style) Style {
+	return Pseudo(":hover", styles...)
+}
+
+// Focus returns styles which apply while the element has focus.
+func Focus(styles ...Style) Style {
+	return Pseudo(":focus", styles...)
+}
+
+// Active returns styles which apply while the element is being activated,
+// e.g. pressed.
+func Active(styles ...Style) Style {
+	return Pseudo(":active", styles...)
+}
+
+// Media returns styles which apply when the media query matches, e.g.
+// "(max-width: 600px)".
+func Media(query string, styles ...Style) Style {
+	return nested{media: query, styles: styles}
+}
+
+// rules collects the blocks of declarations of a class.
+type rules struct {
+	blocks []block
+}
+
+// block is the declarations of a single selector within nested media queries.
+type block struct {
+	media    []string
+	selector string
+	decls    []Declaration
+}
+
+// declare adds declarations to the block of the given media queries and
+// selector, adding the block if it is not the last one.
+func (r *rules) declare(media []string, selector string, decls ...Declaration) {
+	if n := len(r.blocks); n > 0 {
+		if b := &r.blocks[n-1]; b.selector == selector && strings.Join(b.media, "\x00") == strings.Join(media, "\x00") {
+			b.decls = append(b.decls, decls...)
+			return
+		}
+	}
+	r.blocks = append(r.blocks, block{media: media, selector: selector, decls: decls})
+}
+
+// placeholder stands for the class selector in the CSS of a class, before its
+// name is known.
+const placeholder = "\x00"
+
+// text returns the CSS of the collected rules.
+func (r *rules) text() string {
+	var b strings.Builder
+	for _, block := range r.blocks {
+		if len(block.decls) == 0 {
+			continue
+		}
+		for _, m := range block.media {
+			b.WriteString("@media " + m + "{")
+		}
+		b.WriteString("." + placeholder + block.selector)
+		writeDecls(&b, block.decls)
+		b.WriteString(strings.Repeat("}", len(block.media)))
+	}
+	return b.String()
+}
+
+// writeDecls writes a declaration block.
+func writeDecls(b *strings.Builder, decls []Declaration) {
+	b.WriteByte('{')
+	for i, d := range decls {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(d.Property + ":" + d.Value)
+	}
+	b.WriteByte('}')
+}
+
+// Class is a scoped class, whose style rules apply to the elements it is
+// applied to.
+type Class struct {
+	name, css string
+}
+
+// New returns a class with the given styles.
+func New(styles ...Style) *Class {
+	r := &rules{}
+	for _, s := range styles {
+		s.collect(r, nil, "")
+	}
+	css := r.text()
+	name := register("vc-", css)
+	return &Class{name: name, css: strings.Replace(css, placeholder, name, -1)}
+}
+
+// Name returns the generated name of the class, e.g. for use with vecty.Class
+// or in the selector of other CSS. In the browser, its rules are added to the
+// document, if they have not been already.
+func (c *Class) Name() string {
+	use(c.css)
+	return c.name
+}
+
+// Apply implements the vecty.Applyer interface, applying the class to an
+// element.
+func (c *Class) Apply(h *vecty.HTML) {
+	vecty.Class(c.Name()).Apply(h)
+}
+
+// Frame is a keyframe of an Animation.
+type Frame struct {
+	selector string
+	decls    []Declaration
+}
+
+// At returns the keyframe with the given selector, e.g. "from", "to", or "50%".
+func At(selector string, decls ...Declaration) Frame {
+	return Frame{selector: selector, decls: decls}
+}
+
+// Animation is a scoped set of keyframes.
+type Animation struct {
+	name, css string
+}
+
+// Keyframes returns an animation with the given keyframes.
+func Keyframes(frames ...Frame) *Animation {
+	var b strings.Builder
+	b.WriteString("@keyframes " + placeholder + "{")
+	for _, f := range frames {
+		b.WriteString(f.selector)
+		writeDecls(&b, f.decls)
+	}
+	b.WriteByte('}')
+	css := b.String()
+	name := register("vk-", css)
+	return &Animation{name: name, css: strings.Replace(css, placeholder, name, -1)}
+}
+
+// Name returns the generated name of the animation, for use in the animation
+// or animation-name properties. In the browser, its keyframes are added to the
+// document, if they have not been already.
+func (a *Animation) Name() string {
+	use(a.css)
+	return a.name
+}
+
+var (
+	// mu guards names, since server-side rendering may happen on many
+	// goroutines.
+	mu sync.Mutex
+	// names are the CSS (with a placeholder name) of each registered name.
+	names = make(map[string]string)
+)
+
+// register returns a name with the prefix for the CSS, derived only from its
+// hash, such that the same CSS has the same name in every program, e.g. both
+// on a server and in the browser, regardless of the order in which CSS is
+// registered. It panics if different CSS has the same hash, which with a 64-bit
+// hash is vanishingly unlikely.
+func register(prefix, css string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	name := prefix + strconv.FormatUint(hash(css), 36)
+	if prev, ok := names[name]; ok && prev != css {
+		panic("vecty: css: " + strconv.Quote(prev) + " and " + strconv.Quote(css) + " have the same hash")
+	}
+	names[name] = css
+	return name
+}
+
+// hash returns the 64-bit FNV-1a hash of s.
+func hash(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
+// Text returns the CSS of the classes and animations used by the given HTML,
+// such as that of a page rendered by vecty.RenderToString: those whose names it
+// contains, along with the animations and classes whose names their CSS
+// contains, in order of first use.
+func Text(html string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	var (
+		b    strings.Builder
+		seen = make(map[string]bool)
+		scan func(s string)
+	)
+	scan = func(s string) {
+		for _, token := range strings.FieldsFunc(s, notNameChar) {
+			css, ok := names[token]
+			if !ok || seen[token] {
+				continue
+			}
+			seen[token] = true
+			css = strings.Replace(css, placeholder, token, -1)
+			b.WriteString(css)
+			scan(css)
+		}
+	}
+	scan(html)
+	return b.String()
+}
+
+// notNameChar reports whether c cannot be part of a generated name.
+func notNameChar(c rune) bool {
+	return !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-')
+}
+
+// HTML returns a <style> element containing the CSS returned by Text for the
+// given HTML, which is adopted by vecty.AddStyles in the browser. It is
+// intended for the head of a page rendered by vecty.RenderToString.
+func HTML(html string) string {
+	// Prevent the CSS from closing the element, escaping the slash as CSS.
+	return `<style id="` + vecty.StylesElementID + `">` + strings.Replace(Text(html), "</", `<\/`, -1) + "</style>"
+}
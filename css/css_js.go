@@ -0,0 +1,12 @@
+// +build js
+
+package css
+
+import "github.com/hexops/vecty"
+
+// use adds the CSS to the document.
+func use(css string) {
+	if css != "" {
+		vecty.AddStyles(css)
+	}
+}
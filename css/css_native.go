@@ -0,0 +1,7 @@
+// +build !js
+
+package css
+
+// use does nothing under a native GOOS and GOARCH, where the CSS used by
+// rendered HTML is instead collected by Text.
+func use(css string) {}
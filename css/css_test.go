@@ -0,0 +1,135 @@
+// +build !js
+
+package css
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/vectytest"
+)
+
+func TestNew(t *testing.T) {
+	c := New(
+		Decl("color", "white"),
+		Hover(Decl("color", "navy"), Pseudo("::after", Decl("content", `"</style>"`))),
+		Media("(max-width: 600px)",
+			Decl("width", "100%"),
+			Focus(Decl("outline", "none")),
+		),
+		Decl("margin", "0"),
+	)
+	n := c.name
+	want := "." + n + "{color:white}" +
+		"." + n + ":hover{color:navy}" +
+		"." + n + `:hover::after{content:"</style>"}` +
+		"@media (max-width: 600px){." + n + "{width:100%}}" +
+		"@media (max-width: 600px){." + n + ":focus{outline:none}}" +
+		"." + n + "{margin:0}"
+	if c.css != want {
+		t.Fatalf("got  %s\nwant %s", c.css, want)
+	}
+	if c.Name() != n {
+		t.Fatal("expected Name to return the generated name")
+	}
+	if !strings.HasPrefix(n, "vc-") {
+		t.Fatalf("got name %q, want vc- prefix", n)
+	}
+	if same := New(Decl("color", "white"), Hover(Decl("color", "navy"), Pseudo("::after", Decl("content", `"</style>"`))), Media("(max-width: 600px)", Decl("width", "100%"), Focus(Decl("outline", "none"))), Decl("margin", "0")); same.name != n {
+		t.Fatalf("got name %q for identical rules, want %q", same.name, n)
+	}
+	if other := New(Decl("color", "black")); other.name == n {
+		t.Fatal("expected distinct names for distinct rules")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	// Names depend only on the CSS, not on what was registered before.
+	h := fnv.New64a()
+	h.Write([]byte("a"))
+	if got, want := register("vt-", "a"), "vt-"+strconv.FormatUint(h.Sum64(), 36); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Simulate other CSS with the same hash.
+	names[register("vt-", "b")] = "c"
+	defer func() {
+		if r := recover(); r != `vecty: css: "c" and "b" have the same hash` {
+			t.Fatalf("got panic %v", r)
+		}
+	}()
+	register("vt-", "b")
+}
+
+func TestKeyframes(t *testing.T) {
+	a := Keyframes(At("from", Decl("opacity", "0")), At("to", Decl("opacity", "1")))
+	want := "@keyframes " + a.name + "{from{opacity:0}to{opacity:1}}"
+	if a.css != want || !strings.HasPrefix(a.name, "vk-") {
+		t.Fatalf("got %s %s", a.name, a.css)
+	}
+}
+
+type heading struct {
+	vecty.Core
+}
+
+var (
+	fade  = Keyframes(At("from", Decl("opacity", "0")))
+	title = New(Decl("animation", fade.name+" 1s"), Hover(Decl("color", "red")))
+)
+
+func (h *heading) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("h1", vecty.Markup(title), vecty.Text("hi"))
+}
+
+type plain struct {
+	vecty.Core
+}
+
+func (p *plain) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("p", vecty.Markup(vecty.Class("vc-plain")), vecty.Text("x"+title.name))
+}
+
+func TestText(t *testing.T) {
+	html, err := vecty.RenderToString(&heading{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The animation is used by the CSS of the class.
+	if got, want := Text(html), title.css+fade.css; got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+	if html := HTML(html); !strings.HasPrefix(html, `<style id="vecty-styles">`) || strings.Count(html, "</") != 1 {
+		t.Fatalf("unexpected HTML %s", html)
+	}
+	unsafe := New(Decl("content", `"</style>"`))
+	if html := HTML(unsafe.Name()); !strings.Contains(html, `"<\/style>"`) {
+		t.Fatalf("unexpected HTML %s", html)
+	}
+
+	// Only the CSS used by each render is collected, and names must not be
+	// part of other words.
+	html, err = vecty.RenderToString(&plain{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Text(html); got != "" {
+		t.Fatalf("got %s, want no CSS", got)
+	}
+}
+
+func TestName(t *testing.T) {
+	h := &heading{}
+	s := vectytest.Mount(h)
+	defer s.Unmount()
+	if got, _ := s.Query("h1").Attribute("class"); got != title.name {
+		t.Fatalf("got class %q, want %q", got, title.name)
+	}
+	// Styles are only added to the document in the browser.
+	if styles := s.Node().Get("ownerDocument").Call("getElementById", vecty.StylesElementID); styles != nil {
+		t.Fatal("expected no styles to be added to the in-memory DOM")
+	}
+}
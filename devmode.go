@@ -0,0 +1,343 @@
+package vecty
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DevMode configures development mode, in which Vecty reports Diagnostics for
+// common mistakes which otherwise result in silent misbehavior or a panic that
+// is hard to trace back to its cause. Development mode makes rendering
+// considerably slower, and should not be enabled in production.
+//
+// Development mode is enabled by SetDevMode, or by building with the vectydev
+// build tag:
+//
+// 	go build -tags vectydev
+//
+type DevMode struct {
+	// SlowRender is the duration in milliseconds above which a call to a
+	// Component's Render method is reported as slow. If zero, the duration of
+	// one frame at 60Hz is used. If negative, slow renders are not reported.
+	SlowRender float64
+
+	// Report is called with each Diagnostic. A Diagnostic is reported once,
+	// even if the mistake recurs on every render. If nil, Diagnostics are
+	// logged to the browser console as warnings.
+	Report func(d Diagnostic)
+}
+
+// DiagnosticKind is the kind of mistake reported by a Diagnostic.
+type DiagnosticKind int
+
+const (
+	// UnexportedProp is reported for a component with a `vecty:"prop"` tag
+	// on an unexported field, which cannot be copied by reflection.
+	UnexportedProp DiagnosticKind = iota
+
+	// RenderMutation is reported for a component whose fields are changed by
+	// its Render method. State should instead be changed by event listeners,
+	// followed by a call to Rerender.
+	RenderMutation
+
+	// StaleRerender is reported for a call to Rerender with a component
+	// instance which is not the one persisted across renders, e.g. an instance
+	// created by a parent's Render method whose properties were copied into
+	// the persisted instance.
+	StaleRerender
+
+	// AttributeProperty is reported for an attribute set with Property, or a
+	// property set with Attribute, where the other was likely meant.
+	AttributeProperty
+
+	// SlowRender is reported for a call to a Component's Render method which
+	// took longer than DevMode.SlowRender.
+	SlowRender
+)
+
+// String returns the name of the kind.
+func (k DiagnosticKind) String() string {
+	switch k {
+	case UnexportedProp:
+		return "UnexportedProp"
+	case RenderMutation:
+		return "RenderMutation"
+	case StaleRerender:
+		return "StaleRerender"
+	case AttributeProperty:
+		return "AttributeProperty"
+	case SlowRender:
+		return "SlowRender"
+	}
+	return "DiagnosticKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Diagnostic describes a mistake detected in development mode.
+type Diagnostic struct {
+	Kind DiagnosticKind
+
+	// Component is the type of the component concerned, e.g. "main.PageView".
+	Component string
+
+	// Path is the path to the component in the component tree, from the
+	// component rendered at the root, e.g. "main.PageView > main.ItemList".
+	Path string
+
+	// Message describes the mistake.
+	Message string
+}
+
+// String returns a description of the diagnostic.
+func (d Diagnostic) String() string {
+	return "vecty: " + d.Kind.String() + ": " + d.Path + ": " + d.Message
+}
+
+// devMode is the development mode state, or nil if development mode is not
+// enabled.
+var devMode *devState
+
+// devState is the state of development mode.
+type devState struct {
+	DevMode
+	// reported are the diagnostics already reported, such that each is only
+	// reported once.
+	reported map[Diagnostic]bool
+	// checkedProps are the component types whose property tags have been
+	// checked.
+	checkedProps map[reflect.Type]bool
+}
+
+// SetDevMode enables development mode with the given configuration, see
+// DevMode. If m is nil, development mode is disabled.
+func SetDevMode(m *DevMode) {
+	if m == nil {
+		devMode = nil
+		return
+	}
+	devMode = &devState{
+		DevMode:      *m,
+		reported:     make(map[Diagnostic]bool),
+		checkedProps: make(map[reflect.Type]bool),
+	}
+	if devMode.SlowRender == 0 {
+		devMode.SlowRender = 1000.0 / 60
+	}
+}
+
+// report reports a diagnostic of the given kind for the component c, unless it
+// was reported before.
+func (s *devState) report(kind DiagnosticKind, c Component, message string) {
+	d := Diagnostic{
+		Kind:      kind,
+		Component: componentName(c),
+		Path:      componentPath(c),
+		Message:   message,
+	}
+	if s.reported[d] {
+		return
+	}
+	s.reported[d] = true
+	if s.Report != nil {
+		s.Report(d)
+		return
+	}
+	if console := global().Get("console"); console != nil && console.Truthy() {
+		console.Call("warn", d.String())
+	}
+}
+
+// callRender calls the component's Render method, checking it for mistakes in
+// development mode.
+func callRender(c Component) ComponentOrHTML {
+	if devMode != nil {
+		return devMode.render(c)
+	}
+	return c.Render()
+}
+
+// componentName returns the name of the component's type, without the pointer.
+func componentName(c Component) string {
+	t := reflect.TypeOf(c)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// componentPath returns the path to the component in the component tree, as
+// of the current render if it is being rendered, or its last render otherwise.
+func componentPath(c Component) string {
+	var chain []Component
+	if len(rendering) > 0 {
+		chain = append(chain, rendering...)
+	}
+	if len(chain) == 0 || chain[len(chain)-1] != c {
+		chain = append(chain, c)
+	}
+	for p := chain[0].Context().parent; p != nil; p = p.Context().parent {
+		chain = append([]Component{p}, chain...)
+	}
+	names := make([]string, len(chain))
+	for i, c := range chain {
+		names[i] = componentName(c)
+	}
+	return strings.Join(names, " > ")
+}
+
+// checkProps reports `vecty:"prop"` tags on unexported fields of the
+// component, once per component type.
+func (s *devState) checkProps(c Component) {
+	t := reflect.TypeOf(c)
+	if s.checkedProps[t] || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return
+	}
+	s.checkedProps[t] = true
+	for i := 0; i < t.Elem().NumField(); i++ {
+		f := t.Elem().Field(i)
+		if prop, _ := propTag(f.Tag); prop && f.PkgPath != "" {
+			s.report(UnexportedProp, c, "field "+f.Name+" is tagged as a property, but is unexported and so cannot be copied")
+		}
+	}
+}
+
+// render calls the component's Render method, reporting the mistakes it can
+// make.
+func (s *devState) render(c Component) ComponentOrHTML {
+	v := reflect.ValueOf(c)
+	var before reflect.Value
+	if v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		before = reflect.New(v.Elem().Type()).Elem()
+		before.Set(v.Elem())
+	}
+	start := now()
+	r := c.Render()
+	if d := now() - start; s.SlowRender > 0 && d > s.SlowRender {
+		s.report(SlowRender, c, "Render took "+formatMillis(d)+", longer than "+formatMillis(s.SlowRender))
+	}
+	if before.IsValid() {
+		after := v.Elem()
+		for i := 0; i < after.NumField(); i++ {
+			if after.Type().Field(i).Type == reflect.TypeOf(Core{}) {
+				continue
+			}
+			if !shallowEqual(before.Field(i), after.Field(i)) {
+				s.report(RenderMutation, c, "field "+after.Type().Field(i).Name+" was changed by Render")
+			}
+		}
+	}
+	return r
+}
+
+// shallowEqual reports whether a and b, of the same type, are identical
+// without following pointers, such that e.g. two slices are equal if they
+// share their backing array and length. Unlike ==, it supports values of
+// unexported fields.
+func shallowEqual(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float() || math.IsNaN(a.Float()) && math.IsNaN(b.Float())
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	case reflect.Slice:
+		return a.Pointer() == b.Pointer() && a.Len() == b.Len()
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return a.Elem().Type() == b.Elem().Type() && shallowEqual(a.Elem(), b.Elem())
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !shallowEqual(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !shallowEqual(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// checkRerender reports a call to Rerender with a component instance which is
+// not the one persisted across renders.
+func (s *devState) checkRerender(c Component) {
+	switch {
+	case c.Context().prevRender == nil:
+		s.report(StaleRerender, c, "Rerender called with a component which was never rendered; if it was created by its parent's Render method, its properties were copied into the instance created by the parent's first render, which should be used instead")
+	case c.Context().component != c:
+		s.report(StaleRerender, c, "Rerender called with a copy of the component, rather than the instance which was rendered")
+	}
+}
+
+// formatMillis formats a duration in milliseconds.
+func formatMillis(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', 1, 64) + "ms"
+}
+
+// attributesNotProperties are attributes which are commonly set with Property, but
+// are not reflected by a property of the same name, with hints for the fix.
+var attributesNotProperties = map[string]string{
+	"class": `use Class, or Property("className")`,
+	"for":   `use Property("htmlFor")`,
+}
+
+// initialValueAttributes are properties which are commonly set with Attribute,
+// by the elements on which the attribute only provides the initial value.
+var initialValueAttributes = map[string][]string{
+	"value":    {"input", "select", "textarea"},
+	"checked":  {"input"},
+	"selected": {"option"},
+	"muted":    {"audio", "video"},
+}
+
+// propertiesNotAttributes are properties which are commonly set with Attribute, but
+// have no attribute of the same name.
+var propertiesNotAttributes = map[string]bool{
+	"className":   true,
+	"htmlFor":     true,
+	"innerHTML":   true,
+	"textContent": true,
+}
+
+// checkMarkup reports attributes set with Property, and properties set with
+// Attribute, on the element h.
+func (s *devState) checkMarkup(h *HTML) {
+	if len(rendering) == 0 {
+		return
+	}
+	c := rendering[len(rendering)-1]
+	for name := range h.properties {
+		if hint, ok := attributesNotProperties[name]; ok {
+			s.report(AttributeProperty, c, "<"+h.tag+"> property "+strconv.Quote(name)+" does not exist: "+hint)
+		} else if strings.Contains(name, "-") {
+			s.report(AttributeProperty, c, "<"+h.tag+"> property "+strconv.Quote(name)+" does not exist: use Attribute("+strconv.Quote(name)+")")
+		}
+	}
+	for name := range h.attributes {
+		for _, tag := range initialValueAttributes[name] {
+			if h.tag == tag {
+				s.report(AttributeProperty, c, "<"+h.tag+"> attribute "+strconv.Quote(name)+" only sets the initial value: use Property("+strconv.Quote(name)+")")
+			}
+		}
+		if propertiesNotAttributes[name] {
+			s.report(AttributeProperty, c, "<"+h.tag+"> attribute "+strconv.Quote(name)+" does not exist: use Property("+strconv.Quote(name)+")")
+		}
+	}
+}
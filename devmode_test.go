@@ -0,0 +1,76 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// devComponent makes the mistakes reported in development mode.
+type devComponent struct {
+	Core
+	label   string `vecty:"prop"`
+	renders int
+	slow    bool
+}
+
+func (c *devComponent) Render() ComponentOrHTML {
+	c.renders++
+	if c.slow {
+		time.Sleep(2 * time.Millisecond)
+	}
+	return Tag("div",
+		Tag("input", Markup(Attribute("value", "a"), Property("class", "b"))),
+		Tag("svg", Markup(Attribute("viewBox", "0 0 1 1"), Property("aria-label", "c"))),
+	)
+}
+
+// TestDevMode tests the diagnostics reported in development mode.
+func TestDevMode(t *testing.T) {
+	ResetDOM()
+	var got []string
+	SetDevMode(&DevMode{SlowRender: 1, Report: func(d Diagnostic) {
+		got = append(got, d.String())
+	}})
+	defer SetDevMode(nil)
+
+	child := &devComponent{slow: true}
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", child)
+	}})
+	path := "vecty: %s: vecty.componentFunc > vecty.devComponent: "
+	want := []string{
+		fmt.Sprintf(path, "UnexportedProp") + "field label is tagged as a property, but is unexported and so cannot be copied",
+		fmt.Sprintf(path, "SlowRender") + "Render took ",
+		fmt.Sprintf(path, "RenderMutation") + "field renders was changed by Render",
+		fmt.Sprintf(path, "AttributeProperty") + `<input> property "class" does not exist: use Class, or Property("className")`,
+		fmt.Sprintf(path, "AttributeProperty") + `<input> attribute "value" only sets the initial value: use Property("value")`,
+		fmt.Sprintf(path, "AttributeProperty") + `<svg> property "aria-label" does not exist: use Attribute("aria-label")`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] && !(i == 1 && len(got[i]) > len(want[i]) && got[i][:len(want[i])] == want[i]) {
+			t.Fatalf("got %q\nwant %q", got[i], want[i])
+		}
+	}
+
+	// Diagnostics are reported once.
+	got = nil
+	child.slow = false
+	RerenderSync(child)
+	if len(got) != 0 {
+		t.Fatalf("got %q, want none", got)
+	}
+
+	// Rerender with a copy of the component.
+	cpy := *child
+	Rerender(&cpy)
+	want = []string{"vecty: StaleRerender: vecty.componentFunc > vecty.devComponent: Rerender called with a copy of the component, rather than the instance which was rendered"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,9 @@
+// +build vectydev
+
+package vecty
+
+// Building with the vectydev build tag enables development mode, with the
+// default configuration.
+func init() {
+	SetDevMode(&DevMode{})
+}
@@ -0,0 +1,346 @@
+package vecty
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// devtoolsHook is the name of the global variable holding the devtools hook.
+const devtoolsHook = "__VECTY_DEVTOOLS_HOOK__"
+
+// InstallDevtoolsHook installs a bridge for inspecting the live component tree
+// from outside of Go, such as from an inspector page or a browser extension,
+// as the global variable __VECTY_DEVTOOLS_HOOK__. If the variable already
+// holds an object (e.g. defined by an extension before the program started),
+// the bridge is installed into it.
+//
+// The hook provides the following methods:
+//
+// 	tree()   // returns the tree of each mounted root, as a JSON string
+// 	node(id) // returns the first DOM node rendered by the component with the ID
+//
+// The tree consists of component objects, of the form:
+//
+// 	{"id": 1, "type": "main.PageView", "key": ..., "props": {"Name": ...}, "children": [...]}
+//
+// element objects, of the form:
+//
+// 	{"tag": "div", "key": ..., "attributes": {...}, "properties": {...}, "classes": [...], "children": [...]}
+//
+// and text objects, of the form {"text": "..."}. Portals are element objects
+// with "portal": true, whose children are rendered into another DOM node.
+//
+// If the hook has an onEvent method, it is called with a JSON string for each
+// event, of the form {"type": "mount", "id": 1, "component": "main.PageView"}.
+// The event types are "mount" and "render", once a component's first and
+// subsequent renders have been applied to the DOM, and "unmount".
+//
+// Component IDs are assigned when components are first rendered, and are not
+// reused. The hook is intended for development, and makes rendering slower.
+func InstallDevtoolsHook() {
+	hook := global().Get(devtoolsHook)
+	if hook == nil || !hook.Truthy() {
+		global().Set(devtoolsHook, map[string]interface{}{})
+		hook = global().Get(devtoolsHook)
+	}
+	d := &devtoolsBridge{
+		hook:       hook,
+		ids:        make(map[Component]int),
+		components: make(map[int]Component),
+	}
+	hook.Set("tree", funcOf(func(this jsObject, args []jsObject) interface{} {
+		return string(d.tree())
+	}))
+	hook.Set("node", funcOf(func(this jsObject, args []jsObject) interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		if c, ok := d.components[args[0].Int()]; ok {
+			if node := firstNode(c); node != nil {
+				return node
+			}
+		}
+		return nil
+	}))
+	devtools = d
+}
+
+// devtools is the installed devtools bridge, or nil if there is none.
+var devtools *devtoolsBridge
+
+// devtoolsBridge implements the devtools hook.
+type devtoolsBridge struct {
+	hook jsObject
+	// ids are the IDs of live components, and components the reverse.
+	ids        map[Component]int
+	components map[int]Component
+	nextID     int
+}
+
+// id returns the ID of the component, assigning it one if necessary.
+func (d *devtoolsBridge) id(c Component) int {
+	id, ok := d.ids[c]
+	if !ok {
+		d.nextID++
+		id = d.nextID
+		d.ids[c] = id
+		d.components[id] = c
+	}
+	return id
+}
+
+// rendered returns a pending mount which emits a mount or render event for
+// the component, once its render has been applied to the DOM.
+func (d *devtoolsBridge) rendered(c Component, update bool) Mounter {
+	typ := "mount"
+	if update {
+		typ = "render"
+	}
+	return devtoolsEvent{d: d, typ: typ, c: c}
+}
+
+// unmounted emits an unmount event for the component, and forgets its ID.
+func (d *devtoolsBridge) unmounted(c Component) {
+	id, ok := d.ids[c]
+	if !ok {
+		return
+	}
+	d.emit("unmount", id, c)
+	delete(d.ids, c)
+	delete(d.components, id)
+}
+
+// emit calls the hook's onEvent method, if any, with the event.
+func (d *devtoolsBridge) emit(typ string, id int, c Component) {
+	if !d.hook.Get("onEvent").Truthy() {
+		return
+	}
+	b := []byte(`{"type":`)
+	b = appendJSONString(b, typ)
+	b = append(b, `,"id":`...)
+	b = strconv.AppendInt(b, int64(id), 10)
+	b = append(b, `,"component":`...)
+	b = appendJSONString(b, componentName(c))
+	b = append(b, '}')
+	d.hook.Call("onEvent", string(b))
+}
+
+// devtoolsEvent emits a devtools event as a pending mount.
+type devtoolsEvent struct {
+	d   *devtoolsBridge
+	typ string
+	c   Component
+}
+
+// Mount implements the Mounter interface.
+func (e devtoolsEvent) Mount() {
+	if e.c.Context().unmounted {
+		return
+	}
+	e.d.emit(e.typ, e.d.id(e.c), e.c)
+}
+
+// tree returns the JSON serialization of the mounted roots.
+func (d *devtoolsBridge) tree() []byte {
+	b := []byte{'['}
+	for i, r := range roots {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = d.appendNode(b, r.component)
+	}
+	return append(b, ']')
+}
+
+// appendNode appends the JSON serialization of e to b.
+func (d *devtoolsBridge) appendNode(b []byte, e ComponentOrHTML) []byte {
+	switch v := e.(type) {
+	case Component:
+		b = append(b, `{"id":`...)
+		b = strconv.AppendInt(b, int64(d.id(v)), 10)
+		b = append(b, `,"type":`...)
+		b = appendJSONString(b, componentName(v))
+		if k, ok := v.(Keyer); ok {
+			b = append(b, `,"key":`...)
+			b = appendJSONValue(b, reflect.ValueOf(k.Key()), 0)
+		}
+		b = append(b, `,"props":{`...)
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Struct {
+			t := rv.Elem().Type()
+			n := 0
+			for i := 0; i < t.NumField(); i++ {
+				if prop, _ := propTag(t.Field(i).Tag); !prop {
+					continue
+				}
+				if n > 0 {
+					b = append(b, ',')
+				}
+				n++
+				b = appendJSONString(b, t.Field(i).Name)
+				b = append(b, ':')
+				b = appendJSONValue(b, rv.Elem().Field(i), 0)
+			}
+		}
+		b = append(b, `},"children":[`...)
+		if r := v.Context().prevRender; r != nil {
+			b = d.appendChildren(b, []ComponentOrHTML{r})
+		}
+		return append(b, "]}"...)
+	case KeyedList:
+		// Fragments are flattened into their parent's children.
+		return d.appendChildren(b, v.html.children)
+	case *HTML:
+		if v == nil {
+			return append(b, "null"...)
+		}
+		if v.tag == "" && v.portal == nil {
+			b = append(b, `{"text":`...)
+			b = appendJSONString(b, v.text)
+			return append(b, '}')
+		}
+		b = append(b, `{"tag":`...)
+		b = appendJSONString(b, v.tag)
+		if v.key != nil {
+			b = append(b, `,"key":`...)
+			b = appendJSONValue(b, reflect.ValueOf(v.key), 0)
+		}
+		b = append(b, `,"attributes":`...)
+		b = appendJSONValue(b, reflect.ValueOf(v.attributes), 0)
+		b = append(b, `,"properties":`...)
+		b = appendJSONValue(b, reflect.ValueOf(v.properties), 0)
+		b = append(b, `,"classes":`...)
+		b = appendJSONValue(b, reflect.ValueOf(v.Classes()), 0)
+		children := v.children
+		if v.portal != nil {
+			b = append(b, `,"portal":true`...)
+			children = []ComponentOrHTML{v.portal.content}
+		}
+		b = append(b, `,"children":[`...)
+		b = d.appendChildren(b, children)
+		return append(b, "]}"...)
+	}
+	return append(b, "null"...)
+}
+
+// appendChildren appends the comma separated JSON serializations of the
+// children to b, flattening fragments.
+func (d *devtoolsBridge) appendChildren(b []byte, children []ComponentOrHTML) []byte {
+	n := len(b)
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		if len(b) > n && b[len(b)-1] != ',' {
+			b = append(b, ',')
+		}
+		b = d.appendNode(b, child)
+	}
+	if len(b) > n && b[len(b)-1] == ',' {
+		b = b[:len(b)-1]
+	}
+	return b
+}
+
+// maxJSONDepth is the depth to which property values are serialized.
+const maxJSONDepth = 4
+
+// appendJSONValue appends a JSON representation of the value to b. Values
+// which have no JSON representation, such as functions, are represented by a
+// description of their type.
+func appendJSONValue(b []byte, v reflect.Value, depth int) []byte {
+	if !v.IsValid() {
+		return append(b, "null"...)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(b, v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(b, v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(b, v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return strconv.AppendFloat(b, f, 'g', -1, 64)
+		}
+	case reflect.String:
+		return appendJSONString(b, v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return append(b, "null"...)
+		}
+		if depth < maxJSONDepth {
+			return appendJSONValue(b, v.Elem(), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return append(b, "null"...)
+		}
+		if depth < maxJSONDepth {
+			b = append(b, '[')
+			for i := 0; i < v.Len(); i++ {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = appendJSONValue(b, v.Index(i), depth+1)
+			}
+			return append(b, ']')
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, "null"...)
+		}
+		if depth < maxJSONDepth && v.Type().Key().Kind() == reflect.String {
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+			b = append(b, '{')
+			for i, k := range keys {
+				if i > 0 {
+					b = append(b, ',')
+				}
+				b = appendJSONString(b, k.String())
+				b = append(b, ':')
+				b = appendJSONValue(b, v.MapIndex(k), depth+1)
+			}
+			return append(b, '}')
+		}
+	case reflect.Struct:
+		if depth < maxJSONDepth {
+			b = append(b, '{')
+			n := 0
+			for i := 0; i < v.NumField(); i++ {
+				if v.Type().Field(i).PkgPath != "" {
+					continue
+				}
+				if n > 0 {
+					b = append(b, ',')
+				}
+				n++
+				b = appendJSONString(b, v.Type().Field(i).Name)
+				b = append(b, ':')
+				b = appendJSONValue(b, v.Field(i), depth+1)
+			}
+			return append(b, '}')
+		}
+	}
+	return appendJSONString(b, "<"+v.Type().String()+">")
+}
+
+// appendJSONString appends s to b as a JSON string.
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
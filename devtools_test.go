@@ -0,0 +1,65 @@
+// +build !js
+
+package vecty
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// TestDevtools tests that the devtools hook serializes the component
+// tree, and emits events.
+func TestDevtools(t *testing.T) {
+	ResetDOM()
+	InstallDevtoolsHook()
+	hook := global().Get("__VECTY_DEVTOOLS_HOOK__")
+	var events []string
+	hook.Set("onEvent", funcOf(func(this jsObject, args []jsObject) interface{} {
+		events = append(events, args[0].String())
+		return nil
+	}))
+
+	show := true
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		var child MarkupOrChild
+		if show {
+			child = &memoComponent{Name: "a\"b", Tags: tagList{"x"}}
+		}
+		return Tag("body", Markup(Class("app"), Attribute("lang", "en")), child, Text("t"))
+	}}
+	RenderBody(comp)
+	RerenderSync(comp)
+	show = false
+	RerenderSync(comp)
+
+	want := []string{
+		`{"type":"mount","id":1,"component":"vecty.memoComponent"}`,
+		`{"type":"mount","id":2,"component":"vecty.componentFunc"}`,
+		`{"type":"render","id":2,"component":"vecty.componentFunc"}`,
+		`{"type":"unmount","id":1,"component":"vecty.memoComponent"}`,
+		`{"type":"render","id":2,"component":"vecty.componentFunc"}`,
+	}
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Fatalf("got  %s\nwant %s", events, want)
+	}
+
+	show = true
+	RerenderSync(comp)
+	tree := hook.Call("tree").String()
+	var v interface{}
+	if err := json.Unmarshal([]byte(tree), &v); err != nil {
+		t.Fatalf("%v: %s", err, tree)
+	}
+	wantTree := `[{"id":2,"type":"vecty.componentFunc","props":{},"children":[` +
+		`{"tag":"body","attributes":{"lang":"en"},"properties":null,"classes":["app"],"children":[` +
+		`{"id":3,"type":"vecty.memoComponent","props":{"Name":"a\"b","Tags":["x"]},"children":[` +
+		`{"tag":"p","attributes":null,"properties":null,"classes":[],"children":[{"text":"a\"b"}]}]},` +
+		`{"text":"t"}]}]}]`
+	if tree != wantTree {
+		t.Fatalf("got  %s\nwant %s", tree, wantTree)
+	}
+	if !hook.Call("node", 3).Equal(global().Get("document").Call("querySelector", "p")) {
+		t.Fatal("expected node of component")
+	}
+}
@@ -4,8 +4,28 @@ import (
 	"reflect"
 )
 
-// batch renderer singleton
-var batch = &batchRenderer{idx: make(map[Component]int)}
+// batch queues the re-renders of components which were not rendered by a Root,
+// e.g. those reconciled directly in tests.
+var batch = &batchRenderer{}
+
+// handlingEvents is the number of event listeners currently being called.
+var handlingEvents int
+
+// rendering is the stack of components currently being rendered, innermost
+// last. It tracks the parent of each component, and which component panicked
+// when rendering fails.
+var rendering []Component
+
+// pushRendering pushes c onto the rendering stack, recording the component
+// below it (if any) as its parent, whose Root it is rendered by.
+func pushRendering(c Component) {
+	c.Context().component = c
+	if len(rendering) > 0 {
+		c.Context().parent = rendering[len(rendering)-1]
+		c.Context().root = c.Context().parent.Context().root
+	}
+	rendering = append(rendering, c)
+}
 
 // Core implements the Context method of the Component interface, and is the
 // core/central struct which all Component implementations should embed.
@@ -13,6 +33,15 @@ type Core struct {
 	prevRenderComponent Component
 	prevRender          ComponentOrHTML
 	mounted, unmounted  bool
+	// component is the component which embeds this Core, and parent is its
+	// nearest ancestor component, as of the last render.
+	component, parent Component
+	// root is the Root the component is rendered by, if any.
+	root *Root
+	// provisions are the values provided to descendants, by key.
+	provisions map[interface{}]*provision
+	// afterRender are the callbacks to invoke after the next render.
+	afterRender afterRenderCallbacks
 }
 
 // Context implements the Component interface.
@@ -43,6 +72,11 @@ type Component interface {
 	// If Render returns nil, the component will render as nothing (in reality,
 	// a noscript tag, which has no display or action, and is compatible with
 	// Vecty's diffing algorithm).
+	//
+	// If Render returns a List or KeyedList, the component renders as a
+	// fragment: its children are rendered directly into the parent element,
+	// without a wrapping element. A component passed to RenderBody (or
+	// similar) must render a single element, not a fragment.
 	Render() ComponentOrHTML
 
 	// Context returns the components context, which is used internally by
@@ -89,6 +123,32 @@ type Unmounter interface {
 	Unmount()
 }
 
+// BeforeUpdater is an optional interface that a Component can implement in
+// order to receive component update events before it is re-rendered.
+type BeforeUpdater interface {
+	// BeforeUpdate is called before the Render method of a mounted component
+	// is invoked again, whether it is re-rendered on its own (e.g. due to
+	// Rerender) or as part of its parent, after any properties specified by
+	// the parent have been copied into it. It is not called if rendering is
+	// skipped.
+	//
+	// prev is a copy of the component made the last time its Render method
+	// was invoked, such that changes to its properties or state can be
+	// detected. As with RenderSkipper, the previous component may be of a
+	// different type, thus a type assertion should be used.
+	BeforeUpdate(prev Component)
+}
+
+// AfterUpdater is an optional interface that a Component can implement in
+// order to receive component update events after it has been re-rendered.
+type AfterUpdater interface {
+	// AfterUpdate is called after a mounted component has been re-rendered
+	// and the resulting changes have been applied to the DOM, such that e.g.
+	// the updated DOM can be measured. It is called after the Mount and
+	// AfterUpdate methods of its descendants.
+	AfterUpdate()
+}
+
 // Keyer is an optional interface that a Component can implement in order to
 // uniquely identify the component amongst its siblings. If implemented, all
 // siblings, both components and HTML, must also be keyed.
@@ -125,6 +185,9 @@ type ComponentOrHTML interface {
 // difference between renders will be applied to the browser DOM. This
 // interface allows components to bypass calculating the difference altogether
 // and quickly state "nothing has changed, do not re-render".
+//
+// For the common case of skipping rendering when a Component's properties are
+// unchanged, see Memo.
 type RenderSkipper interface {
 	// SkipRender is called with a copy of the Component made the last time its
 	// Render method was invoked. If it returns true, rendering of the
@@ -146,6 +209,8 @@ type HTML struct {
 	styles, dataset                 map[string]string
 	properties, attributes          map[string]interface{}
 	eventListeners                  []*EventListener
+	listenersID                     int
+	refs                            []func(*HTML)
 	children                        []ComponentOrHTML
 	key                             interface{}
 	// keyedChildren stores a map of keys to children, for keyed reconciliation.
@@ -156,8 +221,27 @@ type HTML struct {
 	// lastRendered child tracks the last child that was rendered, across List
 	// boundaries.
 	lastRenderedChild *HTML
+	// portal is the content rendered into another DOM node, for HTML created
+	// by Portal.
+	portal *portal
+}
+
+// setRefs sets the refs applied to h to target, which is h or nil.
+func (h *HTML) setRefs(target *HTML) {
+	for _, ref := range h.refs {
+		ref(target)
+	}
+}
+
+// refMounter sets the refs of an element to the element once it has been
+// rendered, as a pending mount.
+type refMounter struct {
+	h *HTML
 }
 
+// Mount implements the Mounter interface.
+func (m refMounter) Mount() { m.h.setRefs(m.h) }
+
 // Key implements the Keyer interface.
 func (h *HTML) Key() interface{} {
 	return h.key
@@ -183,6 +267,7 @@ func (h *HTML) createNode() {
 	default:
 		h.node = global().Get("document").Call("createTextNode", h.text)
 	}
+	domOps++
 }
 
 // reconcileText replaces the content of a text node.
@@ -191,17 +276,34 @@ func (h *HTML) reconcileText(prev *HTML) {
 
 	// Text modifications.
 	if h.text != prev.text {
+		domOps++
 		h.node.Set("nodeValue", h.text)
 	}
 }
 
 func (h *HTML) reconcile(prev *HTML) []Mounter {
+	if guards > 0 {
+		reconciled = append(reconciled, h)
+	}
+
+	// Portal content is rendered into its target, independent of this node.
+	portalMounts := h.reconcilePortal(prev)
+
+	// Clear the refs of the previous render now, and set ours once rendering
+	// is complete, so that a ref is never cleared after being set.
+	if prev != nil {
+		prev.setRefs(nil)
+	}
+	if len(h.refs) > 0 {
+		portalMounts = append(portalMounts, refMounter{h})
+	}
+
 	// Check for compatible tag and mutate previous instance on match, otherwise start fresh
 	switch {
 	case prev != nil && h.tag == "" && prev.tag == "":
 		// Compatible text node
 		h.reconcileText(prev)
-		return nil
+		return portalMounts
 	case prev != nil && h.tag != "" && prev.tag != "" && h.tag == prev.tag && h.namespace == prev.namespace:
 		// Compatible element node
 		h.node = prev.node
@@ -215,12 +317,17 @@ func (h *HTML) reconcile(prev *HTML) []Mounter {
 
 	if !h.node.Equal(prev.node) {
 		// reconcile properties against empty prev for new nodes.
+		events.remove(prev)
 		h.reconcileProperties(&HTML{})
 	} else {
 		h.reconcileProperties(prev)
 	}
 
-	return h.reconcileChildren(prev)
+	ops, restore := domOps, h.saveEditableSelection()
+	pendingMounts := h.reconcileChildren(prev)
+	h.reconcileLiveProperties()
+	h.reconcileEditableText(ops, restore)
+	return append(pendingMounts, portalMounts...)
 }
 
 // reconcileProperties updates properties/attributes/etc to match the current
@@ -231,38 +338,18 @@ func (h *HTML) reconcileProperties(prev *HTML) {
 		h.removeProperties(prev)
 	}
 	h.tinyGoCannotIterateNilMaps()
-
-	// Wrap event listeners
-	for _, l := range h.eventListeners {
-		l := l
-		l.wrapper = funcOf(func(this jsObject, args []jsObject) interface{} {
-			jsEvent := args[0]
-			if l.callPreventDefault {
-				jsEvent.Call("preventDefault")
-			}
-			if l.callStopPropagation {
-				jsEvent.Call("stopPropagation")
-			}
-			l.Listener(&Event{
-				Value:  jsEvent.(wrappedObject).j,
-				Target: jsEvent.Get("target").(wrappedObject).j,
-			})
-			return undefined()
-		})
+	if devMode != nil {
+		devMode.checkMarkup(h)
 	}
 
-	// Properties
+	// Properties, except live properties which are reconciled against the DOM
+	// once the children have been reconciled.
 	for name, value := range h.properties {
-		var oldValue interface{}
-		switch name {
-		case "value":
-			oldValue = h.node.Get("value").String()
-		case "checked":
-			oldValue = h.node.Get("checked").Bool()
-		default:
-			oldValue = prev.properties[name]
+		if isLiveProperty(name) {
+			continue
 		}
-		if value != oldValue {
+		if value != prev.properties[name] {
+			domOps++
 			h.node.Set(name, value)
 		}
 	}
@@ -270,6 +357,7 @@ func (h *HTML) reconcileProperties(prev *HTML) {
 	// Attributes
 	for name, value := range h.attributes {
 		if value != prev.attributes[name] {
+			domOps++
 			h.node.Call("setAttribute", name, value)
 		}
 	}
@@ -278,6 +366,7 @@ func (h *HTML) reconcileProperties(prev *HTML) {
 	classList := h.node.Get("classList")
 	for name := range h.classes {
 		if _, ok := prev.classes[name]; !ok {
+			domOps++
 			classList.Call("add", name)
 		}
 	}
@@ -286,6 +375,7 @@ func (h *HTML) reconcileProperties(prev *HTML) {
 	dataset := h.node.Get("dataset")
 	for name, value := range h.dataset {
 		if value != prev.dataset[name] {
+			domOps++
 			dataset.Set(name, value)
 		}
 	}
@@ -295,17 +385,19 @@ func (h *HTML) reconcileProperties(prev *HTML) {
 	for name, value := range h.styles {
 		oldValue := prev.styles[name]
 		if value != oldValue {
+			domOps++
 			style.Call("setProperty", name, value)
 		}
 	}
 
-	// Event listeners
-	for _, l := range h.eventListeners {
-		h.node.Call("addEventListener", l.Name, l.wrapper)
-	}
+	// Event listeners are dispatched to by the render root, except those with
+	// options, which are added natively.
+	events.update(h, prev)
+	h.addNativeListeners()
 
 	// InnerHTML
 	if h.innerHTML != prev.innerHTML {
+		domOps++
 		h.node.Set("innerHTML", h.innerHTML)
 	}
 }
@@ -316,6 +408,7 @@ func (h *HTML) removeProperties(prev *HTML) {
 	// Properties
 	for name := range prev.properties {
 		if _, ok := h.properties[name]; !ok {
+			domOps++
 			h.node.Delete(name)
 		}
 	}
@@ -323,6 +416,7 @@ func (h *HTML) removeProperties(prev *HTML) {
 	// Attributes
 	for name := range prev.attributes {
 		if _, ok := h.attributes[name]; !ok {
+			domOps++
 			h.node.Call("removeAttribute", name)
 		}
 	}
@@ -331,6 +425,7 @@ func (h *HTML) removeProperties(prev *HTML) {
 	classList := h.node.Get("classList")
 	for name := range prev.classes {
 		if _, ok := h.classes[name]; !ok {
+			domOps++
 			classList.Call("remove", name)
 		}
 	}
@@ -339,6 +434,7 @@ func (h *HTML) removeProperties(prev *HTML) {
 	dataset := h.node.Get("dataset")
 	for name := range prev.dataset {
 		if _, ok := h.dataset[name]; !ok {
+			domOps++
 			dataset.Delete(name)
 		}
 	}
@@ -347,69 +443,30 @@ func (h *HTML) removeProperties(prev *HTML) {
 	style := h.node.Get("style")
 	for name := range prev.styles {
 		if _, ok := h.styles[name]; !ok {
+			domOps++
 			style.Call("removeProperty", name)
 		}
 	}
 
-	// Event listeners
-	for _, l := range prev.eventListeners {
-		h.node.Call("removeEventListener", l.Name, l.wrapper)
-		l.wrapper.Release()
-	}
+	// Event listeners with options which are not reused.
+	prev.removeNativeListeners(h.reuseNativeListeners(prev))
 }
 
 // reconcileChildren reconciles children of the current HTML against a previous
 // render's DOM nodes.
 func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
-	hasKeyedChildren := len(h.keyedChildren) > 0
+	var hasKeyedChildren bool
 	prevHadKeyedChildren := len(prev.keyedChildren) > 0
-	for i, nextChild := range h.children {
-		// Determine concrete type if necessary.
-		switch v := nextChild.(type) {
-		case *HTML:
-			// If the type of the child is *HTML, but its value is nil, replace
-			// the child with a concrete nil, to ensure consistent render
-			// handling.
-			if v == nil {
-				nextChild = nil
-				h.children[i] = nextChild
-			}
-		case List:
-			// Replace List with keyedList, which can handle nested keys and
-			// children.
-			nextChild = KeyedList{html: &HTML{children: v}}
-			h.children[i] = nextChild
-		}
-
-		// Ensure children implement the keyer interface consistently, and
-		// populate the keyedChildren map now.
-		//
-		// TODO(pdf): Add tests for node equality, keyed children
-		var (
-			new     = !h.node.Equal(prev.node)
-			nextKey interface{}
-		)
-		keyer, isKeyer := nextChild.(Keyer)
-		if hasKeyedChildren && !isKeyer {
-			panic("vecty: all siblings must have keys when using keyed elements")
-		}
-		if isKeyer {
-			nextKey = keyer.Key()
-			if hasKeyedChildren && nextKey == nil {
-				panic("vecty: all siblings must have keys when using keyed elements")
-			}
-			if nextKey != nil {
-				if h.keyedChildren == nil {
-					h.keyedChildren = make(map[interface{}]ComponentOrHTML)
-				}
-				if _, exists := h.keyedChildren[nextKey]; exists {
-					panic("vecty: duplicate sibling key")
-				}
-				// Store the keyed child.
-				h.keyedChildren[nextKey] = nextChild
-				hasKeyedChildren = true
-			}
-		}
+	children := h.prepareChildren()
+	stable := stableKeyedChildren(prev, children)
+	var prevUnkeyed []ComponentOrHTML
+	if prevHadKeyedChildren {
+		prevUnkeyed = unkeyedChildren(prev)
+	}
+	for i := range h.children {
+		nextChild, nextKey := children[i].child, children[i].key
+		hasKeyedChildren = children[i].keyed
+		new := !h.node.Equal(prev.node)
 
 		// If this is a new element (changed type, or did not exist previously),
 		// simply add the element directly. The existence of keyed children
@@ -419,14 +476,19 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 				pendingMounts = append(pendingMounts, nextChildList.reconcile(h, nil)...)
 				continue
 			}
-			nextChildRender, skip, mounters := render(nextChild, nil)
-			if skip || nextChildRender == nil {
+			nextChildRender, skip, mounters := render(nextChild, nil, h)
+			if skip || (nextChildRender == nil && nextChild == nil) {
 				continue
 			}
 			pendingMounts = append(pendingMounts, mounters...)
 			if m, ok := nextChild.(Mounter); ok {
 				pendingMounts = append(pendingMounts, m)
 			}
+			if nextChildRender == nil {
+				// The child is a component which rendered a fragment, and
+				// has already been inserted.
+				continue
+			}
 			h.lastRenderedChild = nextChildRender
 
 			// Note: we must insertBefore not appendChild because if we're
@@ -476,9 +538,17 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 		}
 
 		// If the next child is a list, reconcile its elements in-place, and
-		// we're done.
+		// we're done. A keyed list is then moved into position, and its
+		// previous list is not a leftover, since it was reconciled.
 		if nextChildList, ok := nextChild.(KeyedList); ok {
+			prevSibling := h.lastRenderedChild
 			pendingMounts = append(pendingMounts, nextChildList.reconcile(h, prevChild)...)
+			if hasKeyedChildren {
+				if prevChild != nil {
+					delete(prev.keyedChildren, nextKey)
+				}
+				h.placeFragment(nextChildList, prevSibling)
+			}
 			continue
 		}
 
@@ -489,24 +559,24 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 			prevChild = nil
 		}
 
-		// If we're keyed, find the next DOM node from the previous render to
-		// insert before, for reordering.
-		var (
-			insertBeforeKeyedNode jsObject
-			stableKey             bool
-		)
-		if hasKeyedChildren {
-			insertBeforeKeyedNode = h.lastRenderedChild.nextSibling()
-			// If the next node is our old node, mark key as stable, to avoid
-			// unnecessary insertion.
-			if prevChildRender != nil && prevChildRender.node.Equal(insertBeforeKeyedNode) {
-				stableKey = true
-				insertBeforeKeyedNode = nil
+		// If the previous child was a component which rendered a fragment,
+		// and it will not be re-rendered in place, likewise remove its
+		// fragment.
+		if prevChildComponent, ok := prevChild.(Component); ok {
+			nextChildComponent, ok := nextChild.(Component)
+			if prevFragment, isFragment := extractFragment(prevChildComponent); isFragment && (!ok || !sameType(prevChildComponent, nextChildComponent)) {
+				unmount(prevChildComponent)
+				prevFragment.remove(h)
+				prevChild = nil
 			}
 		}
 
+		// If we're keyed, remember the previously rendered sibling, after which
+		// the child is inserted if it is not stable.
+		prevSibling := h.lastRenderedChild
+
 		// Determine the next child render.
-		nextChildRender, skip, mounters := render(nextChild, prevChild)
+		nextChildRender, skip, mounters := render(nextChild, prevChild, h)
 		if nextChildRender != nil && prevChildRender != nil && nextChildRender == prevChildRender {
 			panic("vecty: next child render must not equal previous child render (did the child Render illegally return a stored render variable?)")
 		}
@@ -531,10 +601,39 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 			}
 		}
 		if skip {
+			// A keyed child which skipped rendering keeps its DOM nodes, which
+			// are not leftovers, but may need to be moved into position.
+			if hasKeyedChildren && nextChild == prevChild {
+				delete(prev.keyedChildren, nextKey)
+				if prevFragment, ok := extractFragment(prevChild); ok {
+					h.placeFragment(prevFragment, prevSibling)
+				} else if prevChildRender != nil {
+					if !stable[i] {
+						h.insertBefore(h.keyedInsertBeforeNode(prevSibling), prevChildRender)
+					}
+					h.lastRenderedChild = prevChildRender
+				}
+			}
 			continue
 		}
 		pendingMounts = append(pendingMounts, mounters...)
 
+		// If the next child is a component which rendered a fragment, its DOM
+		// nodes have already been reconciled in place. If keyed, they are
+		// then moved into position.
+		if nextChildRender == nil && nextChild != nil {
+			if hasKeyedChildren && nextChild == prevChild {
+				delete(prev.keyedChildren, nextKey)
+			}
+			if nextFragment, ok := extractFragment(nextChild); ok && hasKeyedChildren {
+				h.placeFragment(nextFragment, prevSibling)
+			}
+			if m := mountUnmount(nextChild, prevChild); m != nil {
+				pendingMounts = append(pendingMounts, m)
+			}
+			continue
+		}
+
 		// Perform the final reconciliation action for nextChildRender and
 		// prevChildRender. Replace, remove, insert or append the DOM nodes.
 		switch {
@@ -552,30 +651,36 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 				delete(prev.keyedChildren, nextKey)
 			}
 
-			// If we do not have keyed siblings, or the key is stable, replace
-			// the previous node (may be NOOP for equivalent nodes).
-			if !hasKeyedChildren || stableKey {
+			// If we do not have keyed siblings, replace the previous node (may
+			// be NOOP for equivalent nodes).
+			if !hasKeyedChildren {
 				replaceNode(nextChildRender.node, prevChildRender.node)
 				continue
 			}
-			// Moving keyed children need to be inserted (which moves existing
-			// nodes), rather than replacing the previous child at this
-			// position.
-			if insertBeforeKeyedNode != nil {
-				// Insert before the next sibling, if we have one.
-				h.insertBefore(insertBeforeKeyedNode, nextChildRender)
+			// If the keyed child is stable, its previous node is already in
+			// position, so no move is needed. A new node is inserted in its
+			// place, and the previous node removed with other leftovers below.
+			if stable[i] {
+				if !prevChildRender.node.Equal(nextChildRender.node) {
+					h.insertBefore(prevChildRender.node, nextChildRender)
+				}
 				continue
 			}
-			h.insertBefore(h.insertBeforeNode, nextChildRender)
+			// Moving keyed children need to be inserted (which moves existing
+			// nodes) after their previous sibling.
+			h.insertBefore(h.keyedInsertBeforeNode(prevSibling), nextChildRender)
 		case nextChildRender == nil && prevChildRender != nil:
+			if c, ok := prevChild.(Component); ok {
+				unmount(c)
+			}
 			h.removeChild(prevChildRender)
 		case nextChildRender != nil && prevChildRender == nil:
 			if m, ok := nextChild.(Mounter); ok {
 				pendingMounts = append(pendingMounts, m)
 			}
-			if insertBeforeKeyedNode != nil {
-				// Insert before the next keyed sibling, if we have one.
-				h.insertBefore(insertBeforeKeyedNode, nextChildRender)
+			if hasKeyedChildren {
+				// Insert after the previous keyed sibling.
+				h.insertBefore(h.keyedInsertBeforeNode(prevSibling), nextChildRender)
 				continue
 			}
 			h.insertBefore(h.insertBeforeNode, nextChildRender)
@@ -594,7 +699,7 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 			prevChildren[i] = c
 			i++
 		}
-		h.removeChildren(prevChildren)
+		h.removeChildren(append(prevChildren, prevUnkeyed...))
 		return pendingMounts
 	}
 
@@ -605,12 +710,223 @@ func (h *HTML) reconcileChildren(prev *HTML) (pendingMounts []Mounter) {
 	return pendingMounts
 }
 
+// keyedInsertBeforeNode returns the DOM node before which a keyed child is
+// inserted to place it after the given previously rendered sibling, or at the
+// start of the children if there is none.
+func (h *HTML) keyedInsertBeforeNode(prevSibling *HTML) jsObject {
+	if prevSibling != nil {
+		return prevSibling.nextSibling()
+	}
+	if h.insertBeforeNode != nil {
+		return h.insertBeforeNode
+	}
+	return h.firstChild()
+}
+
+// placeFragment moves the DOM nodes of a keyed fragment, a KeyedList or the
+// fragment rendered by a component, which were reconciled in place, such that
+// they follow the previously rendered sibling. Only nodes which are not
+// already in position are moved.
+func (h *HTML) placeFragment(l KeyedList, prevSibling *HTML) {
+	nodes := appendNodes(nil, l)
+	if len(nodes) == 0 {
+		return
+	}
+	next := h.keyedInsertBeforeNode(prevSibling)
+	for _, node := range nodes {
+		if next != nil && next.Equal(node) {
+			next = node.Get("nextSibling")
+			continue
+		}
+		domOps++
+		if next == nil {
+			h.node.Call("appendChild", node)
+		} else {
+			h.node.Call("insertBefore", node, next)
+		}
+	}
+	h.lastRenderedChild = &HTML{node: nodes[len(nodes)-1]}
+}
+
+// unkeyedChildren returns the children of h which are not in h.keyedChildren,
+// i.e. those with a missing or duplicate key when the MissingKeyError or
+// DuplicateKeyError was handled. Such children are never reused, since they
+// cannot be matched by key.
+func unkeyedChildren(h *HTML) []ComponentOrHTML {
+	var unkeyed []ComponentOrHTML
+	for _, child := range h.children {
+		if keyer, ok := child.(Keyer); ok && keyer.Key() != nil && h.keyedChildren[keyer.Key()] == child {
+			continue
+		}
+		unkeyed = append(unkeyed, child)
+	}
+	return unkeyed
+}
+
+// preparedChild is a child of an HTML element, as prepared by prepareChild.
+type preparedChild struct {
+	child ComponentOrHTML
+	key   interface{}
+	keyed bool
+}
+
+// prepareChildren prepares all children of h, as prepareChild does.
+func (h *HTML) prepareChildren() []preparedChild {
+	children := make([]preparedChild, len(h.children))
+	hasKeyedChildren := len(h.keyedChildren) > 0
+	for i := range h.children {
+		c := &children[i]
+		c.child, c.key, c.keyed = h.prepareChild(i, hasKeyedChildren)
+		hasKeyedChildren = c.keyed
+	}
+	return children
+}
+
+// stableKeyedChildren determines which keyed children may stay in place when
+// reconciled against prev, such that the fewest DOM nodes are moved. Keyed
+// lists, and components which render a fragment, are included if they
+// rendered any DOM nodes. It
+// returns, for each child, whether the child is part of the longest
+// subsequence of children whose previous DOM nodes are already in order.
+func stableKeyedChildren(prev *HTML, children []preparedChild) []bool {
+	stable := make([]bool, len(children))
+	if len(prev.keyedChildren) == 0 {
+		return stable
+	}
+	prevIndex := make(map[interface{}]int, len(prev.children))
+	for j, prevChild := range prev.children {
+		if keyer, ok := prevChild.(Keyer); ok && keyer.Key() != nil {
+			prevIndex[keyer.Key()] = j
+		}
+	}
+	// Collect the previous positions of the children which have a previous
+	// DOM node which may be reused.
+	var positions, indexes []int
+	for i, c := range children {
+		if !c.keyed || c.key == nil {
+			continue
+		}
+		prevChild, ok := prev.keyedChildren[c.key]
+		if !ok {
+			continue
+		}
+		if firstNode(prevChild) == nil {
+			continue
+		}
+		if j, ok := prevIndex[c.key]; ok {
+			positions = append(positions, j)
+			indexes = append(indexes, i)
+		}
+	}
+	for _, k := range longestIncreasingSubsequence(positions) {
+		stable[indexes[k]] = true
+	}
+	return stable
+}
+
+// longestIncreasingSubsequence returns the indexes of a longest strictly
+// increasing subsequence of seq, in order, in O(n log n) time.
+func longestIncreasingSubsequence(seq []int) []int {
+	// tails[k] is the index of the smallest value which ends an increasing
+	// subsequence of length k+1, and links[i] the index of the value
+	// preceding seq[i] in the subsequence it ends.
+	tails := make([]int, 0, len(seq))
+	links := make([]int, len(seq))
+	for i, v := range seq {
+		lo, hi := 0, len(tails)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if seq[tails[mid]] < v {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+		links[i] = -1
+		if lo > 0 {
+			links[i] = tails[lo-1]
+		}
+		if lo == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[lo] = i
+		}
+	}
+	lis := make([]int, len(tails))
+	if len(tails) == 0 {
+		return lis
+	}
+	for i, k := len(lis)-1, tails[len(tails)-1]; i >= 0; i-- {
+		lis[i] = k
+		k = links[k]
+	}
+	return lis
+}
+
+// prepareChild determines the concrete type of the child at index i, and
+// ensures children implement the keyer interface consistently, populating the
+// keyedChildren map. It returns the child, its key (if any), and whether h has
+// keyed children.
+func (h *HTML) prepareChild(i int, hasKeyedChildren bool) (nextChild ComponentOrHTML, nextKey interface{}, keyed bool) {
+	nextChild = h.children[i]
+	// Determine concrete type if necessary.
+	switch v := nextChild.(type) {
+	case *HTML:
+		// If the type of the child is *HTML, but its value is nil, replace
+		// the child with a concrete nil, to ensure consistent render
+		// handling.
+		if v == nil {
+			nextChild = nil
+			h.children[i] = nextChild
+		}
+	case List:
+		// Replace List with keyedList, which can handle nested keys and
+		// children.
+		nextChild = KeyedList{html: &HTML{children: v}}
+		h.children[i] = nextChild
+	}
+
+	// Ensure children implement the keyer interface consistently, and
+	// populate the keyedChildren map now.
+	//
+	// TODO(pdf): Add tests for node equality, keyed children
+	keyer, isKeyer := nextChild.(Keyer)
+	//
+	// If the errors are handled, children with missing or duplicate keys are
+	// treated as keyed children without a key, which are not reconciled
+	// against previous children.
+	if hasKeyedChildren && (!isKeyer || keyer.Key() == nil) {
+		handleError(MissingKeyError{Path: errorPath(nil)})
+		return nextChild, nil, hasKeyedChildren
+	}
+	if isKeyer {
+		nextKey = keyer.Key()
+		if nextKey != nil {
+			if h.keyedChildren == nil {
+				h.keyedChildren = make(map[interface{}]ComponentOrHTML)
+			}
+			if _, exists := h.keyedChildren[nextKey]; exists {
+				handleError(DuplicateKeyError{Path: errorPath(nil), Key: nextKey})
+				return nextChild, nil, true
+			}
+			// Store the keyed child.
+			h.keyedChildren[nextKey] = nextChild
+			hasKeyedChildren = true
+		}
+	}
+	return nextChild, nextKey, hasKeyedChildren
+}
+
 // removeChildren removes child elements from the previous render pass that no
 // longer exist on the current HTML children.
 func (h *HTML) removeChildren(prevChildren []ComponentOrHTML) {
 	for _, prevChild := range prevChildren {
-		if prevChildList, ok := prevChild.(KeyedList); ok {
-			// Previous child was a list, so remove all DOM nodes in it.
+		if prevChildList, ok := extractFragment(prevChild); ok {
+			// Previous child was a list, or a component which rendered a
+			// fragment, so remove all DOM nodes in it.
+			if c, ok := prevChild.(Component); ok {
+				unmount(c)
+			}
 			prevChildList.remove(h)
 			continue
 		}
@@ -618,6 +934,9 @@ func (h *HTML) removeChildren(prevChildren []ComponentOrHTML) {
 		if prevChildRender == nil {
 			continue
 		}
+		if c, ok := prevChild.(Component); ok {
+			unmount(c)
+		}
 		h.removeChild(prevChildRender)
 	}
 }
@@ -652,11 +971,13 @@ func (h *HTML) removeChild(child *HTML) {
 	}
 	// Use the child's parent node here, in case our node is not a valid
 	// target by the time we're called.
+	domOps++
 	child.node.Get("parentNode").Call("removeChild", child.node)
 }
 
 // appendChild appends a new child to this element.
 func (h *HTML) appendChild(child *HTML) {
+	domOps++
 	h.node.Call("appendChild", child.node)
 }
 
@@ -667,6 +988,7 @@ func (h *HTML) insertBefore(node jsObject, child *HTML) {
 		h.appendChild(child)
 		return
 	}
+	domOps++
 	h.node.Call("insertBefore", child.node, node)
 }
 
@@ -795,29 +1117,124 @@ func Text(text string, m ...MarkupOrChild) *HTML {
 // Rerender causes the body of the given Component (i.e. the HTML returned by
 // the Component's Render method) to be re-rendered.
 //
-// If the Component has not been rendered before, Rerender panics. If the
-// Component was previously unmounted, Rerender is no-op.
+// If the Component has not been rendered before, Rerender has no effect and
+// reports a NotRenderedError to the handler set by OnError, which panics with
+// the error if no handler is set. If the Component was previously unmounted,
+// Rerender is no-op.
 //
 // Rerender operates efficiently by batching renders together. As a result,
 // there is no guarantee that a calls to Rerender will map 1:1 with calls to
 // the Component's Render method. For example, two calls to Rerender may
 // result in only one call to the Component's Render method.
+//
+// When called from an EventListener, the re-render has PriorityUrgent, and
+// otherwise PriorityNormal. See RerenderWithPriority and SetScheduler.
 func Rerender(c Component) {
 	if c == nil {
 		panic("vecty: Rerender illegally called with a nil Component argument")
 	}
+	p := PriorityNormal
+	if handlingEvents > 0 {
+		p = PriorityUrgent
+	}
+	rerenderWithPriority(c, p)
+}
+
+// RerenderWithPriority is like Rerender, except that the re-render has the
+// given priority. A Component queued for re-rendering more than once is
+// re-rendered with the most urgent of the priorities.
+func RerenderWithPriority(c Component, p Priority) {
+	if c == nil {
+		panic("vecty: RerenderWithPriority illegally called with a nil Component argument")
+	}
+	if p < PriorityUrgent || p >= priorityCount {
+		panic("vecty: RerenderWithPriority illegally called with an invalid Priority")
+	}
+	rerenderWithPriority(c, p)
+}
+
+func rerenderWithPriority(c Component, p Priority) {
+	if devMode != nil {
+		devMode.checkRerender(c)
+	}
 	if c.Context().prevRender == nil {
-		panic("vecty: Rerender invoked on Component that has never been rendered")
+		handleError(NotRenderedError{Path: errorPath(c), Component: c})
+		return
 	}
 	if c.Context().unmounted {
 		return
 	}
-	batch.add(c)
+	batchOf(c).add(c, p)
+}
+
+// RerenderSync is like Rerender, except that the Component, and any other
+// pending re-renders, are rendered synchronously before it returns, as with
+// Flush. This allows e.g. an event listener to interact with the updated DOM
+// immediately:
+//
+// 	vecty.RerenderSync(c)
+// 	c.input.Node().Call("focus")
+//
+func RerenderSync(c Component) {
+	Rerender(c)
+	Flush()
+}
+
+// Flush synchronously renders all Components queued by Rerender, rather than
+// waiting for the next animation frame, and invokes any pending Mounter and
+// AfterRender callbacks. Re-renders queued while flushing are also rendered
+// before Flush returns.
+//
+// Flush panics if called while rendering, e.g. from a Render method.
+func Flush() {
+	if len(rendering) > 0 {
+		panic("vecty: Flush illegally called while rendering")
+	}
+	// Rendering may queue re-renders with other roots.
+	for flushed := true; flushed; {
+		flushed = false
+		allBatches(func(b *batchRenderer) {
+			if b.flush() {
+				flushed = true
+			}
+		})
+	}
+}
+
+// AfterRender registers fn to be called after the next render of the
+// Component has been committed to the DOM, whether the Component is rendered on
+// its own (e.g. due to Rerender) or as part of its parent. If the Component
+// skips rendering, fn is called after the render following it instead.
+//
+// Callbacks are called in the order they were registered, along with any
+// pending Mounter calls.
+func AfterRender(c Component, fn func()) {
+	if c == nil {
+		panic("vecty: AfterRender illegally called with a nil Component argument")
+	}
+	c.Context().afterRender = append(c.Context().afterRender, fn)
+}
+
+// afterRenderCallbacks are the callbacks registered by AfterRender, which are
+// invoked as a pending mount once a render has been committed.
+type afterRenderCallbacks []func()
+
+// Mount implements the Mounter interface.
+func (a afterRenderCallbacks) Mount() {
+	for _, fn := range a {
+		fn()
+	}
 }
 
 // batchRenderer handles component re-renders by queueing and deduplicating
-// them, to be rendered on the next animation frame (via requestAnimationFrame).
+// them by priority, to be rendered when the Scheduler performs the queue.
 type batchRenderer struct {
+	// queues are the pending re-renders, indexed by Priority.
+	queues [priorityCount]renderQueue
+}
+
+// renderQueue is a queue of pending re-renders of a single priority.
+type renderQueue struct {
 	// batch contains the list of pending components to render.
 	batch []Component
 	// idx maps components to batch indexes to allow dedup, retaining order.
@@ -826,85 +1243,190 @@ type batchRenderer struct {
 	scheduled bool
 }
 
-// add a Component to the pending batch.
-func (b *batchRenderer) add(c Component) {
-	if i, ok := b.idx[c]; ok {
-		// Shift idx for delete.
-		for j, c := range b.batch[i+1:] {
-			b.idx[c] = i + j
+// drain removes and returns the current batch.
+func (q *renderQueue) drain() []Component {
+	pending := q.batch
+	q.batch = nil
+	q.idx = nil
+	return pending
+}
+
+// push appends c to the batch, if it is not already queued.
+func (q *renderQueue) push(c Component) {
+	if _, ok := q.idx[c]; ok {
+		return
+	}
+	if q.idx == nil {
+		q.idx = make(map[Component]int)
+	}
+	q.batch = append(q.batch, c)
+	q.idx[c] = len(q.batch) - 1
+}
+
+// remove removes c from the batch, reporting whether it was queued.
+func (q *renderQueue) remove(c Component) bool {
+	i, ok := q.idx[c]
+	if !ok {
+		return false
+	}
+	// Shift idx for delete.
+	for j, c := range q.batch[i+1:] {
+		q.idx[c] = i + j
+	}
+	delete(q.idx, c)
+	// Delete previously queued render.
+	copy(q.batch[i:], q.batch[i+1:])
+	q.batch[len(q.batch)-1] = nil
+	q.batch = q.batch[:len(q.batch)-1]
+	return true
+}
+
+// hold blocks the batch from being processed, until release is called.
+func (b *batchRenderer) hold() {
+	for p := range b.queues {
+		b.queues[p].scheduled = true
+	}
+}
+
+// release schedules the batch after hold.
+func (b *batchRenderer) release() {
+	for p := range b.queues {
+		q := &b.queues[p]
+		q.scheduled = false
+		if Priority(p) == PriorityNormal || len(q.batch) > 0 {
+			b.schedule(Priority(p))
+		}
+	}
+}
+
+// reschedule schedules any pending batches with the current scheduler.
+func (b *batchRenderer) reschedule() {
+	for p := range b.queues {
+		q := &b.queues[p]
+		q.scheduled = false
+		if len(q.batch) > 0 {
+			b.schedule(Priority(p))
+		}
+	}
+}
+
+// schedule requests that the batch of the given priority be rendered.
+func (b *batchRenderer) schedule(p Priority) {
+	b.queues[p].scheduled = true
+	scheduler.Schedule(p, func(d Deadline) {
+		b.render(p, d)
+	})
+}
+
+// flush renders the pending batches, and any components added to them while
+// doing so, immediately, most urgent first. It reports whether any were
+// pending.
+func (b *batchRenderer) flush() (flushed bool) {
+	for p := 0; p < len(b.queues); p++ {
+		q := &b.queues[p]
+		if len(q.batch) == 0 {
+			continue
+		}
+		flushed = true
+		for _, c := range q.drain() {
+			// Skip unmounted components.
+			if c.Context().unmounted {
+				continue
+			}
+			mount(rerender(c)...)
+		}
+		// Rendering may have queued more urgent re-renders.
+		p = -1
+	}
+	return flushed
+}
+
+// discard discards the pending batches. Scheduled calls to render find them
+// empty.
+func (b *batchRenderer) discard() {
+	for p := range b.queues {
+		b.queues[p].drain()
+	}
+}
+
+// add a Component to the pending batch of the given priority.
+func (b *batchRenderer) add(c Component, p Priority) {
+	for other := range b.queues {
+		if _, ok := b.queues[other].idx[c]; !ok {
+			continue
+		}
+		if Priority(other) < p {
+			// Already queued with a higher priority.
+			return
 		}
-		// Delete previously queued render.
-		copy(b.batch[i:], b.batch[i+1:])
-		b.batch[len(b.batch)-1] = nil
-		b.batch = b.batch[:len(b.batch)-1]
+		b.queues[other].remove(c)
 	}
-	// Append and index component.
-	b.batch = append(b.batch, c)
-	b.idx[c] = len(b.batch) - 1
-	// If we're not already scheduled for a render batch, request a render on
-	// the next frame.
-	if !b.scheduled {
-		b.scheduled = true
-		requestAnimationFrame(b.render)
+	q := &b.queues[p]
+	q.push(c)
+	// If we're not already scheduled for a render batch, request one.
+	if !q.scheduled {
+		b.schedule(p)
 	}
 }
 
-// render the pending batch.
-// TODO(pdf): Add tests for time budget and multi-pass renders.
-func (b *batchRenderer) render(startTime float64) {
+// render the pending batch of the given priority, until the deadline.
+func (b *batchRenderer) render(p Priority, d Deadline) {
+	q := &b.queues[p]
+
 	// If the batch is empty, mark as unscheduled, and stop render cycle.
-	if len(b.batch) == 0 {
-		b.scheduled = false
+	if len(q.batch) == 0 {
+		q.scheduled = false
 		return
 	}
 
-	// Drain the current batch.
-	pending := b.batch
-	b.batch = nil
-	b.idx = make(map[Component]int)
+	pending := q.drain()
 
 	// Process batch.
+	var firstRemaining float64
 	for i, c := range pending {
 		// Skip unmounted components.
 		if c.Context().unmounted {
 			continue
 		}
 
-		// Check for remaining time budget, targeting 60fps (~16ms per frame).
+		// Check for remaining time budget.
 		if i > 0 {
-			elapsed := global().Get("performance").Call("now").Float() - startTime
-			budgetRemaining := (1000 / 60) - elapsed
-			avgRenderTime := elapsed / float64(i)
+			remaining := d.TimeRemaining()
+			if i == 1 {
+				firstRemaining = remaining
+			}
+			avgRenderTime := 0.0
+			if i > 1 {
+				avgRenderTime = (firstRemaining - remaining) / float64(i-1)
+			}
 			// If the budget remaining is less than 2 times the average
 			// Component render time, push the remainder of the batch to the
-			// next frame.
-			if budgetRemaining < avgRenderTime*2 {
-				b.batch = pending[i:]
-				for i, c := range b.batch {
-					b.idx[c] = i
+			// next call, ahead of any components queued meanwhile.
+			if remaining <= 0 || remaining < avgRenderTime*2 {
+				queued := q.drain()
+				for _, c := range pending[i:] {
+					q.push(c)
+				}
+				for _, c := range queued {
+					q.push(c)
 				}
 				break
 			}
 		}
 
 		// Perform render.
-		prevHTML := extractHTML(c.Context().prevRender)
-		nextHTML, skip, pendingMounts := renderComponent(c, c)
-		if skip {
-			continue
-		}
-		replaceNode(nextHTML.node, prevHTML.node)
-		mount(pendingMounts...)
+		mount(rerender(c)...)
 	}
 
-	// Schedule next frame.
-	requestAnimationFrame(b.render)
+	// Schedule next call.
+	b.schedule(p)
 }
 
-// extractHTML returns the *HTML from a ComponentOrHTML.
+// extractHTML returns the *HTML from a ComponentOrHTML. Lists, and components
+// which rendered a fragment, have no single *HTML and nil is returned.
 func extractHTML(e ComponentOrHTML) *HTML {
 	switch v := e.(type) {
-	case nil:
+	case nil, KeyedList:
 		return nil
 	case *HTML:
 		return v
@@ -915,6 +1437,97 @@ func extractHTML(e ComponentOrHTML) *HTML {
 	}
 }
 
+// extractFragment returns the KeyedList from a ComponentOrHTML which is either
+// a KeyedList, or a component which rendered a fragment.
+func extractFragment(e ComponentOrHTML) (KeyedList, bool) {
+	switch v := e.(type) {
+	case KeyedList:
+		return v, true
+	case Component:
+		return extractFragment(v.Context().prevRender)
+	default:
+		return KeyedList{}, false
+	}
+}
+
+// firstNode returns the first DOM node rendered by a ComponentOrHTML, or nil
+// if it did not render any.
+func firstNode(e ComponentOrHTML) jsObject {
+	switch v := e.(type) {
+	case *HTML:
+		if v != nil {
+			return v.node
+		}
+	case Component:
+		return firstNode(v.Context().prevRender)
+	case KeyedList:
+		for _, child := range v.html.children {
+			if node := firstNode(child); node != nil {
+				return node
+			}
+		}
+	}
+	return nil
+}
+
+// renderPosition returns a parent for reconciling a fragment in place of the
+// DOM nodes previously rendered by a ComponentOrHTML, for when the actual
+// parent is not known (e.g. when a component is re-rendered on its own).
+func renderPosition(e ComponentOrHTML) *HTML {
+	first := firstNode(e)
+	if first == nil {
+		panic("vecty: internal error (fragment rendered no DOM nodes)")
+	}
+	parent := &HTML{node: first.Get("parentNode"), insertBeforeNode: first}
+	if prevSibling := first.Get("previousSibling"); prevSibling != nil {
+		parent.lastRenderedChild = &HTML{node: prevSibling}
+	}
+	return parent
+}
+
+// fragment converts the List or KeyedList rendered by a component into a
+// fragment. A fragment which would not render any DOM nodes renders an empty
+// text node instead, so that its position in the DOM is retained for
+// subsequent renders.
+func fragment(r ComponentOrHTML) KeyedList {
+	var l KeyedList
+	switch v := r.(type) {
+	case List:
+		l = KeyedList{html: &HTML{children: v}}
+	case KeyedList:
+		l = v
+	}
+	if isEmptyList(l.html.children) {
+		l = KeyedList{key: l.key, html: &HTML{children: []ComponentOrHTML{Text("")}}}
+	}
+	return l
+}
+
+// isEmptyList reports whether the children would render no DOM nodes, i.e.
+// they consist only of nil children and empty lists.
+func isEmptyList(children []ComponentOrHTML) bool {
+	for _, child := range children {
+		switch v := child.(type) {
+		case nil:
+		case *HTML:
+			if v != nil {
+				return false
+			}
+		case List:
+			if !isEmptyList(v) {
+				return false
+			}
+		case KeyedList:
+			if !isEmptyList(v.html.children) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // sameType returns whether first and second ComponentOrHTML are of the same
 // underlying type.
 func sameType(first, second ComponentOrHTML) bool {
@@ -931,16 +1544,16 @@ func copyComponent(c Component) Component {
 	// perform the copy.
 	if copier, ok := c.(Copier); ok {
 		cpy := copier.Copy()
-		if cpy == c {
-			panic("vecty: Component.Copy illegally returned an identical *MyComponent pointer")
+		if cpy != c {
+			return cpy
 		}
-		return cpy
+		handleError(IdenticalCopyError{Path: errorPath(c), Component: c})
+	} else {
+		tinyGoAssertCopier(c)
 	}
 
-	tinyGoAssertCopier(c)
-
-	// Component does not implement the Copier interface, so perform a shallow
-	// copy.
+	// Component does not implement the Copier interface, or its Copy method
+	// failed, so perform a shallow copy.
 	v := reflect.ValueOf(c)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 		panic("vecty: Component must be pointer to struct, found " + reflect.TypeOf(c).String())
@@ -951,7 +1564,7 @@ func copyComponent(c Component) Component {
 }
 
 // copyProps copies all struct fields from src to dst that are tagged with
-// `vecty:"prop"`.
+// `vecty:"prop"` or `vecty:"prop,memo"`.
 //
 // If src and dst are different types or non-pointers, copyProps panics.
 func copyProps(src, dst Component) {
@@ -968,7 +1581,7 @@ func copyProps(src, dst Component) {
 	}
 	for i := 0; i < s.Elem().NumField(); i++ {
 		sf := s.Elem().Field(i)
-		if s.Elem().Type().Field(i).Tag.Get("vecty") == "prop" {
+		if prop, _ := propTag(s.Elem().Type().Field(i).Tag); prop {
 			df := d.Elem().Field(i)
 			if sf.Type() != df.Type() {
 				panic("vecty: internal error (should never be possible, struct types are identical)")
@@ -990,7 +1603,9 @@ func copyProps(src, dst Component) {
 // 5. nextChild == Component && prevChild == *HTML
 // 6. nextChild == Component && prevChild == nil
 //
-func render(next, prev ComponentOrHTML) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+// If next is a Component which renders a fragment, the fragment is reconciled
+// directly into the parent, and nextHTML == nil is returned.
+func render(next, prev ComponentOrHTML, parent *HTML) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
 	switch v := next.(type) {
 	case *HTML:
 		// Cases 1, 2 and 3 above. Reconcile against the prevRender.
@@ -998,7 +1613,7 @@ func render(next, prev ComponentOrHTML) (nextHTML *HTML, skip bool, pendingMount
 		return v, false, pendingMounts
 	case Component:
 		// Cases 4, 5, and 6 above.
-		return renderComponent(v, prev)
+		return renderComponent(v, prev, parent)
 	case nil:
 		return nil, false, nil
 	default:
@@ -1009,7 +1624,21 @@ func render(next, prev ComponentOrHTML) (nextHTML *HTML, skip bool, pendingMount
 // renderComponent handles rendering the given Component into *HTML. If skip ==
 // true is returned, the Component's SkipRender method has signaled the
 // component does not need to be rendered and h == nil is returned.
-func renderComponent(next Component, prev ComponentOrHTML) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+//
+// If the Component renders a fragment (a List or KeyedList), the fragment is
+// reconciled directly into the parent in place of prev, and h == nil is
+// returned. If the parent is nil, the fragment is reconciled in place of the
+// DOM nodes of prev. If the Component previously rendered a fragment and no
+// longer does, the fragment is removed from the parent, and the caller must
+// insert h at the parent's insertBeforeNode.
+func renderComponent(next Component, prev ComponentOrHTML, parent *HTML) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+	if devMode != nil {
+		devMode.checkProps(next)
+	}
+	if p := profiler; p != nil {
+		i := p.begin(next)
+		defer func() { p.end(i, skip) }()
+	}
 	// If we had a component last render, and it's of compatible type, operate
 	// on the previous instance.
 	if prevComponent, ok := prev.(Component); ok && sameType(next, prevComponent) {
@@ -1022,31 +1651,78 @@ func renderComponent(next Component, prev ComponentOrHTML) (nextHTML *HTML, skip
 		next = prevComponent
 	}
 
-	// Before rendering, consult the Component's SkipRender method to see if we
-	// should skip rendering or not.
-	if rs, ok := next.(RenderSkipper); ok {
-		prevRenderComponent := next.Context().prevRenderComponent
-		if prevRenderComponent != nil {
+	// Before rendering, consult the Component's SkipRender method, or compare
+	// its properties if it is memoized and rendered by its parent, to see if
+	// we should skip rendering or not.
+	if prevRenderComponent := next.Context().prevRenderComponent; prevRenderComponent != nil {
+		if rs, ok := next.(RenderSkipper); ok {
 			if next == prevRenderComponent {
 				panic("vecty: internal error (SkipRender called with identical prev component)")
 			}
 			if rs.SkipRender(prevRenderComponent) {
 				return nil, true, nil
 			}
+		} else if len(rendering) > 0 && memoized(next) && propsEqual(next, prevRenderComponent) {
+			return nil, true, nil
 		}
 	}
 
-	// Render the component into HTML, handling nil renders.
-	nextRender := next.Render()
-	prevRender := next.Context().prevRender
-	if nextRender == nil {
+	// A mounted component which was rendered before is being updated.
+	update := next.Context().prevRenderComponent != nil && !next.Context().unmounted
+	if bu, ok := next.(BeforeUpdater); ok && update {
+		bu.BeforeUpdate(next.Context().prevRenderComponent)
+	}
+
+	// Render the component into HTML, catching panics in its descendants if
+	// it is an ErrorBoundary.
+	pushRendering(next)
+	nextRender := normalizeRender(callRender(next))
+	if _, ok := next.(ErrorBoundary); ok {
+		nextHTML, skip, pendingMounts = renderErrorBoundary(next, nextRender, prev, parent)
+	} else {
+		nextHTML, skip, pendingMounts = reconcileRender(next, nextRender, prev, parent)
+	}
+	rendering = rendering[:len(rendering)-1]
+	if au, ok := next.(AfterUpdater); ok && update && !skip {
+		pendingMounts = append(pendingMounts, afterUpdate{au})
+	}
+	if devtools != nil && !skip {
+		pendingMounts = append(pendingMounts, devtools.rendered(next, update))
+	}
+	return nextHTML, skip, pendingMounts
+}
+
+// afterUpdate invokes the AfterUpdate method of a component as a pending
+// mount, once its render has been committed.
+type afterUpdate struct {
+	c AfterUpdater
+}
+
+// Mount implements the Mounter interface.
+func (a afterUpdate) Mount() { a.c.AfterUpdate() }
+
+// normalizeRender translates the value returned by Component.Render, handling
+// nil renders and fragments.
+func normalizeRender(r ComponentOrHTML) ComponentOrHTML {
+	if r == nil {
 		// nil renders are translated into noscript tags.
-		nextRender = Tag("noscript")
+		return Tag("noscript")
+	}
+	switch r.(type) {
+	case List, KeyedList:
+		return fragment(r)
 	}
+	return r
+}
 
+// reconcileRender reconciles nextRender, as rendered by the component next,
+// against prev. It implements the remainder of renderComponent once the
+// component has been rendered.
+func reconcileRender(next Component, nextRender, prev ComponentOrHTML, parent *HTML) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+	prevRender := next.Context().prevRender
 	switch v := nextRender.(type) {
 	case Component:
-		nextHTML, skip, pendingMounts = renderComponent(v, prevRender)
+		nextHTML, skip, pendingMounts = renderComponent(v, prevRender, parent)
 		if skip {
 			return nextHTML, skip, pendingMounts
 		}
@@ -1059,8 +1735,34 @@ func renderComponent(next Component, prev ComponentOrHTML) (nextHTML *HTML, skip
 			v = Tag("noscript")
 		}
 		nextHTML = v
+		// If we previously rendered a fragment, remove it. The caller inserts
+		// the new node in its place.
+		if prevFragment, ok := extractFragment(prev); ok {
+			prevFragment.remove(parent)
+		}
 		// Reconcile the actual rendered HTML.
 		pendingMounts = nextHTML.reconcile(extractHTML(prev))
+	case KeyedList:
+		if parent == nil {
+			if prev == nil {
+				panic("vecty: Component rendered at the root illegally returned a List (the root must render a single element)")
+			}
+			parent = renderPosition(prev)
+		}
+		if prevFragment, ok := extractFragment(prev); ok {
+			// Reconcile the fragment against our previous fragment.
+			pendingMounts = v.reconcile(parent, prevFragment)
+			break
+		}
+		// Insert the fragment in place of the previous node, if any.
+		prevHTML := extractHTML(prev)
+		if prevHTML != nil {
+			parent.insertBeforeNode = prevHTML.node
+		}
+		pendingMounts = v.reconcile(parent, nil)
+		if prevHTML != nil {
+			parent.removeChild(prevHTML)
+		}
 	default:
 		panic("vecty: internal error (unexpected ComponentOrHTML type " + reflect.TypeOf(v).String() + ")")
 	}
@@ -1070,6 +1772,11 @@ func renderComponent(next Component, prev ComponentOrHTML) (nextHTML *HTML, skip
 		pendingMounts = append(pendingMounts, m)
 	}
 
+	if callbacks := next.Context().afterRender; len(callbacks) > 0 {
+		pendingMounts = append(pendingMounts, callbacks)
+		next.Context().afterRender = nil
+	}
+
 	// Update the context to consider this render.
 	next.Context().prevRender = nextRender
 	next.Context().prevRenderComponent = copyComponent(next)
@@ -1111,8 +1818,15 @@ func mountUnmount(next, prev ComponentOrHTML) Mounter {
 
 // mount all pending Mounters
 func mount(pendingMounts ...Mounter) {
+	// Set refs before invoking any other Mounter, such as those of components
+	// rendered within the referenced elements.
+	for _, mounter := range pendingMounts {
+		if m, ok := mounter.(refMounter); ok {
+			m.Mount()
+		}
+	}
 	for _, mounter := range pendingMounts {
-		if mounter == nil {
+		if _, ok := mounter.(refMounter); mounter == nil || ok {
 			continue
 		}
 		if c, ok := mounter.(Component); ok {
@@ -1135,8 +1849,12 @@ func unmount(e ComponentOrHTML) {
 		}
 		c.Context().unmounted = true
 		c.Context().mounted = false
-		if prevRenderComponent, ok := c.Context().prevRender.(Component); ok {
-			unmount(prevRenderComponent)
+		if devtools != nil {
+			devtools.unmounted(c)
+		}
+		switch prevRender := c.Context().prevRender.(type) {
+		case Component, KeyedList:
+			unmount(prevRender)
 		}
 	}
 
@@ -1151,6 +1869,13 @@ func unmount(e ComponentOrHTML) {
 		for _, child := range h.children {
 			unmount(child)
 		}
+		h.setRefs(nil)
+		events.remove(h)
+		if h.portal != nil {
+			// Portal content does not exist within this node, so must be
+			// removed from its target.
+			h.portal.remove()
+		}
 	}
 
 	if u, ok := e.(Unmounter); ok {
@@ -1158,6 +1883,60 @@ func unmount(e ComponentOrHTML) {
 	}
 }
 
+// rerender re-renders the component on its own, in place of its previous
+// render. If rendering panics and the component has an ErrorBoundary ancestor,
+// the nearest one renders its fallback instead.
+func rerender(c Component) (pendingMounts []Mounter) {
+	rendering = rendering[:0]
+	render := func(parent *HTML) (*HTML, bool, []Mounter) {
+		return renderComponent(c, c, parent)
+	}
+	boundary := nearestErrorBoundary(c.Context().parent)
+	if boundary == nil {
+		return renderInPlace(c, render)
+	}
+	r, discarded := guardRender(func() {
+		pendingMounts = renderInPlace(c, render)
+	})
+	if r == nil {
+		return pendingMounts
+	}
+	err := recoverRenderPanic(r, c, 0)
+	return renderInPlace(boundary, func(parent *HTML) (*HTML, bool, []Mounter) {
+		nextHTML, pendingMounts := renderFallback(boundary, err, nil, discarded, parent)
+		return nextHTML, false, pendingMounts
+	})
+}
+
+// renderInPlace renders the component using the given render function, and
+// places the resulting DOM nodes in place of its previous render. The parent is
+// non-nil only if the component previously rendered a fragment.
+func renderInPlace(c Component, render func(parent *HTML) (*HTML, bool, []Mounter)) []Mounter {
+	prevHTML := extractHTML(c.Context().prevRender)
+	var parent *HTML
+	if prevHTML == nil {
+		// The component previously rendered a fragment.
+		parent = renderPosition(c)
+	}
+	nextHTML, skip, pendingMounts := render(parent)
+	if skip {
+		return nil
+	}
+	switch {
+	case nextHTML == nil:
+		// The component rendered a fragment, which has already been
+		// reconciled in place.
+	case prevHTML == nil:
+		parent.insertBefore(parent.insertBeforeNode, nextHTML)
+	default:
+		if !nextHTML.node.Equal(prevHTML.node) {
+			events.replaceRoot(prevHTML.node, nextHTML.node)
+		}
+		replaceNode(nextHTML.node, prevHTML.node)
+	}
+	return pendingMounts
+}
+
 // requestAnimationFrame calls the native JS function of the same name.
 func requestAnimationFrame(callback func(float64)) int {
 	var cb jsFunc
@@ -1176,6 +1955,8 @@ func requestAnimationFrame(callback func(float64)) int {
 //
 // This function blocks forever in order to prevent the program from exiting,
 // which would prevent components from rerendering themselves in the future.
+// Under a native GOOS and GOARCH, where rendering is into an in-memory DOM, it
+// returns immediately instead.
 //
 // It is a short-handed form for writing:
 //
@@ -1185,15 +1966,14 @@ func requestAnimationFrame(callback func(float64)) int {
 // 	}
 // 	select{} // run Go forever
 //
+// except that the error is passed to the function set by OnError, if any.
 func RenderBody(body Component) {
 	target := global().Get("document").Call("querySelector", "body")
 	err := renderIntoNode("RenderBody", target, body)
 	if err != nil {
-		panic(err)
-	}
-	if !isTest {
-		select {} // run Go forever
+		handleError(err)
 	}
+	keepAlive()
 }
 
 // ElementMismatchError is returned when the element returned by a component
@@ -1230,43 +2010,11 @@ func RenderInto(selector string, c Component) error {
 }
 
 func renderIntoNode(methodName string, node jsObject, c Component) error {
-	if !node.Truthy() {
-		return InvalidTargetError{method: methodName}
-	}
-	// block batch until we're done
-	batch.scheduled = true
-	nextRender, skip, pendingMounts := renderComponent(c, nil)
-	if skip {
-		panic("vecty: " + methodName + ": Component.SkipRender illegally returned true")
-	}
-	expectTag := toLower(node.Get("nodeName").String())
-	if nextRender.tag != expectTag {
-		return ElementMismatchError{method: methodName, got: nextRender.tag, want: expectTag}
-	}
-	doc := global().Get("document")
-	if doc.Get("readyState").String() == "loading" {
-		var cb jsFunc
-		cb = funcOf(func(this jsObject, args []jsObject) interface{} {
-			cb.Release()
-
-			replaceNode(nextRender.node, node)
-			mount(pendingMounts...)
-			if m, ok := c.(Mounter); ok {
-				mount(m)
-			}
-			requestAnimationFrame(batch.render)
-			return undefined()
-		})
-		doc.Call("addEventListener", "DOMContentLoaded", cb)
-		return nil
-	}
-	replaceNode(nextRender.node, node)
-	mount(pendingMounts...)
-	if m, ok := c.(Mounter); ok {
-		mount(m)
+	r, err := newRoot(methodName, node)
+	if err != nil {
+		return err
 	}
-	requestAnimationFrame(batch.render)
-	return nil
+	return r.render(methodName, c)
 }
 
 // SetTitle sets the title of the document.
@@ -1274,14 +2022,6 @@ func SetTitle(title string) {
 	global().Get("document").Set("title", title)
 }
 
-// AddStylesheet adds an external stylesheet to the document.
-func AddStylesheet(url string) {
-	link := global().Get("document").Call("createElement", "link")
-	link.Set("rel", "stylesheet")
-	link.Set("href", url)
-	global().Get("document").Get("head").Call("appendChild", link)
-}
-
 type jsFunc interface {
 	Release()
 }
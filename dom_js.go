@@ -8,6 +8,11 @@ import "syscall/js"
 type Event struct {
 	js.Value
 	Target js.Value
+
+	// CurrentTarget is the element whose EventListener is being invoked.
+	// Because event listeners are delegated to the render root, the
+	// currentTarget property of the underlying event is the root instead.
+	CurrentTarget js.Value
 }
 
 // Node returns the underlying JavaScript Element or TextNode.
@@ -30,6 +35,43 @@ func RenderIntoNode(node js.Value, c Component) error {
 	return renderIntoNode("RenderIntoNode", wrapObject(node), c)
 }
 
+// NewRootNode is like NewRoot, except that the root renders into the given
+// existing HTML element by replacing it.
+func NewRootNode(node js.Value) (*Root, error) {
+	return newRoot("NewRootNode", wrapObject(node))
+}
+
+// HydrateIntoNode renders the given component into the existing HTML element,
+// adopting the element and its children instead of replacing them. This allows
+// markup rendered by RenderToString on a server to become interactive without
+// being rebuilt, which would otherwise lose e.g. focus and scroll position.
+//
+// If the Component's Render method does not return an element of the same type,
+// an error of type ElementMismatchError is returned.
+//
+// If the existing children do not match what the component renders, the
+// component is rendered from scratch as with RenderIntoNode, and an error of
+// type HydrationMismatchError describing the first mismatch is returned.
+//
+// If the document is still loading, hydration is deferred until the
+// DOMContentLoaded event, and any such error is instead passed to the handler
+// set by OnError.
+func HydrateIntoNode(node js.Value, c Component) error {
+	return hydrateIntoNode("HydrateIntoNode", wrapObject(node), c)
+}
+
+// Portal returns HTML which renders the given children into the target DOM
+// node (e.g. document.body) instead of in place, such as for modals and
+// tooltips which must escape the overflow and stacking context of their
+// parent. The children otherwise behave as children of the portal's parent:
+// they are mounted, re-rendered and unmounted along with it.
+//
+// In place, a portal renders as an empty text node. Portal content is not
+// rendered by RenderToString.
+func Portal(target js.Value, children ...ComponentOrHTML) *HTML {
+	return newPortal("Portal", wrapObject(target), children)
+}
+
 func toLower(s string) string {
 	// We must call the prototype method here to workaround a limitation of
 	// syscall/js in both Go and GopherJS where we cannot call the
@@ -46,6 +88,30 @@ func global() jsObject {
 	return globalValue
 }
 
+// newEvent wraps a DOM event for use by an EventListener.
+func newEvent(jsEvent, currentTarget jsObject) *Event {
+	return &Event{
+		Value:         jsEvent.(wrappedObject).j,
+		Target:        jsEvent.Get("target").(wrappedObject).j,
+		CurrentTarget: currentTarget.(wrappedObject).j,
+	}
+}
+
+// keepAlive is called after the initial render by RenderBody, and blocks
+// forever in order to prevent the program from exiting, which would prevent
+// components from rerendering themselves in the future.
+func keepAlive() {
+	if !isTest {
+		select {} // run Go forever
+	}
+}
+
+// serverProvide and serverConsume implement Provide and Consume for components
+// being rendered by RenderToString, which is not available under GOOS=js.
+func serverProvide(c *Core, key, value interface{}) bool { return false }
+
+func serverConsume(c *Core, key interface{}) (interface{}, bool) { return nil, false }
+
 func undefined() wrappedObject {
 	return wrappedObject{js.Undefined()}
 }
@@ -0,0 +1,1895 @@
+// +build !js
+
+package vecty
+
+import (
+	"html"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// An in-memory implementation of the subset of the browser DOM which Vecty
+// uses, such that components can be rendered, re-rendered and interacted with
+// under a native GOOS and GOARCH (e.g. under 'go test') with no browser.
+
+// ResetDOM replaces the in-memory DOM with a new document consisting of an
+// empty head and body, discards any pending re-renders and mounted roots, and
+// restores all other package state to its default: the default Scheduler, no
+// error handler (see OnError), and development mode and profiling disabled.
+//
+// ResetDOM is only available under a native GOOS and GOARCH, where Vecty
+// renders into an in-memory DOM instead of a browser DOM. It is typically
+// called at the start of each test.
+func ResetDOM() {
+	globalValue = newMemWindow()
+	batch = &batchRenderer{}
+	roots = nil
+	devtools = nil
+	styles, addedStyles = nil, nil
+	headResources = nil
+	scheduler = &browserScheduler{}
+	events = &delegator{}
+	devMode = nil
+	profiler = nil
+	errorHandler = nil
+	rendering = nil
+	handlingEvents = 0
+	domOps = 0
+	guards, reconciled = 0, nil
+}
+
+// RunAnimationFrames invokes all pending requestAnimationFrame callbacks, in
+// the order they were requested, and returns the number of callbacks invoked.
+// Callbacks requested while RunAnimationFrames is running are deferred to the
+// next call, as they would be deferred to the next frame in a browser.
+//
+// Because Vecty batches calls to Rerender into the next animation frame,
+// RunAnimationFrames must be called for re-renders to be applied to the
+// in-memory DOM. Urgent re-renders, such as those from event listeners, are
+// instead applied in a microtask once the outermost listener returns.
+//
+// RunAnimationFrames is only available under a native GOOS and GOARCH.
+func RunAnimationFrames() int {
+	w, ok := global().(*memWindow)
+	if !ok {
+		return 0
+	}
+	return w.runAnimationFrames()
+}
+
+// memUndefined is the JavaScript undefined value.
+var memUndefined = memValue{}
+
+// memValueOf converts a Go value to its in-memory DOM equivalent.
+func memValueOf(v interface{}) jsObject {
+	switch v := v.(type) {
+	case nil:
+		return memUndefined
+	case wrappedObject:
+		if _, ok := v.j.(*jsObjectImpl); ok {
+			// The result of undefined().
+			return memUndefined
+		}
+		return v.j
+	case jsObject:
+		return v
+	case *jsFuncImpl:
+		return &memFunc{f: v}
+	case bool, string, float64:
+		return memValue{v: v}
+	case int:
+		return memValue{v: float64(v)}
+	case int8:
+		return memValue{v: float64(v)}
+	case int16:
+		return memValue{v: float64(v)}
+	case int32:
+		return memValue{v: float64(v)}
+	case int64:
+		return memValue{v: float64(v)}
+	case uint:
+		return memValue{v: float64(v)}
+	case uint8:
+		return memValue{v: float64(v)}
+	case uint16:
+		return memValue{v: float64(v)}
+	case uint32:
+		return memValue{v: float64(v)}
+	case uint64:
+		return memValue{v: float64(v)}
+	case float32:
+		return memValue{v: float64(v)}
+	case map[string]interface{}:
+		o := &memObject{props: make(map[string]jsObject, len(v))}
+		for key, value := range v {
+			o.props[key] = memValueOf(value)
+		}
+		return o
+	default:
+		panic("vecty: in-memory DOM: cannot convert Go value to JavaScript value")
+	}
+}
+
+// memString converts a Go value to a string as JavaScript would.
+func memString(v interface{}) string {
+	return memValueOf(v).String()
+}
+
+// memTruthy reports whether a Go value is truthy as JavaScript would.
+func memTruthy(v interface{}) bool {
+	return memValueOf(v).Truthy()
+}
+
+// memValue is a primitive value (a string, number or boolean) or undefined.
+type memValue struct {
+	v interface{}
+}
+
+// Set implements the jsObject interface.
+func (m memValue) Set(key string, value interface{}) {}
+
+// Get implements the jsObject interface.
+func (m memValue) Get(key string) jsObject {
+	if m.v == nil {
+		panic("vecty: in-memory DOM: cannot read property " + strconv.Quote(key) + " of undefined")
+	}
+	if s, ok := m.v.(string); ok && key == "length" {
+		return memValue{v: float64(len(s))}
+	}
+	return memUndefined
+}
+
+// Delete implements the jsObject interface.
+func (m memValue) Delete(key string) {}
+
+// Call implements the jsObject interface.
+func (m memValue) Call(name string, args ...interface{}) jsObject {
+	panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+}
+
+// String implements the jsObject interface.
+func (m memValue) String() string {
+	switch v := m.v.(type) {
+	case nil:
+		return "undefined"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e21 {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	panic("vecty: internal error (unexpected in-memory DOM value)")
+}
+
+// Truthy implements the jsObject interface.
+func (m memValue) Truthy() bool {
+	switch v := m.v.(type) {
+	case string:
+		return v != ""
+	case bool:
+		return v
+	case float64:
+		return v != 0 && !math.IsNaN(v)
+	}
+	return false
+}
+
+// Equal implements the jsObject interface.
+func (m memValue) Equal(other jsObject) bool {
+	o, ok := other.(memValue)
+	return ok && o.v == m.v
+}
+
+// IsUndefined implements the jsObject interface.
+func (m memValue) IsUndefined() bool { return m.v == nil }
+
+// Bool implements the jsObject interface.
+func (m memValue) Bool() bool {
+	v, ok := m.v.(bool)
+	if !ok {
+		panic("vecty: in-memory DOM: value is not a boolean")
+	}
+	return v
+}
+
+// Int implements the jsObject interface.
+func (m memValue) Int() int { return int(m.Float()) }
+
+// Float implements the jsObject interface.
+func (m memValue) Float() float64 {
+	v, ok := m.v.(float64)
+	if !ok {
+		panic("vecty: in-memory DOM: value is not a number")
+	}
+	return v
+}
+
+// memBase implements the parts of the jsObject interface which are common to
+// all in-memory objects.
+type memBase struct{}
+
+// String implements the jsObject interface.
+func (memBase) String() string { return "[object Object]" }
+
+// Truthy implements the jsObject interface.
+func (memBase) Truthy() bool { return true }
+
+// IsUndefined implements the jsObject interface.
+func (memBase) IsUndefined() bool { return false }
+
+// Bool implements the jsObject interface.
+func (memBase) Bool() bool { panic("vecty: in-memory DOM: value is not a boolean") }
+
+// Int implements the jsObject interface.
+func (memBase) Int() int { panic("vecty: in-memory DOM: value is not a number") }
+
+// Float implements the jsObject interface.
+func (memBase) Float() float64 { panic("vecty: in-memory DOM: value is not a number") }
+
+// memObject is a plain JavaScript object.
+type memObject struct {
+	memBase
+	props map[string]jsObject
+}
+
+// Set implements the jsObject interface.
+func (o *memObject) Set(key string, value interface{}) {
+	if o.props == nil {
+		o.props = make(map[string]jsObject)
+	}
+	o.props[key] = memValueOf(value)
+}
+
+// Get implements the jsObject interface.
+func (o *memObject) Get(key string) jsObject {
+	if v, ok := o.props[key]; ok {
+		return v
+	}
+	return memUndefined
+}
+
+// Delete implements the jsObject interface.
+func (o *memObject) Delete(key string) { delete(o.props, key) }
+
+// Call implements the jsObject interface.
+func (o *memObject) Call(name string, args ...interface{}) jsObject {
+	if f, ok := o.props[name].(*memFunc); ok {
+		return f.invoke(o, args...)
+	}
+	panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+}
+
+// Equal implements the jsObject interface.
+func (o *memObject) Equal(other jsObject) bool {
+	v, ok := other.(*memObject)
+	return ok && v == o
+}
+
+// memFunc is a JavaScript function implemented in Go, as created by funcOf.
+type memFunc struct {
+	memObject
+	f *jsFuncImpl
+}
+
+// String implements the jsObject interface.
+func (f *memFunc) String() string { return "func" }
+
+// Equal implements the jsObject interface.
+func (f *memFunc) Equal(other jsObject) bool {
+	v, ok := other.(*memFunc)
+	return ok && v.f == f.f
+}
+
+// invoke calls the function with the given this value and arguments.
+func (f *memFunc) invoke(this jsObject, args ...interface{}) jsObject {
+	jsArgs := make([]jsObject, len(args))
+	for i, arg := range args {
+		jsArgs[i] = memValueOf(arg)
+	}
+	return memValueOf(f.f.goFunc(this, jsArgs))
+}
+
+// memWindow is the global object of the in-memory DOM.
+type memWindow struct {
+	memObject
+	document    *memNode
+	performance *memObject
+	start       time.Time
+	// frames are the pending requestAnimationFrame callbacks, by ID.
+	frames    map[int]*memFunc
+	nextFrame int
+	// microtasks are the pending queueMicrotask callbacks, and callbackDepth
+	// the number of callbacks currently being invoked.
+	microtasks    []*memFunc
+	callbackDepth int
+	// marks are the User Timing marks, and measures the names of the User
+	// Timing measures, in the order they were made.
+	marks    map[string]bool
+	measures []string
+	// moves counts the nodes which have been moved, i.e. inserted while
+	// already attached to a parent, such that tests and benchmarks can
+	// measure how much work reconciliation performs.
+	moves int
+}
+
+// newMemWindow creates a window containing a document with an empty head and
+// body.
+func newMemWindow() *memWindow {
+	w := &memWindow{
+		start:     time.Now(),
+		frames:    make(map[int]*memFunc),
+		nextFrame: 1,
+	}
+	w.performance = &memObject{}
+	w.performance.Set("now", &jsFuncImpl{goFunc: func(this jsObject, args []jsObject) interface{} {
+		return float64(time.Since(w.start)) / float64(time.Millisecond)
+	}})
+	// User Timing marks and measures are recorded by name only.
+	w.marks = make(map[string]bool)
+	w.performance.Set("mark", &jsFuncImpl{goFunc: func(this jsObject, args []jsObject) interface{} {
+		w.marks[args[0].String()] = true
+		return nil
+	}})
+	w.performance.Set("measure", &jsFuncImpl{goFunc: func(this jsObject, args []jsObject) interface{} {
+		if len(args) > 1 && !w.marks[args[1].String()] {
+			panic("SyntaxError: The mark '" + args[1].String() + "' does not exist.")
+		}
+		w.measures = append(w.measures, args[0].String())
+		return nil
+	}})
+	w.performance.Set("clearMarks", &jsFuncImpl{goFunc: func(this jsObject, args []jsObject) interface{} {
+		delete(w.marks, args[0].String())
+		return nil
+	}})
+	w.Set("queueMicrotask", &jsFuncImpl{goFunc: func(this jsObject, args []jsObject) interface{} {
+		w.microtasks = append(w.microtasks, args[0].(*memFunc))
+		return nil
+	}})
+	// The console writes to standard error.
+	console := &memObject{}
+	for _, name := range []string{"log", "warn", "error"} {
+		console.Set(name, &jsFuncImpl{goFunc: func(this jsObject, args []jsObject) interface{} {
+			for i, arg := range args {
+				if i > 0 {
+					os.Stderr.WriteString(" ")
+				}
+				os.Stderr.WriteString(arg.String())
+			}
+			os.Stderr.WriteString("\n")
+			return nil
+		}})
+	}
+	w.Set("console", console)
+
+	doc := &memNode{nodeType: documentNode, nodeName: "#document"}
+	doc.document = doc
+	doc.props = map[string]jsObject{"readyState": memValue{v: "complete"}}
+	root := doc.createElement("", "html")
+	root.appendChild(doc.createElement("", "head"))
+	root.appendChild(doc.createElement("", "body"))
+	doc.appendChild(root)
+	w.document = doc
+	return w
+}
+
+// Get implements the jsObject interface.
+func (w *memWindow) Get(key string) jsObject {
+	switch key {
+	case "document":
+		return w.document
+	case "performance":
+		return w.performance
+	case "window":
+		return w
+	}
+	return w.memObject.Get(key)
+}
+
+// Call implements the jsObject interface.
+func (w *memWindow) Call(name string, args ...interface{}) jsObject {
+	switch name {
+	case "requestAnimationFrame":
+		id := w.nextFrame
+		w.nextFrame++
+		w.frames[id] = memValueOf(args[0]).(*memFunc)
+		return memValue{v: float64(id)}
+	case "cancelAnimationFrame":
+		delete(w.frames, memValueOf(args[0]).Int())
+		return memUndefined
+	}
+	return w.memObject.Call(name, args...)
+}
+
+// Equal implements the jsObject interface.
+func (w *memWindow) Equal(other jsObject) bool {
+	v, ok := other.(*memWindow)
+	return ok && v == w
+}
+
+// String implements the jsObject interface.
+func (w *memWindow) String() string { return "[object Window]" }
+
+// runAnimationFrames invokes all currently pending animation frame callbacks.
+func (w *memWindow) runAnimationFrames() int {
+	ids := make([]int, 0, len(w.frames))
+	for id := range w.frames {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	now := float64(time.Since(w.start)) / float64(time.Millisecond)
+	n := 0
+	for _, id := range ids {
+		f, ok := w.frames[id]
+		if !ok {
+			// Cancelled by a prior callback.
+			continue
+		}
+		delete(w.frames, id)
+		invokeCallback(f, memUndefined, now)
+		n++
+	}
+	return n
+}
+
+// invokeCallback invokes f as a callback from the browser, such as an event
+// listener, and then invokes pending microtasks once the outermost callback
+// has returned, as a browser does.
+func invokeCallback(f *memFunc, this jsObject, args ...interface{}) {
+	w, ok := global().(*memWindow)
+	if !ok {
+		f.invoke(this, args...)
+		return
+	}
+	func() {
+		w.callbackDepth++
+		defer func() { w.callbackDepth-- }()
+		f.invoke(this, args...)
+	}()
+	if w.callbackDepth > 0 {
+		return
+	}
+	for len(w.microtasks) > 0 {
+		task := w.microtasks[0]
+		w.microtasks = w.microtasks[1:]
+		invokeCallback(task, memUndefined)
+	}
+}
+
+// DOM node types not already declared for hydration.
+const documentNode = 9
+
+// memNode is a DOM node: a document, element, text node or comment.
+type memNode struct {
+	memBase
+	nodeType            int
+	nodeName, nodeValue string
+	namespace, tag      string
+	document            *memNode
+	parent              *memNode
+	children            []*memNode
+	// attrs are the element's attributes, in the order they were added.
+	attrs []memAttr
+	// styles are the element's inline style declarations, in the order they
+	// were added.
+	styles []memAttr
+	// innerHTML is markup set via the innerHTML property, which the in-memory
+	// DOM does not parse.
+	innerHTML string
+	// props are expando properties, i.e. any property that does not have
+	// special meaning to the in-memory DOM.
+	props     map[string]jsObject
+	listeners []*memListener
+	// selection is the selection of a document node, created on first use.
+	selection *memSelection
+}
+
+// memAttr is a name and value pair.
+type memAttr struct {
+	name, value string
+}
+
+// memListener is an event listener registered via addEventListener.
+type memListener struct {
+	typ     string
+	fn      *memFunc
+	capture bool
+	once    bool
+	passive bool
+}
+
+const htmlNamespace = "http://www.w3.org/1999/xhtml"
+
+// reflectedProperties maps properties to the attributes they reflect, for
+// string-valued properties.
+var reflectedProperties = map[string]string{
+	"id": "id", "className": "class", "htmlFor": "for", "href": "href",
+	"src": "src", "type": "type", "name": "name", "placeholder": "placeholder",
+	"alt": "alt", "title": "title", "rel": "rel", "lang": "lang", "dir": "dir",
+	"role": "role", "target": "target", "action": "action", "method": "method",
+}
+
+// reflectedBoolProperties maps properties to the attributes they reflect, for
+// boolean-valued properties.
+var reflectedBoolProperties = map[string]string{
+	"disabled": "disabled", "hidden": "hidden", "required": "required",
+	"readOnly": "readonly", "autofocus": "autofocus", "multiple": "multiple",
+	"contentEditable": "contenteditable", "draggable": "draggable",
+}
+
+// createElement creates an element owned by the document.
+func (n *memNode) createElement(namespace, tag string) *memNode {
+	e := &memNode{nodeType: elementNode, namespace: namespace, tag: tag, document: n.document}
+	if namespace == "" {
+		e.namespace = htmlNamespace
+	}
+	if e.namespace == htmlNamespace {
+		e.nodeName = strings.ToUpper(tag)
+	} else {
+		e.nodeName = tag
+	}
+	return e
+}
+
+// isHTML reports whether the node is an element in the HTML namespace.
+func (n *memNode) isHTML() bool {
+	return n.nodeType == elementNode && n.namespace == htmlNamespace
+}
+
+// String implements the jsObject interface.
+func (n *memNode) String() string {
+	switch n.nodeType {
+	case textNode:
+		return "[object Text]"
+	case commentNode:
+		return "[object Comment]"
+	case documentNode:
+		return "[object HTMLDocument]"
+	}
+	return "[object HTMLElement]"
+}
+
+// Equal implements the jsObject interface.
+func (n *memNode) Equal(other jsObject) bool {
+	v, ok := other.(*memNode)
+	return ok && v == n
+}
+
+// nodeOrNull returns n, or a nil jsObject (JavaScript null) if n is nil.
+func nodeOrNull(n *memNode) jsObject {
+	if n == nil {
+		return nil
+	}
+	return n
+}
+
+// index returns the index of n amongst its siblings.
+func (n *memNode) index() int {
+	if n.parent == nil {
+		return -1
+	}
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// sibling returns the sibling at the given offset, or nil.
+func (n *memNode) sibling(offset int) *memNode {
+	i := n.index()
+	if i < 0 || i+offset < 0 || i+offset >= len(n.parent.children) {
+		return nil
+	}
+	return n.parent.children[i+offset]
+}
+
+// elementChildren returns the children of n which are elements.
+func (n *memNode) elementChildren() []*memNode {
+	var elems []*memNode
+	for _, c := range n.children {
+		if c.nodeType == elementNode {
+			elems = append(elems, c)
+		}
+	}
+	return elems
+}
+
+// Get implements the jsObject interface.
+func (n *memNode) Get(key string) jsObject {
+	switch key {
+	case "nodeType":
+		return memValue{v: float64(n.nodeType)}
+	case "nodeName":
+		return memValue{v: n.nodeName}
+	case "tagName":
+		if n.nodeType == elementNode {
+			return memValue{v: n.nodeName}
+		}
+		return memUndefined
+	case "localName":
+		if n.nodeType == elementNode {
+			return memValue{v: n.tag}
+		}
+		return memUndefined
+	case "namespaceURI":
+		if n.nodeType == elementNode {
+			return memValue{v: n.namespace}
+		}
+		return nil
+	case "nodeValue", "data":
+		if n.nodeType == textNode || n.nodeType == commentNode {
+			return memValue{v: n.nodeValue}
+		}
+		return nil
+	case "textContent", "innerText":
+		if n.nodeType == documentNode {
+			return nil
+		}
+		return memValue{v: n.textContent()}
+	case "innerHTML":
+		return memValue{v: n.serializeChildren()}
+	case "outerHTML":
+		return memValue{v: n.serialize()}
+	case "ownerDocument":
+		if n.nodeType == documentNode {
+			return nil
+		}
+		return n.document
+	case "parentNode":
+		return nodeOrNull(n.parent)
+	case "parentElement":
+		if n.parent == nil || n.parent.nodeType != elementNode {
+			return nil
+		}
+		return n.parent
+	case "firstChild":
+		if len(n.children) == 0 {
+			return nil
+		}
+		return n.children[0]
+	case "lastChild":
+		if len(n.children) == 0 {
+			return nil
+		}
+		return n.children[len(n.children)-1]
+	case "nextSibling":
+		return nodeOrNull(n.sibling(1))
+	case "previousSibling":
+		return nodeOrNull(n.sibling(-1))
+	case "childNodes":
+		return &memNodeList{nodes: append([]*memNode(nil), n.children...)}
+	case "children":
+		return &memNodeList{nodes: n.elementChildren()}
+	case "isConnected":
+		return memValue{v: n.root().nodeType == documentNode}
+	case "classList":
+		if n.nodeType == elementNode {
+			return &memClassList{n: n}
+		}
+	case "dataset":
+		if n.nodeType == elementNode {
+			return &memDataset{n: n}
+		}
+	case "style":
+		if n.nodeType == elementNode {
+			return &memStyle{n: n}
+		}
+	case "value":
+		if n.nodeType == elementNode {
+			return memValue{v: n.value()}
+		}
+	case "checked":
+		if n.nodeType == elementNode {
+			if v, ok := n.props[key]; ok {
+				return memValue{v: v.Truthy()}
+			}
+			_, ok := n.getAttribute("checked")
+			return memValue{v: ok}
+		}
+	case "selected":
+		if n.nodeType == elementNode {
+			return memValue{v: n.selected()}
+		}
+	case "selectedIndex":
+		if n.nodeType == elementNode {
+			for i, opt := range n.options() {
+				if opt.selected() {
+					return memValue{v: float64(i)}
+				}
+			}
+			return memValue{v: float64(-1)}
+		}
+	case "options":
+		if n.nodeType == elementNode {
+			return &memNodeList{nodes: n.options()}
+		}
+	case "selectionStart", "selectionEnd", "selectionDirection":
+		if !n.isTextControl() {
+			return nil
+		}
+		if v, ok := n.props[key]; ok {
+			return v
+		}
+		if key == "selectionDirection" {
+			return memValue{v: "none"}
+		}
+		return memValue{v: float64(0)}
+	}
+	if n.nodeType == documentNode {
+		switch key {
+		case "documentElement":
+			return nodeOrNull(n.firstElement())
+		case "head", "body":
+			if root := n.firstElement(); root != nil {
+				for _, c := range root.elementChildren() {
+					if c.tag == key {
+						return c
+					}
+				}
+			}
+			return nil
+		case "title":
+			if v, ok := n.props[key]; ok {
+				return v
+			}
+			return memValue{v: ""}
+		case "activeElement":
+			if v, ok := n.props[key]; ok {
+				if e, ok := v.(*memNode); ok && e.root() == n {
+					return e
+				}
+			}
+			return n.Get("body")
+		}
+	}
+	if n.nodeType == elementNode {
+		if attr, ok := reflectedProperties[key]; ok {
+			v, _ := n.getAttribute(attr)
+			return memValue{v: v}
+		}
+		if attr, ok := reflectedBoolProperties[key]; ok {
+			_, ok := n.getAttribute(attr)
+			return memValue{v: ok}
+		}
+	}
+	if v, ok := n.props[key]; ok {
+		return v
+	}
+	return memUndefined
+}
+
+// Set implements the jsObject interface.
+func (n *memNode) Set(key string, value interface{}) {
+	switch key {
+	case "nodeValue", "data":
+		if n.nodeType == textNode || n.nodeType == commentNode {
+			n.nodeValue = memString(value)
+			if n.document != nil && n.document.selection != nil {
+				// As in browsers, replacing the data of a node moves the
+				// selection within it to its start.
+				n.document.selection.replaced(n)
+			}
+		}
+		return
+	case "textContent", "innerText":
+		if n.nodeType == textNode || n.nodeType == commentNode {
+			n.nodeValue = memString(value)
+			return
+		}
+		n.removeAllChildren()
+		if s := memString(value); s != "" {
+			n.appendChild(&memNode{nodeType: textNode, nodeName: "#text", nodeValue: s, document: n.document})
+		}
+		return
+	case "innerHTML":
+		n.removeAllChildren()
+		n.innerHTML = memString(value)
+		return
+	}
+	if n.nodeType == elementNode {
+		if attr, ok := reflectedProperties[key]; ok {
+			n.setAttribute(attr, memString(value))
+			return
+		}
+		if attr, ok := reflectedBoolProperties[key]; ok {
+			if memTruthy(value) {
+				n.setAttribute(attr, "")
+			} else {
+				n.removeAttribute(attr)
+			}
+			return
+		}
+		switch key {
+		case "value":
+			n.setValue(memString(value))
+			return
+		case "selected":
+			n.setSelected(memTruthy(value))
+			return
+		case "selectedIndex":
+			i := memValueOf(value).Int()
+			for j, opt := range n.options() {
+				opt.setSelected(i == j)
+			}
+			return
+		}
+	}
+	if n.props == nil {
+		n.props = make(map[string]jsObject)
+	}
+	n.props[key] = memValueOf(value)
+}
+
+// Delete implements the jsObject interface.
+func (n *memNode) Delete(key string) {
+	if n.nodeType == elementNode {
+		if attr, ok := reflectedProperties[key]; ok {
+			n.removeAttribute(attr)
+			return
+		}
+		if attr, ok := reflectedBoolProperties[key]; ok {
+			n.removeAttribute(attr)
+			return
+		}
+	}
+	delete(n.props, key)
+}
+
+// Call implements the jsObject interface.
+func (n *memNode) Call(name string, args ...interface{}) jsObject {
+	arg := func(i int) jsObject {
+		if i >= len(args) {
+			return memUndefined
+		}
+		return memValueOf(args[i])
+	}
+	node := func(i int) *memNode {
+		v, ok := arg(i).(*memNode)
+		if !ok {
+			if i < len(args) && args[i] == nil {
+				return nil
+			}
+			panic("vecty: in-memory DOM: " + name + ": argument " + strconv.Itoa(i+1) + " is not a Node")
+		}
+		return v
+	}
+	switch name {
+	case "appendChild":
+		return n.appendChild(node(0))
+	case "insertBefore":
+		return n.insertBefore(node(0), node(1))
+	case "removeChild":
+		return n.removeChild(node(0))
+	case "replaceChild":
+		newChild, oldChild := node(0), node(1)
+		n.insertBefore(newChild, oldChild)
+		return n.removeChild(oldChild)
+	case "remove":
+		if n.parent != nil {
+			n.parent.removeChild(n)
+		}
+		return memUndefined
+	case "contains":
+		for c := node(0); c != nil; c = c.parent {
+			if c == n {
+				return memValue{v: true}
+			}
+		}
+		return memValue{v: false}
+	case "hasChildNodes":
+		return memValue{v: len(n.children) > 0}
+	case "getAttribute":
+		if v, ok := n.getAttribute(arg(0).String()); ok {
+			return memValue{v: v}
+		}
+		return nil
+	case "setAttribute":
+		n.setAttribute(arg(0).String(), arg(1).String())
+		return memUndefined
+	case "removeAttribute":
+		n.removeAttribute(arg(0).String())
+		return memUndefined
+	case "hasAttribute":
+		_, ok := n.getAttribute(arg(0).String())
+		return memValue{v: ok}
+	case "addEventListener":
+		n.addEventListener(arg(0).String(), arg(1), arg(2))
+		return memUndefined
+	case "removeEventListener":
+		n.removeEventListener(arg(0).String(), arg(1), arg(2))
+		return memUndefined
+	case "dispatchEvent":
+		ev, ok := arg(0).(*memEvent)
+		if !ok {
+			panic("vecty: in-memory DOM: dispatchEvent: argument 1 is not an Event")
+		}
+		return memValue{v: n.dispatchEvent(ev)}
+	case "querySelector":
+		matches := n.querySelectorAll(arg(0).String(), true)
+		if len(matches) == 0 {
+			return nil
+		}
+		return matches[0]
+	case "querySelectorAll":
+		return &memNodeList{nodes: n.querySelectorAll(arg(0).String(), false)}
+	case "matches":
+		return memValue{v: mustParseSelector(arg(0).String()).matches(n)}
+	case "closest":
+		sel := mustParseSelector(arg(0).String())
+		for e := n; e != nil && e.nodeType == elementNode; e = e.parent {
+			if sel.matches(e) {
+				return e
+			}
+		}
+		return nil
+	case "setSelectionRange":
+		if n.isTextControl() {
+			direction := "none"
+			if len(args) > 2 {
+				direction = arg(2).String()
+			}
+			n.setSelectionRange(arg(0).Int(), arg(1).Int(), direction)
+			return memUndefined
+		}
+	case "focus":
+		n.focus()
+		return memUndefined
+	case "blur":
+		n.blur()
+		return memUndefined
+	case "click":
+		n.click()
+		return memUndefined
+	}
+	if n.nodeType == documentNode {
+		switch name {
+		case "createElement":
+			return n.createElement("", toLower(arg(0).String()))
+		case "createElementNS":
+			return n.createElement(arg(0).String(), arg(1).String())
+		case "createTextNode":
+			return &memNode{nodeType: textNode, nodeName: "#text", nodeValue: arg(0).String(), document: n}
+		case "createComment":
+			return &memNode{nodeType: commentNode, nodeName: "#comment", nodeValue: arg(0).String(), document: n}
+		case "createEvent":
+			return &memEvent{}
+		case "getSelection":
+			if n.selection == nil {
+				n.selection = &memSelection{}
+			}
+			return n.selection
+		case "getElementById":
+			id := arg(0).String()
+			var found *memNode
+			n.walk(func(e *memNode) bool {
+				if v, ok := e.getAttribute("id"); ok && v == id {
+					found = e
+					return false
+				}
+				return true
+			})
+			return nodeOrNull(found)
+		}
+	}
+	if f, ok := n.props[name].(*memFunc); ok {
+		return f.invoke(n, args...)
+	}
+	panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+}
+
+// root returns the root of the tree which n is in.
+func (n *memNode) root() *memNode {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// firstElement returns the first element child of n.
+func (n *memNode) firstElement() *memNode {
+	for _, c := range n.children {
+		if c.nodeType == elementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// walk calls fn for each element descendant of n in document order, until fn
+// returns false.
+func (n *memNode) walk(fn func(e *memNode) bool) bool {
+	for _, c := range n.children {
+		if c.nodeType != elementNode {
+			continue
+		}
+		if !fn(c) || !c.walk(fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendChild appends child to n, removing it from its previous parent.
+func (n *memNode) appendChild(child *memNode) jsObject {
+	return n.insertBefore(child, nil)
+}
+
+// insertBefore inserts child before the reference node, or appends it if the
+// reference is nil. The child is removed from its previous parent.
+func (n *memNode) insertBefore(child, ref *memNode) jsObject {
+	if child == nil {
+		panic("vecty: in-memory DOM: insertBefore: argument 1 is not a Node")
+	}
+	if child == ref {
+		return child
+	}
+	for p := n; p != nil; p = p.parent {
+		if p == child {
+			panic("vecty: in-memory DOM: HierarchyRequestError: the new child is an ancestor of the parent")
+		}
+	}
+	if ref != nil && ref.parent != n {
+		panic("vecty: in-memory DOM: NotFoundError: the node before which the new node is to be inserted is not a child of this node")
+	}
+	if child.parent != nil {
+		if w, ok := global().(*memWindow); ok {
+			w.moves++
+		}
+		child.parent.removeChild(child)
+	}
+	n.innerHTML = ""
+	child.parent = n
+	if ref == nil {
+		n.children = append(n.children, child)
+		return child
+	}
+	i := ref.index()
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = child
+	return child
+}
+
+// removeChild removes child from n.
+func (n *memNode) removeChild(child *memNode) jsObject {
+	if child == nil || child.parent != n {
+		panic("vecty: in-memory DOM: NotFoundError: the node to be removed is not a child of this node")
+	}
+	i := child.index()
+	copy(n.children[i:], n.children[i+1:])
+	n.children[len(n.children)-1] = nil
+	n.children = n.children[:len(n.children)-1]
+	child.parent = nil
+	return child
+}
+
+// removeAllChildren removes all children from n.
+func (n *memNode) removeAllChildren() {
+	for _, c := range n.children {
+		c.parent = nil
+	}
+	n.children = nil
+	n.innerHTML = ""
+}
+
+// getAttribute returns the named attribute, and whether it exists.
+func (n *memNode) getAttribute(name string) (string, bool) {
+	if n.isHTML() {
+		name = strings.ToLower(name)
+	}
+	if name == "style" && len(n.styles) > 0 {
+		return n.styleText(), true
+	}
+	for _, a := range n.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// setAttribute sets the named attribute.
+func (n *memNode) setAttribute(name, value string) {
+	if n.isHTML() {
+		name = strings.ToLower(name)
+	}
+	if name == "style" {
+		n.styles = parseStyleText(value)
+		return
+	}
+	for i, a := range n.attrs {
+		if a.name == name {
+			n.attrs[i].value = value
+			return
+		}
+	}
+	n.attrs = append(n.attrs, memAttr{name: name, value: value})
+}
+
+// removeAttribute removes the named attribute.
+func (n *memNode) removeAttribute(name string) {
+	if n.isHTML() {
+		name = strings.ToLower(name)
+	}
+	if name == "style" {
+		n.styles = nil
+		return
+	}
+	for i, a := range n.attrs {
+		if a.name == name {
+			n.attrs = append(n.attrs[:i], n.attrs[i+1:]...)
+			return
+		}
+	}
+}
+
+// classes returns the element's class names.
+func (n *memNode) classes() []string {
+	v, _ := n.getAttribute("class")
+	return strings.Fields(v)
+}
+
+// textContent returns the concatenated text of all descendant text nodes.
+func (n *memNode) textContent() string {
+	if n.nodeType == textNode || n.nodeType == commentNode {
+		return n.nodeValue
+	}
+	var b strings.Builder
+	var walk func(n *memNode)
+	walk = func(n *memNode) {
+		for _, c := range n.children {
+			switch c.nodeType {
+			case textNode:
+				b.WriteString(c.nodeValue)
+			case elementNode:
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// value returns the value property of a form element.
+func (n *memNode) value() string {
+	if v, ok := n.props["value"]; ok {
+		return v.String()
+	}
+	switch n.tag {
+	case "select":
+		for _, opt := range n.options() {
+			if opt.selected() {
+				return opt.value()
+			}
+		}
+		return ""
+	case "option":
+		if v, ok := n.getAttribute("value"); ok {
+			return v
+		}
+		return strings.TrimSpace(n.textContent())
+	case "textarea":
+		return n.textContent()
+	}
+	v, _ := n.getAttribute("value")
+	return v
+}
+
+// setValue sets the value property of a form element.
+func (n *memNode) setValue(value string) {
+	if n.tag == "select" {
+		for _, opt := range n.options() {
+			opt.setSelected(opt.value() == value)
+		}
+		return
+	}
+	if n.props == nil {
+		n.props = make(map[string]jsObject)
+	}
+	changed := n.value() != value
+	n.props["value"] = memValue{v: value}
+	if changed && n.isTextControl() {
+		// As in browsers, changing the value moves the caret to the end.
+		end := len(utf16.Encode([]rune(value)))
+		n.setSelectionRange(end, end, "none")
+	}
+}
+
+// isTextControl reports whether n is an input or textarea element which has a
+// text selection.
+func (n *memNode) isTextControl() bool {
+	switch n.tag {
+	case "textarea":
+		return true
+	case "input":
+		typ, _ := n.getAttribute("type")
+		switch toLower(typ) {
+		case "", "text", "search", "url", "tel", "password":
+			return true
+		}
+	}
+	return false
+}
+
+// setSelectionRange sets the selection of a text control, clamped to the
+// length of its value.
+func (n *memNode) setSelectionRange(start, end int, direction string) {
+	length := len(utf16.Encode([]rune(n.value())))
+	if end > length {
+		end = length
+	}
+	if start > end {
+		start = end
+	}
+	if direction != "forward" && direction != "backward" {
+		direction = "none"
+	}
+	if n.props == nil {
+		n.props = make(map[string]jsObject)
+	}
+	n.props["selectionStart"] = memValue{v: float64(start)}
+	n.props["selectionEnd"] = memValue{v: float64(end)}
+	n.props["selectionDirection"] = memValue{v: direction}
+}
+
+// options returns the option elements of a select element.
+func (n *memNode) options() []*memNode {
+	if n.tag != "select" {
+		return nil
+	}
+	var opts []*memNode
+	n.walk(func(e *memNode) bool {
+		if e.tag == "option" {
+			opts = append(opts, e)
+		}
+		return true
+	})
+	return opts
+}
+
+// selected returns whether an option element is selected.
+func (n *memNode) selected() bool {
+	if v, ok := n.props["selected"]; ok {
+		return v.Truthy()
+	}
+	_, ok := n.getAttribute("selected")
+	return ok
+}
+
+// setSelected sets whether an option element is selected, deselecting other
+// options if the select element does not allow multiple selections.
+func (n *memNode) setSelected(selected bool) {
+	if n.props == nil {
+		n.props = make(map[string]jsObject)
+	}
+	n.props["selected"] = memValue{v: selected}
+	if !selected {
+		return
+	}
+	for sel := n.parent; sel != nil; sel = sel.parent {
+		if sel.tag != "select" {
+			continue
+		}
+		if _, multiple := sel.getAttribute("multiple"); multiple {
+			return
+		}
+		for _, opt := range sel.options() {
+			if opt != n {
+				opt.setSelected(false)
+			}
+		}
+		return
+	}
+}
+
+// focus makes n the active element of its document.
+func (n *memNode) focus() {
+	if n.document == nil || n.document.Get("activeElement").Equal(n) {
+		return
+	}
+	if prev, ok := n.document.Get("activeElement").(*memNode); ok {
+		prev.blur()
+	}
+	n.document.Set("activeElement", n)
+	n.dispatchEvent(newMemEvent("focus", false, false))
+	n.dispatchEvent(newMemEvent("focusin", true, false))
+}
+
+// blur removes focus from n, if it is the active element of its document.
+func (n *memNode) blur() {
+	if n.document == nil {
+		return
+	}
+	if v, ok := n.document.props["activeElement"]; !ok || !v.Equal(n) {
+		return
+	}
+	n.document.Delete("activeElement")
+	n.dispatchEvent(newMemEvent("blur", false, false))
+	n.dispatchEvent(newMemEvent("focusout", true, false))
+}
+
+// click simulates a mouse click on n, including the activation behavior of
+// checkboxes and radio buttons.
+func (n *memNode) click() {
+	typ, _ := n.getAttribute("type")
+	toggle := n.tag == "input" && (typ == "checkbox" || typ == "radio")
+	var wasChecked bool
+	if toggle {
+		wasChecked = n.Get("checked").Bool()
+		n.Set("checked", typ == "radio" || !wasChecked)
+	}
+	if !n.dispatchEvent(newMemEvent("click", true, true)) && toggle {
+		// Default prevented, so revert the activation behavior.
+		n.Set("checked", wasChecked)
+		return
+	}
+	if toggle && n.Get("checked").Bool() != wasChecked {
+		n.dispatchEvent(newMemEvent("input", true, false))
+		n.dispatchEvent(newMemEvent("change", true, false))
+	}
+}
+
+// listenerOptions parses the options argument of addEventListener and
+// removeEventListener.
+func listenerOptions(options jsObject) (capture, once, passive bool) {
+	switch v := options.(type) {
+	case memValue:
+		return v.Truthy(), false, false
+	case *memObject:
+		return v.Get("capture").Truthy(), v.Get("once").Truthy(), v.Get("passive").Truthy()
+	}
+	return false, false, false
+}
+
+// addEventListener registers an event listener, unless an identical one is
+// already registered.
+func (n *memNode) addEventListener(typ string, listener, options jsObject) {
+	fn, ok := listener.(*memFunc)
+	if !ok {
+		return
+	}
+	capture, once, passive := listenerOptions(options)
+	for _, l := range n.listeners {
+		if l.typ == typ && l.fn.Equal(fn) && l.capture == capture {
+			return
+		}
+	}
+	n.listeners = append(n.listeners, &memListener{typ: typ, fn: fn, capture: capture, once: once, passive: passive})
+}
+
+// removeEventListener removes a matching event listener.
+func (n *memNode) removeEventListener(typ string, listener, options jsObject) {
+	capture, _, _ := listenerOptions(options)
+	for i, l := range n.listeners {
+		if l.typ == typ && l.fn.Equal(listener) && l.capture == capture {
+			n.listeners = append(n.listeners[:i:i], n.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchEvent dispatches the event to n, through the capture, target and
+// bubble phases. It returns false if the event was cancelable and its default
+// was prevented.
+func (n *memNode) dispatchEvent(ev *memEvent) bool {
+	ev.target = n
+	ev.dispatched = true
+	var path []*memNode
+	for p := n.parent; p != nil; p = p.parent {
+		path = append(path, p)
+	}
+
+	// Capture phase, from the root towards the target.
+	ev.phase = 1
+	for i := len(path) - 1; i >= 0 && !ev.stopped; i-- {
+		path[i].invokeListeners(ev, true, false)
+	}
+	// Target phase.
+	ev.phase = 2
+	if !ev.stopped {
+		n.invokeListeners(ev, true, true)
+	}
+	// Bubble phase, from the target towards the root.
+	if ev.bubbles {
+		ev.phase = 3
+		for i := 0; i < len(path) && !ev.stopped; i++ {
+			path[i].invokeListeners(ev, false, true)
+		}
+	}
+	ev.phase = 0
+	ev.currentTarget = nil
+	return !ev.defaultPrevented
+}
+
+// invokeListeners invokes the listeners of n for the event which match the
+// given phases.
+func (n *memNode) invokeListeners(ev *memEvent, capture, bubble bool) {
+	ev.currentTarget = n
+	listeners := append([]*memListener(nil), n.listeners...)
+	for _, l := range listeners {
+		if l.typ != ev.typ || (l.capture && !capture) || (!l.capture && !bubble) {
+			continue
+		}
+		if l.once {
+			n.removeEventListener(l.typ, l.fn, memValue{v: l.capture})
+		}
+		ev.passive = l.passive
+		invokeCallback(l.fn, n, ev)
+		ev.passive = false
+		if ev.immediateStopped {
+			return
+		}
+	}
+}
+
+// querySelectorAll returns the element descendants of n matching the CSS
+// selector, in document order.
+func (n *memNode) querySelectorAll(selector string, first bool) []*memNode {
+	sel := mustParseSelector(selector)
+	var matches []*memNode
+	n.walk(func(e *memNode) bool {
+		if sel.matches(e) {
+			matches = append(matches, e)
+			return !first
+		}
+		return true
+	})
+	return matches
+}
+
+// styleText returns the inline style declarations in attribute form.
+func (n *memNode) styleText() string {
+	decls := make([]string, len(n.styles))
+	for i, s := range n.styles {
+		decls[i] = s.name + ": " + s.value + ";"
+	}
+	return strings.Join(decls, " ")
+}
+
+// parseStyleText parses inline style declarations in attribute form.
+func parseStyleText(text string) []memAttr {
+	var styles []memAttr
+	for _, decl := range strings.Split(text, ";") {
+		i := strings.Index(decl, ":")
+		if i < 0 {
+			continue
+		}
+		name := strings.TrimSpace(decl[:i])
+		value := strings.TrimSpace(decl[i+1:])
+		if name != "" && value != "" {
+			styles = append(styles, memAttr{name: name, value: value})
+		}
+	}
+	return styles
+}
+
+// serialize returns the HTML serialization of the node.
+func (n *memNode) serialize() string {
+	switch n.nodeType {
+	case textNode:
+		if n.parent != nil && rawTextElements[n.parent.tag] {
+			return n.nodeValue
+		}
+		return html.EscapeString(n.nodeValue)
+	case commentNode:
+		return "<!--" + n.nodeValue + "-->"
+	case documentNode:
+		return n.serializeChildren()
+	}
+	var b strings.Builder
+	b.WriteString("<" + n.tag)
+	attrs := n.attrs
+	if len(n.styles) > 0 {
+		attrs = append(attrs[:len(attrs):len(attrs)], memAttr{name: "style", value: n.styleText()})
+	}
+	for _, a := range attrs {
+		b.WriteString(" " + a.name + `="` + html.EscapeString(a.value) + `"`)
+	}
+	b.WriteString(">")
+	if n.isHTML() && voidElements[n.tag] {
+		return b.String()
+	}
+	b.WriteString(n.serializeChildren())
+	b.WriteString("</" + n.tag + ">")
+	return b.String()
+}
+
+// serializeChildren returns the HTML serialization of the children of n.
+func (n *memNode) serializeChildren() string {
+	if n.innerHTML != "" {
+		return n.innerHTML
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		b.WriteString(c.serialize())
+	}
+	return b.String()
+}
+
+// memNodeList is a static NodeList or HTMLCollection.
+type memNodeList struct {
+	memBase
+	nodes []*memNode
+}
+
+// Set implements the jsObject interface.
+func (l *memNodeList) Set(key string, value interface{}) {}
+
+// Get implements the jsObject interface.
+func (l *memNodeList) Get(key string) jsObject {
+	if key == "length" {
+		return memValue{v: float64(len(l.nodes))}
+	}
+	if i, err := strconv.Atoi(key); err == nil {
+		if i >= 0 && i < len(l.nodes) {
+			return l.nodes[i]
+		}
+	}
+	return memUndefined
+}
+
+// Delete implements the jsObject interface.
+func (l *memNodeList) Delete(key string) {}
+
+// Call implements the jsObject interface.
+func (l *memNodeList) Call(name string, args ...interface{}) jsObject {
+	if name == "item" && len(args) == 1 {
+		if i := memValueOf(args[0]).Int(); i >= 0 && i < len(l.nodes) {
+			return l.nodes[i]
+		}
+		return nil
+	}
+	panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+}
+
+// Equal implements the jsObject interface.
+func (l *memNodeList) Equal(other jsObject) bool {
+	v, ok := other.(*memNodeList)
+	return ok && v == l
+}
+
+// memSelection is the selection of a document, as returned by getSelection. It
+// supports a single range, whose anchor and focus offsets are in UTF-16 code
+// units, but does not otherwise track changes to the DOM.
+type memSelection struct {
+	memBase
+	anchorNode, focusNode     *memNode
+	anchorOffset, focusOffset int
+}
+
+// Set implements the jsObject interface.
+func (s *memSelection) Set(key string, value interface{}) {}
+
+// Get implements the jsObject interface.
+func (s *memSelection) Get(key string) jsObject {
+	switch key {
+	case "anchorNode":
+		return nodeOrNull(s.anchorNode)
+	case "anchorOffset":
+		return memValue{v: float64(s.anchorOffset)}
+	case "focusNode":
+		return nodeOrNull(s.focusNode)
+	case "focusOffset":
+		return memValue{v: float64(s.focusOffset)}
+	case "rangeCount":
+		if s.anchorNode == nil {
+			return memValue{v: float64(0)}
+		}
+		return memValue{v: float64(1)}
+	case "isCollapsed":
+		return memValue{v: s.anchorNode == s.focusNode && s.anchorOffset == s.focusOffset}
+	}
+	return memUndefined
+}
+
+// Delete implements the jsObject interface.
+func (s *memSelection) Delete(key string) {}
+
+// Call implements the jsObject interface.
+func (s *memSelection) Call(name string, args ...interface{}) jsObject {
+	node := func(i int) *memNode {
+		n, _ := memValueOf(args[i]).(*memNode)
+		return n
+	}
+	offset := func(i int) int {
+		return memValueOf(args[i]).Int()
+	}
+	switch name {
+	case "collapse":
+		s.anchorNode, s.anchorOffset = node(0), offset(1)
+		s.focusNode, s.focusOffset = s.anchorNode, s.anchorOffset
+	case "setBaseAndExtent":
+		s.anchorNode, s.anchorOffset = node(0), offset(1)
+		s.focusNode, s.focusOffset = node(2), offset(3)
+	case "removeAllRanges":
+		*s = memSelection{}
+	default:
+		panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+	}
+	return memUndefined
+}
+
+// Equal implements the jsObject interface.
+func (s *memSelection) Equal(other jsObject) bool {
+	v, ok := other.(*memSelection)
+	return ok && v == s
+}
+
+// String implements the jsObject interface.
+func (s *memSelection) String() string { return "[object Selection]" }
+
+// replaced updates the selection after the data of the node n was replaced.
+func (s *memSelection) replaced(n *memNode) {
+	if s.anchorNode == n {
+		s.anchorOffset = 0
+	}
+	if s.focusNode == n {
+		s.focusOffset = 0
+	}
+}
+
+// memClassList is the classList of an element.
+type memClassList struct {
+	memBase
+	n *memNode
+}
+
+// Set implements the jsObject interface.
+func (c *memClassList) Set(key string, value interface{}) {}
+
+// Get implements the jsObject interface.
+func (c *memClassList) Get(key string) jsObject {
+	classes := c.n.classes()
+	if key == "length" {
+		return memValue{v: float64(len(classes))}
+	}
+	if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(classes) {
+		return memValue{v: classes[i]}
+	}
+	return memUndefined
+}
+
+// Delete implements the jsObject interface.
+func (c *memClassList) Delete(key string) {}
+
+// Call implements the jsObject interface.
+func (c *memClassList) Call(name string, args ...interface{}) jsObject {
+	classes := c.n.classes()
+	has := func(name string) int {
+		for i, c := range classes {
+			if c == name {
+				return i
+			}
+		}
+		return -1
+	}
+	switch name {
+	case "add":
+		for _, arg := range args {
+			if name := memString(arg); has(name) < 0 {
+				classes = append(classes, name)
+			}
+		}
+	case "remove":
+		for _, arg := range args {
+			if i := has(memString(arg)); i >= 0 {
+				classes = append(classes[:i], classes[i+1:]...)
+			}
+		}
+	case "toggle":
+		name := memString(args[0])
+		i := has(name)
+		if i >= 0 {
+			classes = append(classes[:i], classes[i+1:]...)
+		} else {
+			classes = append(classes, name)
+		}
+		c.n.setAttribute("class", strings.Join(classes, " "))
+		return memValue{v: i < 0}
+	case "contains":
+		return memValue{v: has(memString(args[0])) >= 0}
+	default:
+		panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+	}
+	c.n.setAttribute("class", strings.Join(classes, " "))
+	return memUndefined
+}
+
+// Equal implements the jsObject interface.
+func (c *memClassList) Equal(other jsObject) bool {
+	v, ok := other.(*memClassList)
+	return ok && v.n == c.n
+}
+
+// memDataset is the dataset of an element.
+type memDataset struct {
+	memBase
+	n *memNode
+}
+
+// Set implements the jsObject interface.
+func (d *memDataset) Set(key string, value interface{}) {
+	d.n.setAttribute("data-"+datasetAttribute(key), memString(value))
+}
+
+// Get implements the jsObject interface.
+func (d *memDataset) Get(key string) jsObject {
+	if v, ok := d.n.getAttribute("data-" + datasetAttribute(key)); ok {
+		return memValue{v: v}
+	}
+	return memUndefined
+}
+
+// Delete implements the jsObject interface.
+func (d *memDataset) Delete(key string) {
+	d.n.removeAttribute("data-" + datasetAttribute(key))
+}
+
+// Call implements the jsObject interface.
+func (d *memDataset) Call(name string, args ...interface{}) jsObject {
+	panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+}
+
+// Equal implements the jsObject interface.
+func (d *memDataset) Equal(other jsObject) bool {
+	v, ok := other.(*memDataset)
+	return ok && v.n == d.n
+}
+
+// memStyle is the inline style (CSSStyleDeclaration) of an element.
+type memStyle struct {
+	memBase
+	n *memNode
+}
+
+// Set implements the jsObject interface.
+func (s *memStyle) Set(key string, value interface{}) {
+	s.setProperty(datasetAttribute(key), memString(value))
+}
+
+// Get implements the jsObject interface.
+func (s *memStyle) Get(key string) jsObject {
+	if key == "cssText" {
+		return memValue{v: s.n.styleText()}
+	}
+	return memValue{v: s.getProperty(datasetAttribute(key))}
+}
+
+// Delete implements the jsObject interface.
+func (s *memStyle) Delete(key string) {
+	s.setProperty(datasetAttribute(key), "")
+}
+
+// Call implements the jsObject interface.
+func (s *memStyle) Call(name string, args ...interface{}) jsObject {
+	switch name {
+	case "setProperty":
+		s.setProperty(memString(args[0]), memString(args[1]))
+		return memUndefined
+	case "removeProperty":
+		old := s.getProperty(memString(args[0]))
+		s.setProperty(memString(args[0]), "")
+		return memValue{v: old}
+	case "getPropertyValue":
+		return memValue{v: s.getProperty(memString(args[0]))}
+	}
+	panic("vecty: in-memory DOM: " + strconv.Quote(name) + " is not a function")
+}
+
+// Equal implements the jsObject interface.
+func (s *memStyle) Equal(other jsObject) bool {
+	v, ok := other.(*memStyle)
+	return ok && v.n == s.n
+}
+
+// getProperty returns the value of the named style property, or "".
+func (s *memStyle) getProperty(name string) string {
+	for _, decl := range s.n.styles {
+		if decl.name == name {
+			return decl.value
+		}
+	}
+	return ""
+}
+
+// setProperty sets the named style property, removing it if value is "".
+func (s *memStyle) setProperty(name, value string) {
+	for i, decl := range s.n.styles {
+		if decl.name != name {
+			continue
+		}
+		if value == "" {
+			s.n.styles = append(s.n.styles[:i:i], s.n.styles[i+1:]...)
+			return
+		}
+		s.n.styles[i].value = value
+		return
+	}
+	if value != "" {
+		s.n.styles = append(s.n.styles, memAttr{name: name, value: value})
+	}
+}
+
+// memEvent is a DOM Event.
+type memEvent struct {
+	memObject
+	typ                       string
+	bubbles, cancelable       bool
+	target, currentTarget     *memNode
+	phase                     int
+	defaultPrevented, passive bool
+	stopped, immediateStopped bool
+	dispatched                bool
+}
+
+// newMemEvent creates an initialized event.
+func newMemEvent(typ string, bubbles, cancelable bool) *memEvent {
+	return &memEvent{typ: typ, bubbles: bubbles, cancelable: cancelable}
+}
+
+// String implements the jsObject interface.
+func (e *memEvent) String() string { return "[object Event]" }
+
+// Get implements the jsObject interface.
+func (e *memEvent) Get(key string) jsObject {
+	switch key {
+	case "type":
+		return memValue{v: e.typ}
+	case "bubbles":
+		return memValue{v: e.bubbles}
+	case "cancelable":
+		return memValue{v: e.cancelable}
+	case "target", "srcElement":
+		return nodeOrNull(e.target)
+	case "currentTarget":
+		return nodeOrNull(e.currentTarget)
+	case "eventPhase":
+		return memValue{v: float64(e.phase)}
+	case "cancelBubble":
+		return memValue{v: e.stopped}
+	case "defaultPrevented":
+		return memValue{v: e.defaultPrevented}
+	}
+	return e.memObject.Get(key)
+}
+
+// Call implements the jsObject interface.
+func (e *memEvent) Call(name string, args ...interface{}) jsObject {
+	switch name {
+	case "initEvent":
+		e.typ = memString(args[0])
+		e.bubbles = len(args) > 1 && memTruthy(args[1])
+		e.cancelable = len(args) > 2 && memTruthy(args[2])
+		e.stopped, e.immediateStopped, e.defaultPrevented = false, false, false
+		return memUndefined
+	case "preventDefault":
+		if e.cancelable && !e.passive {
+			e.defaultPrevented = true
+		}
+		return memUndefined
+	case "stopPropagation":
+		e.stopped = true
+		return memUndefined
+	case "stopImmediatePropagation":
+		e.stopped = true
+		e.immediateStopped = true
+		return memUndefined
+	}
+	return e.memObject.Call(name, args...)
+}
+
+// Equal implements the jsObject interface.
+func (e *memEvent) Equal(other jsObject) bool {
+	v, ok := other.(*memEvent)
+	return ok && v == e
+}
@@ -0,0 +1,368 @@
+// +build !js
+
+package vecty
+
+import "strings"
+
+// memSelector is a parsed CSS selector list. It supports the subset of CSS
+// selectors needed to query the in-memory DOM: type, universal, ID, class and
+// attribute selectors (including the ~=, ^=, $= and *= operators), the
+// :first-child, :last-child, :only-child, :checked, :disabled, :enabled,
+// :empty and :not() pseudo-classes, and the descendant, child, adjacent
+// sibling and general sibling combinators.
+type memSelector []memComplexSelector
+
+// memComplexSelector is a sequence of compound selectors separated by
+// combinators, e.g. "ul > li.active a".
+type memComplexSelector struct {
+	compounds []memCompoundSelector
+	// combinators[i] is the combinator between compounds[i] and
+	// compounds[i+1].
+	combinators []byte
+}
+
+// memCompoundSelector is a sequence of simple selectors which all apply to the
+// same element, e.g. "li.active[data-id]".
+type memCompoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []memAttrSelector
+	pseudos []memPseudoSelector
+}
+
+// memAttrSelector is an attribute selector, e.g. [type="text"].
+type memAttrSelector struct {
+	name, op, value string
+}
+
+// memPseudoSelector is a pseudo-class selector, e.g. :first-child.
+type memPseudoSelector struct {
+	name string
+	not  memSelector
+}
+
+// mustParseSelector parses a CSS selector list, panicking if it is invalid or
+// unsupported, as the browser DOM would throw a SyntaxError.
+func mustParseSelector(s string) memSelector {
+	p := &selectorParser{s: s}
+	sel := p.parseList()
+	if p.pos < len(p.s) {
+		p.fail()
+	}
+	return sel
+}
+
+// selectorParser is a CSS selector parser.
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+// fail panics with a syntax error.
+func (p *selectorParser) fail() {
+	panic("vecty: in-memory DOM: SyntaxError: " + `"` + p.s + `" is not a valid (or supported) selector`)
+}
+
+// peek returns the next byte, or 0 at the end of input.
+func (p *selectorParser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+// skipSpace skips whitespace, and reports whether any was skipped.
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.s) && isWhitespace(p.s[p.pos:p.pos+1]) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+// ident parses a CSS identifier.
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		p.fail()
+	}
+	return p.s[start:p.pos]
+}
+
+// parseList parses a comma-separated selector list, stopping at the end of
+// input or a closing parenthesis.
+func (p *selectorParser) parseList() memSelector {
+	var sel memSelector
+	for {
+		p.skipSpace()
+		sel = append(sel, p.parseComplex())
+		p.skipSpace()
+		if p.peek() != ',' {
+			return sel
+		}
+		p.pos++
+	}
+}
+
+// parseComplex parses a complex selector.
+func (p *selectorParser) parseComplex() memComplexSelector {
+	var c memComplexSelector
+	c.compounds = append(c.compounds, p.parseCompound())
+	for {
+		space := p.skipSpace()
+		switch next := p.peek(); next {
+		case '>', '+', '~':
+			p.pos++
+			p.skipSpace()
+			c.combinators = append(c.combinators, next)
+		case ',', ')', 0:
+			return c
+		default:
+			if !space {
+				p.fail()
+			}
+			c.combinators = append(c.combinators, ' ')
+		}
+		c.compounds = append(c.compounds, p.parseCompound())
+	}
+}
+
+// parseCompound parses a compound selector.
+func (p *selectorParser) parseCompound() memCompoundSelector {
+	var c memCompoundSelector
+	start := p.pos
+	switch next := p.peek(); {
+	case next == '*':
+		p.pos++
+	case next != '#' && next != '.' && next != '[' && next != ':':
+		c.tag = strings.ToLower(p.ident())
+	}
+	for {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			c.id = p.ident()
+		case '.':
+			p.pos++
+			c.classes = append(c.classes, p.ident())
+		case '[':
+			p.pos++
+			c.attrs = append(c.attrs, p.parseAttr())
+		case ':':
+			p.pos++
+			c.pseudos = append(c.pseudos, p.parsePseudo())
+		default:
+			if p.pos == start {
+				p.fail()
+			}
+			return c
+		}
+	}
+}
+
+// parseAttr parses an attribute selector, after the opening bracket.
+func (p *selectorParser) parseAttr() memAttrSelector {
+	p.skipSpace()
+	a := memAttrSelector{name: strings.ToLower(p.ident())}
+	p.skipSpace()
+	if p.peek() == ']' {
+		p.pos++
+		return a
+	}
+	if strings.HasPrefix(p.s[p.pos:], "=") {
+		a.op = "="
+	} else if len(p.s) >= p.pos+2 && strings.Contains("~^$*", p.s[p.pos:p.pos+1]) && p.s[p.pos+1] == '=' {
+		a.op = p.s[p.pos : p.pos+2]
+	} else {
+		p.fail()
+	}
+	p.pos += len(a.op)
+	p.skipSpace()
+	if q := p.peek(); q == '"' || q == '\'' {
+		end := strings.IndexByte(p.s[p.pos+1:], q)
+		if end < 0 {
+			p.fail()
+		}
+		a.value = p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		a.value = p.ident()
+	}
+	p.skipSpace()
+	if p.peek() != ']' {
+		p.fail()
+	}
+	p.pos++
+	return a
+}
+
+// parsePseudo parses a pseudo-class selector, after the colon.
+func (p *selectorParser) parsePseudo() memPseudoSelector {
+	ps := memPseudoSelector{name: strings.ToLower(p.ident())}
+	switch ps.name {
+	case "first-child", "last-child", "only-child", "checked", "disabled", "enabled", "empty":
+	case "not":
+		if p.peek() != '(' {
+			p.fail()
+		}
+		p.pos++
+		ps.not = p.parseList()
+		if p.peek() != ')' {
+			p.fail()
+		}
+		p.pos++
+	default:
+		p.fail()
+	}
+	return ps
+}
+
+// matches reports whether the element matches any selector in the list.
+func (s memSelector) matches(e *memNode) bool {
+	for _, c := range s {
+		if c.matches(e, len(c.compounds)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the element matches the complex selector up to and
+// including compounds[i].
+func (c memComplexSelector) matches(e *memNode, i int) bool {
+	if !c.compounds[i].matches(e) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c.combinators[i-1] {
+	case '>':
+		p := e.parent
+		return p != nil && p.nodeType == elementNode && c.matches(p, i-1)
+	case ' ':
+		for p := e.parent; p != nil && p.nodeType == elementNode; p = p.parent {
+			if c.matches(p, i-1) {
+				return true
+			}
+		}
+	case '+':
+		s := previousElementSibling(e)
+		return s != nil && c.matches(s, i-1)
+	case '~':
+		for s := previousElementSibling(e); s != nil; s = previousElementSibling(s) {
+			if c.matches(s, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// previousElementSibling returns the previous sibling of e which is an
+// element, or nil.
+func previousElementSibling(e *memNode) *memNode {
+	for s := e.sibling(-1); s != nil; s = s.sibling(-1) {
+		if s.nodeType == elementNode {
+			return s
+		}
+	}
+	return nil
+}
+
+// matches reports whether the element matches the compound selector.
+func (c memCompoundSelector) matches(e *memNode) bool {
+	if e.nodeType != elementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != strings.ToLower(e.tag) {
+		return false
+	}
+	if c.id != "" {
+		if id, _ := e.getAttribute("id"); id != c.id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		classes := e.classes()
+	classLoop:
+		for _, want := range c.classes {
+			for _, have := range classes {
+				if have == want {
+					continue classLoop
+				}
+			}
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := e.getAttribute(a.name)
+		if !ok {
+			return false
+		}
+		switch a.op {
+		case "=":
+			ok = v == a.value
+		case "~=":
+			ok = false
+			for _, f := range strings.Fields(v) {
+				ok = ok || f == a.value
+			}
+		case "^=":
+			ok = a.value != "" && strings.HasPrefix(v, a.value)
+		case "$=":
+			ok = a.value != "" && strings.HasSuffix(v, a.value)
+		case "*=":
+			ok = a.value != "" && strings.Contains(v, a.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	for _, ps := range c.pseudos {
+		var ok bool
+		switch ps.name {
+		case "first-child":
+			ok = previousElementSibling(e) == nil
+		case "last-child":
+			ok = nextElementSibling(e) == nil
+		case "only-child":
+			ok = previousElementSibling(e) == nil && nextElementSibling(e) == nil
+		case "checked":
+			ok = e.Get("checked").Truthy() || e.tag == "option" && e.selected()
+		case "disabled":
+			_, ok = e.getAttribute("disabled")
+		case "enabled":
+			_, disabled := e.getAttribute("disabled")
+			ok = !disabled
+		case "empty":
+			ok = len(e.children) == 0 && e.innerHTML == ""
+		case "not":
+			ok = !ps.not.matches(e)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// nextElementSibling returns the next sibling of e which is an element, or
+// nil.
+func nextElementSibling(e *memNode) *memNode {
+	for s := e.sibling(1); s != nil; s = s.sibling(1) {
+		if s.nodeType == elementNode {
+			return s
+		}
+	}
+	return nil
+}
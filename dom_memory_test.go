@@ -0,0 +1,276 @@
+// +build !js
+
+package vecty
+
+import "testing"
+
+// TestMemoryDOM_Render tests that components render into, and re-render
+// within, the in-memory DOM.
+func TestMemoryDOM_Render(t *testing.T) {
+	ResetDOM()
+
+	var items []string
+	comp := &componentFunc{}
+	comp.render = func() ComponentOrHTML {
+		var list List
+		for _, item := range items {
+			list = append(list, Tag("li", Text(item)))
+		}
+		return Tag("body",
+			Tag("button",
+				Markup(
+					Property("id", "add"),
+					Class("btn"),
+					Style("color", "red"),
+					Data("fooBar", "baz"),
+					&EventListener{Name: "click", Listener: func(e *Event) {
+						items = append(items, "item")
+						Rerender(comp)
+					}},
+				),
+				Text("Add"),
+			),
+			Tag("ul", list),
+		)
+	}
+	RenderBody(comp)
+
+	want := `<body><button id="add" class="btn" data-foo-bar="baz" style="color: red;">Add</button><ul></ul></body>`
+	if got := memBody().Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	button := global().Get("document").Call("querySelector", "#add")
+	button.Call("click")
+	button.Call("click")
+	if got := RunAnimationFrames(); got != 1 {
+		t.Fatalf("got %d animation frames, want 1", got)
+	}
+	want = `<ul><li>item</li><li>item</li></ul>`
+	if got := global().Get("document").Call("querySelector", "ul").Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := global().Get("document").Call("querySelectorAll", "body > ul li").Get("length").Int(); got != 2 {
+		t.Fatalf("got %d list items, want 2", got)
+	}
+}
+
+// TestMemoryDOM_ResetDOM tests that ResetDOM restores all package state, such
+// that state left behind by one test does not affect the next.
+func TestMemoryDOM_ResetDOM(t *testing.T) {
+	ResetDOM()
+	SetDevMode(&DevMode{})
+	SetProfiler(&Profiler{})
+	OnError(func(err error) {})
+	SetScheduler(&ManualScheduler{})
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", Text("a"))
+	}})
+
+	ResetDOM()
+	if devMode != nil || profiler != nil || errorHandler != nil {
+		t.Fatal("expected development mode, profiling and the error handler to be reset")
+	}
+	if _, ok := scheduler.(*browserScheduler); !ok {
+		t.Fatalf("got scheduler %T, want the default", scheduler)
+	}
+	if len(rendering) != 0 || handlingEvents != 0 || domOps != 0 || guards != 0 || reconciled != nil {
+		t.Fatal("expected rendering state to be reset")
+	}
+	if got := memBody().Get("outerHTML").String(); got != "<body></body>" {
+		t.Fatalf("got %s, want an empty body", got)
+	}
+	if got := global().(*memWindow).moves; got != 0 {
+		t.Fatalf("got %d moves, want 0", got)
+	}
+}
+
+// TestMemoryDOM_Keyed tests that keyed children are reordered, retaining their
+// DOM nodes.
+func TestMemoryDOM_Keyed(t *testing.T) {
+	ResetDOM()
+
+	keys := []string{"a", "b", "c", "d"}
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		var list List
+		for _, k := range keys {
+			list = append(list, Tag("li", Markup(Key(k), Property("id", k)), Text(k)))
+		}
+		return Tag("body", Tag("ul", list))
+	}}
+	RenderBody(comp)
+	doc := global().Get("document")
+	before := map[string]jsObject{}
+	for _, k := range keys {
+		before[k] = doc.Call("getElementById", k)
+	}
+
+	keys = []string{"d", "b", "a"}
+	Rerender(comp)
+	RunAnimationFrames()
+
+	want := `<ul><li id="d">d</li><li id="b">b</li><li id="a">a</li></ul>`
+	if got := doc.Call("querySelector", "ul").Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	for _, k := range keys {
+		if !doc.Call("getElementById", k).Equal(before[k]) {
+			t.Fatalf("keyed element %q was not retained", k)
+		}
+	}
+	if before["c"].Get("parentNode") != nil {
+		t.Fatal("removed keyed element is still attached")
+	}
+}
+
+// TestMemoryDOM_Events tests event dispatch semantics of the in-memory DOM.
+func TestMemoryDOM_Events(t *testing.T) {
+	ResetDOM()
+
+	var got []string
+	listener := func(name string) *EventListener {
+		return &EventListener{Name: "click", Listener: func(e *Event) {
+			got = append(got, name+":"+e.Target.Get("id").String())
+		}}
+	}
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			Tag("div", Markup(Property("id", "outer"), listener("outer")),
+				Tag("a", Markup(Property("id", "inner"), listener("inner").PreventDefault())),
+				Tag("span", Markup(Property("id", "stop"), listener("stop").StopPropagation())),
+			),
+		)
+	}})
+	doc := global().Get("document")
+
+	ev := doc.Call("createEvent", "Event")
+	ev.Call("initEvent", "click", true, true)
+	if doc.Call("getElementById", "inner").Call("dispatchEvent", ev).Bool() {
+		t.Fatal("expected default to be prevented")
+	}
+	doc.Call("getElementById", "stop").Call("click")
+
+	want := []string{"inner:inner", "outer:inner", "stop:stop"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v want %v", got, want)
+		}
+	}
+}
+
+// TestMemoryDOM_FormElements tests the value, checked and selected
+// properties of form elements in the in-memory DOM.
+func TestMemoryDOM_FormElements(t *testing.T) {
+	ResetDOM()
+
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			Tag("input", Markup(Property("type", "checkbox"), Property("id", "check"))),
+			Tag("input", Markup(Property("value", "hello"), Property("id", "text"))),
+			Tag("select", Markup(Property("id", "select")),
+				Tag("option", Markup(Property("value", "a")), Text("A")),
+				Tag("option", Text("b")),
+			),
+		)
+	}})
+	doc := global().Get("document")
+
+	check := doc.Call("getElementById", "check")
+	check.Call("click")
+	if !check.Get("checked").Bool() || doc.Call("querySelector", "input:checked") == nil {
+		t.Fatal("expected checkbox to be checked after click")
+	}
+	if got := doc.Call("getElementById", "text").Get("value").String(); got != "hello" {
+		t.Fatalf("got value %q want %q", got, "hello")
+	}
+	sel := doc.Call("getElementById", "select")
+	if got := sel.Get("value").String(); got != "" {
+		t.Fatalf("got select value %q want %q", got, "")
+	}
+	sel.Set("value", "b")
+	if got := sel.Get("selectedIndex").Int(); got != 1 {
+		t.Fatalf("got selectedIndex %d want 1", got)
+	}
+}
+
+// TestMemoryDOM_Selection tests the document selection of the in-memory DOM,
+// which moves to the start of a text node when its data is replaced.
+func TestMemoryDOM_Selection(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	a, b := doc.Call("createTextNode", "abc"), doc.Call("createTextNode", "def")
+	doc.Get("body").Call("appendChild", a)
+	doc.Get("body").Call("appendChild", b)
+
+	sel := doc.Call("getSelection")
+	if got := sel.Get("rangeCount").Int(); got != 0 {
+		t.Fatalf("got rangeCount %d want 0", got)
+	}
+	sel.Call("setBaseAndExtent", a, 1, b, 2)
+	if !sel.Get("anchorNode").Equal(a) || sel.Get("anchorOffset").Int() != 1 || !sel.Get("focusNode").Equal(b) || sel.Get("focusOffset").Int() != 2 {
+		t.Fatal("expected selection from a:1 to b:2")
+	}
+	b.Set("data", "xyz")
+	if sel.Get("anchorOffset").Int() != 1 || sel.Get("focusOffset").Int() != 0 {
+		t.Fatalf("got offsets %d-%d want 1-0", sel.Get("anchorOffset").Int(), sel.Get("focusOffset").Int())
+	}
+	sel.Call("collapse", a, 3)
+	if !sel.Get("isCollapsed").Bool() {
+		t.Fatal("expected selection to be collapsed")
+	}
+}
+
+// TestMemoryDOM_Selectors tests the CSS selectors supported by the in-memory
+// DOM.
+func TestMemoryDOM_Selectors(t *testing.T) {
+	ResetDOM()
+
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			Tag("ul", Markup(Class("list")),
+				Tag("li", Markup(Property("id", "one"), Class("a", "b"))),
+				Tag("li", Markup(Property("id", "two"), Attribute("data-x", "foo bar"))),
+				Tag("li", Markup(Property("id", "three"), Property("disabled", true))),
+			),
+			Tag("p", Markup(Property("id", "para"))),
+		)
+	}})
+	doc := global().Get("document")
+
+	cases := map[string]string{
+		"li":                       "one two three",
+		"*#two":                    "two",
+		".a.b":                     "one",
+		"ul.list > li:first-child": "one",
+		"li:last-child":            "three",
+		"li + li":                  "two three",
+		"ul ~ p":                   "para",
+		`[data-x~="bar"]`:          "two",
+		"[data-x^=foo]":            "two",
+		"li:disabled, p":           "three para",
+		"li:not(.a, :disabled)":    "two",
+		"body li:not([id$=o])":     "one three",
+	}
+	for sel, want := range cases {
+		list := doc.Call("querySelectorAll", sel)
+		var got string
+		for i := 0; i < list.Get("length").Int(); i++ {
+			if i > 0 {
+				got += " "
+			}
+			got += list.Call("item", i).Get("id").String()
+		}
+		if got != want {
+			t.Errorf("querySelectorAll(%q) = %q want %q", sel, got, want)
+		}
+	}
+
+	got := recoverStr(func() { doc.Call("querySelector", "li::before") })
+	want := `vecty: in-memory DOM: SyntaxError: "li::before" is not a valid (or supported) selector`
+	if got != want {
+		t.Fatalf("got panic %q want %q", got, want)
+	}
+}
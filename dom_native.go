@@ -4,21 +4,27 @@ package vecty
 
 import "strings"
 
-// Stubs for building Vecty under a native GOOS and GOARCH, so that Vecty
+// Support for building Vecty under a native GOOS and GOARCH, so that Vecty
 // type-checks, lints, auto-completes, and serves documentation under godoc.org
 // as with any other normal Go package that is not under GOOS=js and
-// GOARCH=wasm.
+// GOARCH=wasm. Under a native GOOS and GOARCH, components are rendered into an
+// in-memory DOM (see ResetDOM), or to HTML using RenderToString.
 
 // SyscallJSValue is an actual syscall/js.Value type under WebAssembly compilation.
 //
-// It is declared here just for purposes of testing Vecty under native
-// 'go test', linting, and serving documentation under godoc.org.
+// Under a native GOOS and GOARCH it is a value in the in-memory DOM, which
+// supports the subset of the syscall/js.Value API used by Vecty.
 type SyscallJSValue jsObject
 
 // Event represents a DOM event.
 type Event struct {
 	Value  SyscallJSValue
 	Target SyscallJSValue
+
+	// CurrentTarget is the element whose EventListener is being invoked.
+	// Because event listeners are delegated to the render root, the
+	// currentTarget property of the underlying event is the root instead.
+	CurrentTarget SyscallJSValue
 }
 
 // Node returns the underlying JavaScript Element or TextNode.
@@ -38,6 +44,43 @@ func RenderIntoNode(node SyscallJSValue, c Component) error {
 	return renderIntoNode("RenderIntoNode", node, c)
 }
 
+// NewRootNode is like NewRoot, except that the root renders into the given
+// existing HTML element by replacing it.
+func NewRootNode(node SyscallJSValue) (*Root, error) {
+	return newRoot("NewRootNode", node)
+}
+
+// HydrateIntoNode renders the given component into the existing HTML element,
+// adopting the element and its children instead of replacing them. This allows
+// markup rendered by RenderToString on a server to become interactive without
+// being rebuilt, which would otherwise lose e.g. focus and scroll position.
+//
+// If the Component's Render method does not return an element of the same type,
+// an error of type ElementMismatchError is returned.
+//
+// If the existing children do not match what the component renders, the
+// component is rendered from scratch as with RenderIntoNode, and an error of
+// type HydrationMismatchError describing the first mismatch is returned.
+//
+// If the document is still loading, hydration is deferred until the
+// DOMContentLoaded event, and any such error is instead passed to the handler
+// set by OnError.
+func HydrateIntoNode(node SyscallJSValue, c Component) error {
+	return hydrateIntoNode("HydrateIntoNode", node, c)
+}
+
+// Portal returns HTML which renders the given children into the target DOM
+// node (e.g. document.body) instead of in place, such as for modals and
+// tooltips which must escape the overflow and stacking context of their
+// parent. The children otherwise behave as children of the portal's parent:
+// they are mounted, re-rendered and unmounted along with it.
+//
+// In place, a portal renders as an empty text node. Portal content is not
+// rendered by RenderToString.
+func Portal(target SyscallJSValue, children ...ComponentOrHTML) *HTML {
+	return newPortal("Portal", target, children)
+}
+
 func toLower(s string) string {
 	return strings.ToLower(s)
 }
@@ -45,9 +88,22 @@ func toLower(s string) string {
 var globalValue jsObject
 
 func global() jsObject {
+	if globalValue == nil {
+		globalValue = newMemWindow()
+	}
 	return globalValue
 }
 
+// newEvent wraps a DOM event for use by an EventListener.
+func newEvent(jsEvent, currentTarget jsObject) *Event {
+	return &Event{Value: jsEvent, Target: jsEvent.Get("target"), CurrentTarget: currentTarget}
+}
+
+// keepAlive is called after the initial render by RenderBody. Under a native
+// GOOS and GOARCH there is no browser event loop to keep alive, so it returns
+// immediately.
+func keepAlive() {}
+
 func undefined() wrappedObject {
 	return wrappedObject{j: &jsObjectImpl{}}
 }
@@ -80,12 +136,13 @@ func (e *jsObjectImpl) Equal(other jsObject) bool {
 
 var (
 	htmlNodeImpl = func(h *HTML) SyscallJSValue {
-		panic("not implemented on this architecture in non-testing environment")
+		if h.node == nil {
+			panic("vecty: cannot call (*HTML).Node() before DOM node creation / component mount")
+		}
+		return h.node
 	}
 	funcOfImpl = func(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
-		panic("not implemented on this architecture in non-testing environment")
-	}
-	valueOfImpl = func(v interface{}) jsObject {
-		panic("not implemented on this architecture in non-testing environment")
+		return &jsFuncImpl{goFunc: fn}
 	}
+	valueOfImpl = memValueOf
 )
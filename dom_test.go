@@ -309,12 +309,9 @@ func TestHTML_reconcile_std(t *testing.T) {
 		}
 		prev := Tag("div", Markup(initEventListeners...))
 		prev.reconcile(nil)
-		ts.record("(expected two added event listeners above)")
-		for i, m := range initEventListeners {
-			listener := m.(*EventListener)
-			if listener.wrapper == nil {
-				t.Fatalf("listener %d wrapper == nil: %+v", i, listener)
-			}
+		ts.record("(expected listeners ID set above)")
+		if events.elements[prev.listenersID] != prev {
+			t.Fatal("event listeners not registered")
 		}
 
 		targetEventListeners := []Applyer{
@@ -322,12 +319,18 @@ func TestHTML_reconcile_std(t *testing.T) {
 		}
 		h := Tag("div", Markup(targetEventListeners...))
 		h.reconcile(prev)
-		ts.record("(expected two removed, one added event listeners above)")
-		for i, m := range targetEventListeners {
-			listener := m.(*EventListener)
-			if listener.wrapper == nil {
-				t.Fatalf("listener %d wrapper == nil: %+v", i, listener)
-			}
+		ts.record("(expected no event listener changes above)")
+		if h.listenersID != prev.listenersID || events.elements[h.listenersID] != h {
+			t.Fatal("event listeners not registered")
+		}
+		if got, want := events.types, []string{"click", "keydown"}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("got event types %v, want %v", got, want)
+		}
+
+		h2 := Tag("div")
+		h2.reconcile(h)
+		if _, ok := events.elements[h.listenersID]; ok {
+			t.Fatal("removed event listeners still registered")
 		}
 	})
 
@@ -422,11 +425,8 @@ func TestHTML_reconcile_nil(t *testing.T) {
 		e1 := &EventListener{Name: "keydown"}
 		h := Tag("div", Markup(e0, e1))
 		h.reconcile(nil)
-		if e0.wrapper == nil {
-			t.Fatal("e0.wrapper == nil")
-		}
-		if e1.wrapper == nil {
-			t.Fatal("e1.wrapper == nil")
+		if h.listenersID == 0 || events.elements[h.listenersID] != h {
+			t.Fatal("event listeners not registered")
 		}
 	})
 	t.Run("children", func(t *testing.T) {
@@ -549,7 +549,7 @@ func TestRerender_no_prevRender(t *testing.T) {
 			},
 		})
 	})
-	want := "vecty: Rerender invoked on Component that has never been rendered"
+	want := "vecty: vecty.componentFunc: Rerender invoked on Component that has never been rendered"
 	if got != want {
 		t.Fatalf("got panic %q expected %q", got, want)
 	}
@@ -1121,6 +1121,7 @@ func TestAddStylesheet(t *testing.T) {
 	ts := testSuite(t)
 	defer ts.done()
 
+	ts.ints.mock(`global.Get("document").Get("head").Call("querySelectorAll", "link[rel=\"stylesheet\"]").Get("length")`, 0)
 	AddStylesheet("https://google.com/foobar.css")
 }
 
@@ -1169,3 +1170,4 @@ func TestKeyedChild_DifferentType(t *testing.T) {
 
 	rerender()
 }
+
@@ -4,5 +4,6 @@ func replaceNode(newNode, oldNode jsObject) {
 	if newNode.Equal(oldNode) {
 		return
 	}
+	domOps++
 	oldNode.Get("parentNode").Call("replaceChild", newNode, oldNode)
 }
@@ -0,0 +1,220 @@
+package vecty
+
+import "reflect"
+
+// ErrorBoundary is an optional interface that a Component can implement in
+// order to catch panics which occur while rendering its descendants, such that
+// one broken component does not take down the whole application.
+//
+// If rendering a descendant of an ErrorBoundary panics, either in its Render
+// method or while applying its rendered HTML to the DOM, the descendants are
+// unmounted and discarded, and the component renders the fallback returned by
+// RenderError in their place. This applies both when the ErrorBoundary itself
+// is rendered, and when a descendant is re-rendered on its own.
+//
+// Panics in the ErrorBoundary's own Render and RenderError methods are not
+// caught by it, but by the nearest ErrorBoundary above it, if any.
+type ErrorBoundary interface {
+	// RenderError is called when rendering a descendant of the component
+	// panics, and returns the fallback to render instead, as Render would.
+	//
+	// The next time the component is re-rendered, Render is called again as
+	// usual, e.g. allowing the descendants to be retried.
+	RenderError(err RenderPanicError) ComponentOrHTML
+}
+
+// RenderPanicError describes a panic which occurred while rendering a
+// component, as caught by an ErrorBoundary.
+type RenderPanicError struct {
+	// Value is the value recovered from the panic.
+	Value interface{}
+
+	// Component is the innermost component being rendered when the panic
+	// occurred, and ComponentType is its type (e.g. *main.MyComponent).
+	Component     Component
+	ComponentType reflect.Type
+}
+
+func (e RenderPanicError) Error() string {
+	return "vecty: panic while rendering " + e.ComponentType.String() + ": " + panicString(e.Value)
+}
+
+// panicString describes a value recovered from a panic, without relying on
+// the fmt package.
+func panicString(v interface{}) string {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case string:
+		return v
+	case interface{ String() string }:
+		return v.String()
+	default:
+		return "panic value of type " + reflect.TypeOf(v).String()
+	}
+}
+
+// recoverRenderPanic converts a value recovered from a panic while rendering
+// c into a RenderPanicError, and unwinds the rendering stack to the given
+// depth.
+func recoverRenderPanic(r interface{}, c Component, depth int) RenderPanicError {
+	failing := c
+	if len(rendering) > 0 {
+		failing = rendering[len(rendering)-1]
+	}
+	rendering = rendering[:depth]
+	return RenderPanicError{
+		Value:         r,
+		Component:     failing,
+		ComponentType: reflect.TypeOf(failing),
+	}
+}
+
+// nearestErrorBoundary returns c, or its nearest ancestor, which implements
+// ErrorBoundary, or nil if there is none.
+func nearestErrorBoundary(c Component) Component {
+	for ; c != nil; c = c.Context().parent {
+		if _, ok := c.(ErrorBoundary); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// renderErrorBoundary is like reconcileRender, except that if reconciling the
+// render of the ErrorBoundary c panics, its fallback is rendered instead.
+func renderErrorBoundary(c Component, nextRender, prev ComponentOrHTML, parent *HTML) (nextHTML *HTML, skip bool, pendingMounts []Mounter) {
+	depth := len(rendering)
+	r, discarded := guardRender(func() {
+		nextHTML, skip, pendingMounts = reconcileRender(c, nextRender, prev, parent)
+	})
+	if r == nil {
+		return nextHTML, skip, pendingMounts
+	}
+	nextHTML, pendingMounts = renderFallback(c, recoverRenderPanic(r, c, depth), nextRender, discarded, parent)
+	return nextHTML, false, pendingMounts
+}
+
+var (
+	// guards is the number of renders guarded by guardRender in progress, and
+	// reconciled the elements reconciled by them.
+	guards     int
+	reconciled []*HTML
+)
+
+// guardRender calls render, which renders descendants of an ErrorBoundary,
+// and returns the value it panicked with, if any, along with the elements it
+// reconciled before panicking. As those elements were never mounted, they must
+// be discarded when the ErrorBoundary renders its fallback.
+func guardRender(render func()) (r interface{}, discarded []*HTML) {
+	guards++
+	start := len(reconciled)
+	defer func() {
+		guards--
+		if r = recover(); r != nil {
+			discarded = append(discarded, reconciled[start:]...)
+			reconciled = reconciled[:start]
+		}
+		if guards == 0 {
+			reconciled = nil
+		}
+	}()
+	render()
+	return nil, nil
+}
+
+// discard releases the event listeners and portal content of an element whose
+// render was discarded.
+func (h *HTML) discard() {
+	events.remove(h)
+	if h.portal != nil {
+		h.portal.discard()
+	}
+}
+
+// renderFallback renders the fallback of the ErrorBoundary c in place of its
+// previous render, after rendering failed with err. partial is the render of c
+// which panicked, if any, and discarded the elements reconciled by the render
+// which panicked, as returned by guardRender.
+//
+// The returned HTML must be placed by the caller in the same way as for
+// renderComponent.
+func renderFallback(c Component, err RenderPanicError, partial ComponentOrHTML, discarded []*HTML, parent *HTML) (*HTML, []Mounter) {
+	fallback := normalizeRender(c.(ErrorBoundary).RenderError(err))
+
+	// The DOM nodes of the partial render are in an unknown state, so discard
+	// them along with the previous render, and render the fallback from
+	// scratch. The node of a previous HTML render, if any, is retained for the
+	// caller to replace.
+	prevRender := c.Context().prevRender
+	if prevRender != nil {
+		unmount(prevRender)
+	}
+	// The elements of the partial render were never mounted, so release their
+	// event listeners and portal content, once those of the previous render
+	// which they replaced have been.
+	for _, h := range discarded {
+		h.discard()
+	}
+	prevHTML := extractHTML(prevRender)
+	var keep jsObject
+	if prevHTML != nil {
+		keep = prevHTML.node
+	}
+	discardNodes(parent, appendNodes(appendNodes(nil, partial), prevRender), keep)
+
+	var prev ComponentOrHTML
+	if _, ok := fallback.(KeyedList); ok && prevHTML != nil {
+		// A fragment is inserted in place of the previous node.
+		prev = prevHTML
+	}
+	c.Context().prevRender = nil
+	nextHTML, _, pendingMounts := reconcileRender(c, fallback, prev, parent)
+	return nextHTML, pendingMounts
+}
+
+// appendNodes appends the top-level DOM nodes rendered by e to nodes.
+func appendNodes(nodes []jsObject, e ComponentOrHTML) []jsObject {
+	switch v := e.(type) {
+	case *HTML:
+		if v != nil && v.node != nil {
+			nodes = append(nodes, v.node)
+		}
+	case Component:
+		nodes = appendNodes(nodes, v.Context().prevRender)
+	case KeyedList:
+		for _, child := range v.html.children {
+			nodes = appendNodes(nodes, child)
+		}
+	}
+	return nodes
+}
+
+// discardNodes removes the given DOM nodes, except keep, from the DOM if they
+// are attached. The parent's insertBeforeNode is advanced past them.
+func discardNodes(parent *HTML, nodes []jsObject, keep jsObject) {
+	discarded := func(node jsObject) bool {
+		if keep != nil && keep.Equal(node) {
+			return false
+		}
+		for _, n := range nodes {
+			if n.Equal(node) {
+				return true
+			}
+		}
+		return false
+	}
+	if parent != nil {
+		for parent.insertBeforeNode != nil && discarded(parent.insertBeforeNode) {
+			parent.insertBeforeNode = parent.insertBeforeNode.Get("nextSibling")
+		}
+	}
+	for _, node := range nodes {
+		if keep != nil && keep.Equal(node) {
+			continue
+		}
+		if parentNode := node.Get("parentNode"); parentNode != nil {
+			parentNode.Call("removeChild", node)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// +build !js
+
+package vecty
+
+import (
+	"reflect"
+	"testing"
+)
+
+// errorBoundary is an ErrorBoundary which renders the result of render, and
+// records the error it last caught.
+type errorBoundary struct {
+	Core
+	render func() ComponentOrHTML
+	err    *RenderPanicError
+}
+
+func (b *errorBoundary) Render() ComponentOrHTML { return b.render() }
+
+func (b *errorBoundary) RenderError(err RenderPanicError) ComponentOrHTML {
+	b.err = &err
+	return Tag("p", Text("error"))
+}
+
+// brokenComponent renders a span, or panics while rendering if broken is set.
+type brokenComponent struct {
+	Core
+	broken    bool
+	mounted   int
+	unmounted int
+}
+
+func (c *brokenComponent) Render() ComponentOrHTML {
+	if c.broken {
+		panic("broken")
+	}
+	return Tag("span", Text("ok"))
+}
+
+func (c *brokenComponent) Mount()   { c.mounted++ }
+func (c *brokenComponent) Unmount() { c.unmounted++ }
+
+// TestErrorBoundary tests that an ErrorBoundary renders its fallback
+// in place of descendants which panic, both when the boundary is rendered and
+// when the descendant is re-rendered on its own.
+func TestErrorBoundary(t *testing.T) {
+	widget := &brokenComponent{}
+	fragmentWidget := &brokenComponent{}
+	boundary := &errorBoundary{render: func() ComponentOrHTML {
+		return Tag("div", Text("a"), widget)
+	}}
+	fragmentBoundary := &errorBoundary{render: func() ComponentOrHTML {
+		return List{Text("b"), fragmentWidget}
+	}}
+	renderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", boundary, Tag("hr"), fragmentBoundary, Tag("hr"))
+	}})
+	checkHTML(t, memBody(), `<div>a<span>ok</span></div><hr>b<span>ok</span><hr>`)
+
+	// A descendant re-rendered on its own panics.
+	widget.broken = true
+	fragmentWidget.broken = true
+	Rerender(widget)
+	Rerender(fragmentWidget)
+	RunAnimationFrames()
+	checkHTML(t, memBody(), `<p>error</p><hr><p>error</p><hr>`)
+	if boundary.err == nil || boundary.err.Component != widget {
+		t.Fatalf("got error %v, want error for widget", boundary.err)
+	}
+	want := "vecty: panic while rendering *vecty.brokenComponent: broken"
+	if got := boundary.err.Error(); got != want {
+		t.Fatalf("got error %q want %q", got, want)
+	}
+	if widget.unmounted != 1 || fragmentWidget.unmounted != 1 {
+		t.Fatalf("got %d and %d unmounts, want 1", widget.unmounted, fragmentWidget.unmounted)
+	}
+
+	// Re-rendering the boundaries retries rendering the descendants.
+	widget.broken = false
+	fragmentWidget.broken = false
+	Rerender(boundary)
+	Rerender(fragmentBoundary)
+	RunAnimationFrames()
+	checkHTML(t, memBody(), `<div>a<span>ok</span></div><hr>b<span>ok</span><hr>`)
+	if widget.mounted != 2 || fragmentWidget.mounted != 2 {
+		t.Fatalf("got %d and %d mounts, want 2", widget.mounted, fragmentWidget.mounted)
+	}
+
+	// A descendant panics while the boundary is rendered, after part of the
+	// boundary's render has been applied to the DOM.
+	widget.broken = true
+	fragmentWidget.broken = true
+	Rerender(boundary)
+	Rerender(fragmentBoundary)
+	RunAnimationFrames()
+	checkHTML(t, memBody(), `<p>error</p><hr><p>error</p><hr>`)
+	if fragmentBoundary.err == nil || fragmentBoundary.err.ComponentType != reflect.TypeOf(fragmentWidget) {
+		t.Fatalf("got error %v, want error for fragmentWidget", fragmentBoundary.err)
+	}
+}
+
+// TestErrorBoundary_discard tests that the event listeners and portal content
+// of a render which panics are released when the ErrorBoundary renders its
+// fallback, both when the boundary is rendered and when a descendant is
+// re-rendered on its own.
+func TestErrorBoundary_discard(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	target := doc.Call("createElement", "div")
+	doc.Get("documentElement").Call("appendChild", target)
+
+	listener := func() *EventListener {
+		return &EventListener{Name: "click", Listener: func(*Event) {}}
+	}
+	widget := &brokenComponent{}
+	partial := &componentFunc{render: func() ComponentOrHTML {
+		return Tag("div",
+			Tag("button", Markup(listener(), listener().Passive())),
+			Portal(target, Tag("a", Markup(listener()))),
+			widget,
+		)
+	}}
+	boundary := &errorBoundary{render: func() ComponentOrHTML {
+		return Tag("section", partial)
+	}}
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", boundary)
+	}})
+	if len(events.elements) != 2 || len(events.roots) != 2 {
+		t.Fatalf("got %d elements and %d roots, want 2 and 2", len(events.elements), len(events.roots))
+	}
+	check := func(button jsObject) {
+		t.Helper()
+		checkHTML(t, memBody(), "<p>error</p>")
+		if len(events.elements) != 0 || len(events.roots) != 1 {
+			t.Fatalf("got %d elements and %d roots, want 0 and 1", len(events.elements), len(events.roots))
+		}
+		if got := target.Get("innerHTML").String(); got != "" {
+			t.Fatalf("got portal content %q, want none", got)
+		}
+		if button != nil && len(button.(*memNode).listeners) != 0 {
+			t.Fatalf("got %d native listeners, want 0", len(button.(*memNode).listeners))
+		}
+	}
+
+	// A descendant re-rendered on its own panics.
+	button := doc.Call("querySelector", "button")
+	widget.broken = true
+	Rerender(partial)
+	RunAnimationFrames()
+	check(button)
+
+	// A descendant panics while the boundary is rendered.
+	widget.broken = false
+	Rerender(boundary)
+	RunAnimationFrames()
+	button = doc.Call("querySelector", "button")
+	widget.broken = true
+	Rerender(boundary)
+	RunAnimationFrames()
+	check(button)
+
+	// A descendant panics when the boundary is first rendered.
+	ResetDOM()
+	doc = global().Get("document")
+	target = doc.Call("createElement", "div")
+	doc.Get("documentElement").Call("appendChild", target)
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", boundary)
+	}})
+	check(nil)
+}
@@ -0,0 +1,138 @@
+package vecty
+
+// errorHandler is the function set by OnError, or nil if errors panic.
+var errorHandler func(err error)
+
+// OnError sets the function which handles errors caused by misuse of Vecty,
+// such as duplicate sibling keys or a call to Rerender with a component which
+// was never rendered. By default, such errors cause a panic with the error as
+// its value.
+//
+// If fn is not nil, it is called with each error instead, and Vecty recovers
+// from the misuse as well as it can, e.g. by not reusing the DOM nodes of
+// children with missing or duplicate keys, such that an application can log
+// the error and keep running:
+//
+// 	vecty.OnError(func(err error) {
+// 		js.Global().Get("console").Call("error", err.Error())
+// 	})
+//
+// The errors are of the types MissingKeyError, DuplicateKeyError,
+// NotRenderedError, IdenticalCopyError, StylePropertyError and ClassNameError,
+// as well as the errors of RenderBody and HydrateBody, which cannot return
+// them. Calling OnError with nil restores the default.
+func OnError(fn func(err error)) {
+	errorHandler = fn
+}
+
+// handleError passes err to the function set by OnError, or panics with err if
+// there is none.
+func handleError(err error) {
+	if errorHandler == nil {
+		panic(err)
+	}
+	errorHandler(err)
+}
+
+// errorPath returns the path to the component c in the component tree, or if
+// c is nil, to the component being rendered, if any.
+func errorPath(c Component) string {
+	if c == nil {
+		if len(rendering) == 0 {
+			return ""
+		}
+		c = rendering[len(rendering)-1]
+	}
+	return componentPath(c)
+}
+
+// errorString formats the message of an error concerning the component with
+// the given path.
+func errorString(path, msg string) string {
+	if path == "" {
+		return "vecty: " + msg
+	}
+	return "vecty: " + path + ": " + msg
+}
+
+// MissingKeyError is reported when a child has no key, but one of its siblings
+// rendered before it has a key. The child is re-created on every render.
+type MissingKeyError struct {
+	// Path is the path to the component which rendered the child, e.g.
+	// "main.PageView > main.ItemList", or empty if there is none.
+	Path string
+}
+
+func (e MissingKeyError) Error() string {
+	return errorString(e.Path, "all siblings must have keys when using keyed elements")
+}
+
+// DuplicateKeyError is reported when a child has the same key as one of its
+// siblings rendered before it. The child is re-created on every render.
+type DuplicateKeyError struct {
+	// Path is the path to the component which rendered the child, or empty if
+	// there is none.
+	Path string
+
+	// Key is the duplicate key.
+	Key interface{}
+}
+
+func (e DuplicateKeyError) Error() string {
+	return errorString(e.Path, "duplicate sibling key")
+}
+
+// NotRenderedError is reported when Rerender is called with a component which
+// has never been rendered. The call has no effect.
+type NotRenderedError struct {
+	// Path is the path to the component, or simply its type if it was never
+	// rendered as a child of another component.
+	Path string
+
+	Component Component
+}
+
+func (e NotRenderedError) Error() string {
+	return errorString(e.Path, "Rerender invoked on Component that has never been rendered")
+}
+
+// IdenticalCopyError is reported when a component's Copy method returns the
+// component itself, rather than a copy. A shallow copy is made instead.
+type IdenticalCopyError struct {
+	// Path is the path to the component, or empty if there is none.
+	Path string
+
+	Component Component
+}
+
+func (e IdenticalCopyError) Error() string {
+	return errorString(e.Path, "Component.Copy illegally returned an identical *MyComponent pointer")
+}
+
+// StylePropertyError is reported when Property is called with the key "style".
+// The property is not applied.
+type StylePropertyError struct {
+	// Path is the path to the component being rendered, or empty if there is
+	// none.
+	Path string
+}
+
+func (e StylePropertyError) Error() string {
+	return errorString(e.Path, `Property called with key "style"; style package or Style should be used instead`)
+}
+
+// ClassNameError is reported when Class is called with a class name which
+// contains a space. The space separated class names it contains are applied
+// instead.
+type ClassNameError struct {
+	// Path is the path to the component being rendered, or empty if there is
+	// none.
+	Path string
+
+	// Class is the invalid class name.
+	Class string
+}
+
+func (e ClassNameError) Error() string {
+	return errorString(e.Path, `invalid argument to vecty.Class "`+e.Class+`" (string may not contain spaces)`)
+}
@@ -0,0 +1,88 @@
+// +build !js
+
+package vecty
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// selfCopier is a component whose Copy method illegally returns itself.
+type selfCopier struct {
+	Core
+	Name string `vecty:"prop"`
+}
+
+func (c *selfCopier) Copy() Component { return c }
+
+func (c *selfCopier) Render() ComponentOrHTML {
+	return Tag("i", Text(c.Name))
+}
+
+// TestOnError tests that misuse of Vecty panics with a typed error when no
+// handler is set, and is otherwise passed to the handler set by OnError, with
+// rendering recovering as well as it can.
+func TestOnError(t *testing.T) {
+	ResetDOM()
+
+	// Without a handler, errors panic with a typed error.
+	func() {
+		defer func() {
+			if _, ok := recover().(NotRenderedError); !ok {
+				t.Fatal("expected NotRenderedError panic")
+			}
+		}()
+		Rerender(&componentFunc{})
+	}()
+
+	var errs []string
+	OnError(func(err error) {
+		errs = append(errs, reflect.TypeOf(err).Name()+": "+err.Error())
+	})
+	defer OnError(nil)
+
+	keys := []interface{}{"a", "a", nil}
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		var items List
+		for _, k := range keys {
+			items = append(items, Tag("li", Markup(Key(k))))
+		}
+		return Tag("body",
+			Markup(Class("x y"), Property("style", "color: red")),
+			Tag("ul", items),
+			&selfCopier{Name: "n"},
+		)
+	}}
+	RenderBody(comp)
+	RerenderSync(comp)
+	Rerender(&componentFunc{})
+
+	want := []string{
+		`ClassNameError: vecty: vecty.componentFunc: invalid argument to vecty.Class "x y" (string may not contain spaces)`,
+		`StylePropertyError: vecty: vecty.componentFunc: Property called with key "style"; style package or Style should be used instead`,
+		`DuplicateKeyError: vecty: vecty.componentFunc: duplicate sibling key`,
+		`MissingKeyError: vecty: vecty.componentFunc: all siblings must have keys when using keyed elements`,
+		`IdenticalCopyError: vecty: vecty.componentFunc > vecty.selfCopier: Component.Copy illegally returned an identical *MyComponent pointer`,
+		`ClassNameError: vecty: vecty.componentFunc: invalid argument to vecty.Class "x y" (string may not contain spaces)`,
+		`StylePropertyError: vecty: vecty.componentFunc: Property called with key "style"; style package or Style should be used instead`,
+		`DuplicateKeyError: vecty: vecty.componentFunc: duplicate sibling key`,
+		`MissingKeyError: vecty: vecty.componentFunc: all siblings must have keys when using keyed elements`,
+		`IdenticalCopyError: vecty: vecty.componentFunc > vecty.selfCopier: Component.Copy illegally returned an identical *MyComponent pointer`,
+		`NotRenderedError: vecty: vecty.componentFunc: Rerender invoked on Component that has never been rendered`,
+	}
+	if strings.Join(errs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(errs, "\n"), strings.Join(want, "\n"))
+	}
+
+	body := global().Get("document").Get("body")
+	if classes := body.Get("classList"); classes.Get("length").Int() != 2 || !classes.Call("contains", "x").Bool() || !classes.Call("contains", "y").Bool() {
+		t.Fatalf("got className %q want classes x and y", body.Get("className").String())
+	}
+	if got := body.Call("querySelectorAll", "li").Get("length").Int(); got != 3 {
+		t.Fatalf("got %d list items, want 3", got)
+	}
+	if got := body.Call("querySelector", "i").Get("textContent").String(); got != "n" {
+		t.Fatalf("got %q, want copied component to render", got)
+	}
+}
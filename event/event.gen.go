@@ -1161,6 +1161,9 @@ func TouchEnd(listener func(*vecty.Event)) *vecty.EventListener {
 // TouchMove is an event fired when a touch point is moved along the touch
 // surface.
 //
+// Listeners which do not call PreventDefault should be made Passive, such that
+// the browser need not wait for them before scrolling.
+//
 // https://developer.mozilla.org/docs/Web/Events/touchmove
 func TouchMove(listener func(*vecty.Event)) *vecty.EventListener {
 	return &vecty.EventListener{Name: "touchmove", Listener: listener}
@@ -1169,6 +1172,9 @@ func TouchMove(listener func(*vecty.Event)) *vecty.EventListener {
 // TouchStart is an event fired when a touch point is placed on the touch
 // surface.
 //
+// Listeners which do not call PreventDefault should be made Passive, such that
+// the browser need not wait for them before scrolling.
+//
 // https://developer.mozilla.org/docs/Web/Events/touchstart
 func TouchStart(listener func(*vecty.Event)) *vecty.EventListener {
 	return &vecty.EventListener{Name: "touchstart", Listener: listener}
@@ -1258,6 +1264,9 @@ func Waiting(listener func(*vecty.Event)) *vecty.EventListener {
 // Wheel is an event fired when a wheel button of a pointing device is rotated
 // in any direction.
 //
+// Listeners which do not call PreventDefault should be made Passive, such that
+// the browser need not wait for them before scrolling.
+//
 // https://developer.mozilla.org/docs/Web/Events/wheel
 func Wheel(listener func(*vecty.Event)) *vecty.EventListener {
 	return &vecty.EventListener{Name: "wheel", Listener: listener}
@@ -11,6 +11,21 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// passiveEvents are the events which the browser may need to wait for
+// listeners of before scrolling, and so whose listeners should be passive when
+// possible.
+var passiveEvents = map[string]bool{
+	"touchmove":  true,
+	"touchstart": true,
+	"wheel":      true,
+}
+
+// passiveNote is appended to the documentation of passiveEvents.
+const passiveNote = `
+//
+// Listeners which do not call PreventDefault should be made Passive, such that
+// the browser need not wait for them before scrolling.`
+
 type Event struct {
 	Name string
 	Link string
@@ -193,13 +208,17 @@ import "github.com/hexops/vecty"
 		if e.Spec == "WebVR API" {
 			continue // not stabilized
 		}
+		doc := descToComments(e.Desc)
+		if passiveEvents[e.Name] {
+			doc += passiveNote
+		}
 		fmt.Fprintf(file, `%s
 //
 // https://developer.mozilla.org%s
 func %s(listener func(*vecty.Event)) *vecty.EventListener {
 	return &vecty.EventListener{Name: "%s", Listener: listener}
 }
-`, descToComments(e.Desc), e.Link[6:], name, e.Name)
+`, doc, e.Link[6:], name, e.Name)
 	}
 }
 
@@ -0,0 +1,320 @@
+package vecty
+
+// listenersProperty is the property of DOM elements with event listeners which
+// holds the ID under which their listeners are registered for dispatch.
+const listenersProperty = "__vectyListeners"
+
+// dispatchedProperty is the property set on events once they have been
+// dispatched to the listeners of their path, such that an event is only
+// dispatched once when it bubbles through more than one render root.
+const dispatchedProperty = "__vectyDispatched"
+
+// delegator implements event delegation: rather than adding native listeners
+// to every element with EventListeners, a single native listener for each
+// event type is added to each render root, which dispatches events to the
+// EventListeners of the elements in their path.
+type delegator struct {
+	// roots are the nodes with native listeners.
+	roots []*delegatedRoot
+	// types are the event types listened for on the roots, in the order they
+	// were first used.
+	types []string
+	// elements maps element IDs to the HTML whose listeners are dispatched
+	// to.
+	elements map[int]*HTML
+	nextID   int
+	// capture and bubble are the native listeners, added in the capture phase
+	// for events which do not bubble, and in the bubble phase for those which
+	// do.
+	capture, bubble jsFunc
+}
+
+// delegatedRoot is a node with native listeners.
+type delegatedRoot struct {
+	node jsObject
+	// refs is the number of times the node was added as a root, e.g. by a
+	// Root and by portals targeting it, less the times it was removed.
+	refs int
+}
+
+// events is the event delegator singleton.
+var events = &delegator{}
+
+// addRoot adds native listeners for all event types to the given node, if it
+// does not have them already.
+func (d *delegator) addRoot(node jsObject) {
+	d.addRootRefs(node, 1)
+}
+
+// addRootRefs adds refs references to the given node as a root.
+func (d *delegator) addRootRefs(node jsObject, refs int) {
+	for _, root := range d.roots {
+		if root.node.Equal(node) {
+			root.refs += refs
+			return
+		}
+	}
+	d.roots = append(d.roots, &delegatedRoot{node: node, refs: refs})
+	for _, typ := range d.types {
+		d.listen(node, typ)
+	}
+}
+
+// removeRoot removes a reference to the given node as a root, removing its
+// native listeners once no references remain. The native listeners are
+// released once there are no roots.
+func (d *delegator) removeRoot(node jsObject) {
+	for _, root := range d.roots {
+		if root.node.Equal(node) {
+			root.refs--
+			if root.refs <= 0 {
+				d.unlistenRoot(root)
+			}
+			return
+		}
+	}
+}
+
+// unlistenRoot removes the root and its native listeners.
+func (d *delegator) unlistenRoot(root *delegatedRoot) {
+	for i, r := range d.roots {
+		if r == root {
+			d.roots = append(d.roots[:i:i], d.roots[i+1:]...)
+			break
+		}
+	}
+	for _, typ := range d.types {
+		root.node.Call("removeEventListener", typ, d.capture, true)
+		root.node.Call("removeEventListener", typ, d.bubble, false)
+	}
+	if len(d.roots) == 0 && d.capture != nil {
+		d.capture.Release()
+		d.bubble.Release()
+		d.capture, d.bubble = nil, nil
+	}
+}
+
+// replaceRoot replaces the root prev, if it is one, with next, e.g. when a
+// component rendered at the root renders a different element.
+func (d *delegator) replaceRoot(prev, next jsObject) {
+	if prev.Equal(next) {
+		return
+	}
+	for _, root := range d.roots {
+		if root.node.Equal(prev) {
+			refs := root.refs
+			// Add next before removing prev, such that the native
+			// listeners are not released meanwhile.
+			d.addRootRefs(next, refs)
+			d.unlistenRoot(root)
+			return
+		}
+	}
+}
+
+// addType adds native listeners for the given event type to all roots, if they
+// do not have them already.
+func (d *delegator) addType(typ string) {
+	for _, t := range d.types {
+		if t == typ {
+			return
+		}
+	}
+	d.types = append(d.types, typ)
+	for _, root := range d.roots {
+		d.listen(root.node, typ)
+	}
+}
+
+// listen adds the native listeners for the event type to the node.
+func (d *delegator) listen(node jsObject, typ string) {
+	if d.capture == nil {
+		d.capture = funcOf(func(this jsObject, args []jsObject) interface{} {
+			if !args[0].Get("bubbles").Bool() {
+				d.dispatch(args[0])
+			}
+			return undefined()
+		})
+		d.bubble = funcOf(func(this jsObject, args []jsObject) interface{} {
+			if args[0].Get("bubbles").Bool() {
+				d.dispatch(args[0])
+			}
+			return undefined()
+		})
+	}
+	node.Call("addEventListener", typ, d.capture, true)
+	node.Call("addEventListener", typ, d.bubble, false)
+}
+
+// update registers the EventListeners of h, reconciled against prev, for
+// dispatch.
+func (d *delegator) update(h, prev *HTML) {
+	if h.node.Equal(prev.node) {
+		h.listenersID = prev.listenersID
+	}
+	if len(h.eventListeners) == 0 {
+		d.remove(prev)
+		return
+	}
+	if h.listenersID == 0 {
+		d.nextID++
+		h.listenersID = d.nextID
+		h.node.Set(listenersProperty, h.listenersID)
+	}
+	if d.elements == nil {
+		d.elements = make(map[int]*HTML)
+	}
+	d.elements[h.listenersID] = h
+	for _, l := range h.eventListeners {
+		if !l.hasOptions() {
+			d.addType(l.Name)
+		}
+	}
+}
+
+// remove unregisters the EventListeners of h, if they are registered, and
+// removes its native listeners.
+func (d *delegator) remove(h *HTML) {
+	if h.listenersID != 0 && d.elements[h.listenersID] == h {
+		delete(d.elements, h.listenersID)
+	}
+	h.removeNativeListeners(nil)
+}
+
+// dispatch invokes the EventListeners for the event, from its target towards
+// the document if the event bubbles, until propagation is stopped.
+func (d *delegator) dispatch(jsEvent jsObject) {
+	if jsEvent.Get(dispatchedProperty).Truthy() {
+		return
+	}
+	jsEvent.Set(dispatchedProperty, true)
+	typ := jsEvent.Get("type").String()
+	bubbles := jsEvent.Get("bubbles").Bool()
+	for node := jsEvent.Get("target"); node != nil && node.Truthy(); node = node.Get("parentNode") {
+		if id := node.Get(listenersProperty); id.Truthy() {
+			if h, ok := d.elements[id.Int()]; ok {
+				h.dispatch(typ, jsEvent)
+			}
+		}
+		if !bubbles || jsEvent.Get("cancelBubble").Bool() {
+			return
+		}
+	}
+}
+
+// dispatch invokes the EventListeners of h for the event type.
+func (h *HTML) dispatch(typ string, jsEvent jsObject) {
+	// The listeners may re-render h, so use those at the time of dispatch.
+	listeners := h.eventListeners
+	node := h.node
+	for _, l := range listeners {
+		if l.Name == typ && !l.hasOptions() {
+			l.call(jsEvent, node)
+		}
+	}
+}
+
+// call invokes the listener for the event, such that re-renders it causes are
+// urgent.
+func (l *EventListener) call(jsEvent, currentTarget jsObject) {
+	if l.callPreventDefault && !l.passive {
+		jsEvent.Call("preventDefault")
+	}
+	if l.callStopPropagation {
+		jsEvent.Call("stopPropagation")
+	}
+	if l.Listener == nil {
+		return
+	}
+	handlingEvents++
+	defer func() { handlingEvents-- }()
+	l.Listener(newEvent(jsEvent, currentTarget))
+}
+
+// hasOptions reports whether the listener has options, and so must be added
+// to its element natively rather than delegated.
+func (l *EventListener) hasOptions() bool {
+	return l.passive || l.capture || l.once
+}
+
+// sameOptions reports whether the listeners l and other have the same event
+// type and options.
+func (l *EventListener) sameOptions(other *EventListener) bool {
+	return l.Name == other.Name && l.passive == other.passive && l.capture == other.capture && l.once == other.once
+}
+
+// nativeListener is the native listener added to an element for an
+// EventListener with options.
+type nativeListener struct {
+	// listener is the EventListener invoked, which is updated when the
+	// element is re-rendered.
+	listener *EventListener
+	wrapper  jsFunc
+	// fired tracks whether a listener added with once has been invoked, and
+	// so removed by the browser.
+	fired bool
+}
+
+// reuseNativeListeners reuses the native listeners of prev, an earlier render
+// of the same DOM node, for the listeners of h with the same event type and
+// options, in order. It returns the native listeners which were reused.
+func (h *HTML) reuseNativeListeners(prev *HTML) map[*nativeListener]bool {
+	var reused map[*nativeListener]bool
+	for _, l := range h.eventListeners {
+		if !l.hasOptions() {
+			continue
+		}
+		for _, p := range prev.eventListeners {
+			if p.native == nil || reused[p.native] || !l.sameOptions(p) {
+				continue
+			}
+			if reused == nil {
+				reused = make(map[*nativeListener]bool)
+			}
+			reused[p.native] = true
+			l.native = p.native
+			l.native.listener = l
+			break
+		}
+	}
+	return reused
+}
+
+// addNativeListeners adds native listeners for the listeners of h with
+// options, which do not have one already.
+func (h *HTML) addNativeListeners() {
+	for _, l := range h.eventListeners {
+		if !l.hasOptions() || l.native != nil {
+			continue
+		}
+		n := &nativeListener{listener: l}
+		n.wrapper = funcOf(func(this jsObject, args []jsObject) interface{} {
+			if n.listener.once {
+				n.fired = true
+			}
+			n.listener.call(args[0], args[0].Get("currentTarget"))
+			return undefined()
+		})
+		l.native = n
+		h.node.Call("addEventListener", l.Name, n.wrapper, map[string]interface{}{
+			"capture": l.capture,
+			"passive": l.passive,
+			"once":    l.once,
+		})
+	}
+}
+
+// removeNativeListeners removes the native listeners of h, except those which
+// were reused. The capture option must match that used to add the listener.
+func (h *HTML) removeNativeListeners(reused map[*nativeListener]bool) {
+	for _, l := range h.eventListeners {
+		if l.native == nil || l.native.listener != l || reused[l.native] {
+			continue
+		}
+		if !l.native.fired {
+			h.node.Call("removeEventListener", l.Name, l.native.wrapper, l.capture)
+		}
+		l.native.wrapper.Release()
+		l.native = nil
+	}
+}
@@ -0,0 +1,145 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestEventDelegation tests that event listeners are dispatched to
+// by native listeners on the render roots, rather than added to each element.
+func TestEventDelegation(t *testing.T) {
+	ResetDOM()
+	var (
+		got    []string
+		holder *HTML
+		portal bool
+	)
+	listener := func(typ, name string) *EventListener {
+		return &EventListener{Name: typ, Listener: func(e *Event) {
+			got = append(got, name+":"+e.CurrentTarget.Get("id").String())
+		}}
+	}
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		var content MarkupOrChild
+		if portal {
+			content = Portal(holder.Node(), Tag("button", Markup(Property("id", "portaled"), listener("click", "button"))))
+		}
+		return Tag("body",
+			Tag("div", Markup(Property("id", "outer"), listener("focus", "outer"), listener("click", "outer")),
+				Tag("input", Markup(Property("id", "input"), listener("focus", "input"))),
+			),
+			Tag("div", Markup(Property("id", "holder"), Ref(&holder), listener("click", "holder"))),
+			content,
+		)
+	}}
+	RenderBody(comp)
+	doc := global().Get("document")
+	listeners := func(id string) int {
+		return len(doc.Call("getElementById", id).(*memNode).listeners)
+	}
+	if got := listeners("outer"); got != 0 {
+		t.Fatalf("got %d native listeners on element, want 0", got)
+	}
+	if got := len(memBody().(*memNode).listeners); got != 4 {
+		t.Fatalf("got %d native listeners on root, want 4", got)
+	}
+
+	// Re-rendering does not add or remove native listeners.
+	RerenderSync(comp)
+	if got := len(memBody().(*memNode).listeners); got != 4 {
+		t.Fatalf("got %d native listeners on root, want 4", got)
+	}
+
+	// Events which do not bubble are dispatched to the target only.
+	doc.Call("getElementById", "input").Call("focus")
+	doc.Call("getElementById", "input").Call("click")
+	want := []string{"input:input", "outer:outer"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+
+	// Events within a portal are dispatched once, by the portal target.
+	got = nil
+	portal = true
+	RerenderSync(comp)
+	doc.Call("getElementById", "portaled").Call("click")
+	want = []string{"button:portaled", "holder:holder"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+}
+
+// TestEventListenerOptions tests that listeners with the Passive, Capture or
+// Once options are added to their element as native listeners with those
+// options, and removed once they are no longer rendered.
+func TestEventListenerOptions(t *testing.T) {
+	ResetDOM()
+	var (
+		got     []string
+		options = true
+	)
+	listener := func(typ, name string) *EventListener {
+		return &EventListener{Name: typ, Listener: func(e *Event) {
+			got = append(got, name+":"+e.CurrentTarget.Get("id").String())
+		}}
+	}
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		outer := Markup(Property("id", "outer"))
+		if options {
+			outer = Markup(Property("id", "outer"),
+				listener("focus", "capture").Capture(),
+				listener("click", "once").Once(),
+				listener("wheel", "passive").Passive().PreventDefault(),
+			)
+		}
+		return Tag("body",
+			Tag("div", outer,
+				Tag("input", Markup(Property("id", "input"), listener("focus", "input"))),
+			),
+		)
+	}}
+	RenderBody(comp)
+	doc := global().Get("document")
+	outer := doc.Call("getElementById", "outer").(*memNode)
+	if got := len(outer.listeners); got != 3 {
+		t.Fatalf("got %d native listeners on element, want 3", got)
+	}
+
+	// Capture listeners see events of descendants which do not bubble. Those
+	// are dispatched to delegated listeners by the root, in the capture phase.
+	doc.Call("getElementById", "input").Call("focus")
+	want := []string{"input:input", "capture:outer"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+
+	// Once listeners are invoked once, even across re-renders.
+	got = nil
+	doc.Call("getElementById", "input").Call("click")
+	RerenderSync(comp)
+	doc.Call("getElementById", "input").Call("click")
+	want = []string{"once:outer"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if got := len(outer.listeners); got != 2 {
+		t.Fatalf("got %d native listeners on element, want 2", got)
+	}
+
+	// Passive listeners cannot prevent the default behavior.
+	ev := doc.Call("createEvent", "Event")
+	ev.Call("initEvent", "wheel", true, true)
+	if !doc.Call("getElementById", "input").Call("dispatchEvent", ev).Bool() {
+		t.Fatal("expected default not to be prevented")
+	}
+
+	// Listeners which are no longer rendered are removed, with matching
+	// options.
+	options = false
+	RerenderSync(comp)
+	if got := len(outer.listeners); got != 0 {
+		t.Fatalf("got %d native listeners on element, want 0", got)
+	}
+}
@@ -44,7 +44,7 @@ func (p *ItemView) onToggleCompleted(event *vecty.Event) {
 func (p *ItemView) onStartEdit(event *vecty.Event) {
 	p.editing = true
 	p.editTitle = p.Item.Title
-	vecty.Rerender(p)
+	vecty.RerenderSync(p)
 	p.input.Node().Call("focus")
 }
 
@@ -64,14 +64,6 @@ func (p *ItemView) onStopEdit(event *vecty.Event) {
 
 // Render implements the vecty.Component interface.
 func (p *ItemView) Render() vecty.ComponentOrHTML {
-	p.input = elem.Input(
-		vecty.Markup(
-			vecty.Class("edit"),
-			prop.Value(p.editTitle),
-			event.Input(p.onEditInput),
-		),
-	)
-
 	return elem.ListItem(
 		vecty.Markup(
 			vecty.ClassMap{
@@ -111,7 +103,14 @@ func (p *ItemView) Render() vecty.ComponentOrHTML {
 				style.Margin(style.Px(0)),
 				event.Submit(p.onStopEdit).PreventDefault(),
 			),
-			p.input,
+			elem.Input(
+				vecty.Markup(
+					vecty.Class("edit"),
+					vecty.Ref(&p.input),
+					prop.Value(p.editTitle),
+					event.Input(p.onEditInput),
+				),
+			),
 		),
 	)
 }
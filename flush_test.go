@@ -0,0 +1,55 @@
+// +build !js
+
+package vecty
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFlush tests that Flush and RerenderSync apply pending
+// re-renders synchronously, and that AfterRender callbacks are called once
+// the next render has been committed.
+func TestFlush(t *testing.T) {
+	text := "a"
+	other := &componentFunc{}
+	other.render = func() ComponentOrHTML { return Tag("p", Text(text)) }
+	c := &componentFunc{}
+	c.render = func() ComponentOrHTML { return Tag("body", Text(text), other) }
+	renderBody(c)
+	RunAnimationFrames()
+
+	text = "b"
+	Rerender(other)
+	Flush()
+	checkHTML(t, memBody(), `a<p>b</p>`)
+
+	// AfterRender callbacks see the committed DOM, and re-renders they queue
+	// are flushed too.
+	var calls []string
+	AfterRender(c, func() {
+		calls = append(calls, memBody().Get("innerHTML").String())
+		text = "c"
+		Rerender(other)
+		AfterRender(other, func() {
+			calls = append(calls, memBody().Get("innerHTML").String())
+		})
+	})
+	RerenderSync(c)
+	checkHTML(t, memBody(), `b<p>c</p>`)
+	if want := []string{`b<p>b</p>`, `b<p>c</p>`}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("got calls %q want %q", calls, want)
+	}
+	if n := RunAnimationFrames(); n != 1 {
+		t.Fatalf("got %d animation frames, want 1", n)
+	}
+
+	other.render = func() ComponentOrHTML {
+		Flush()
+		return nil
+	}
+	got := recoverStr(func() { RerenderSync(other) })
+	if want := "vecty: Flush illegally called while rendering"; got != want {
+		t.Fatalf("got panic %q want %q", got, want)
+	}
+}
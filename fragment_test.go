@@ -0,0 +1,168 @@
+// +build !js
+
+package vecty
+
+import (
+	"strconv"
+	"testing"
+)
+
+// fragmentComponent is a component which renders a fragment of list items,
+// or a single element.
+type fragmentComponent struct {
+	Core
+	items     []string
+	single    bool
+	mounted   int
+	unmounted int
+}
+
+func (c *fragmentComponent) Render() ComponentOrHTML {
+	if c.single {
+		return Tag("b", Text("single"))
+	}
+	var list List
+	for _, item := range c.items {
+		list = append(list, Tag("li", Text(item)))
+	}
+	return list
+}
+
+func (c *fragmentComponent) Mount()   { c.mounted++ }
+func (c *fragmentComponent) Unmount() { c.unmounted++ }
+
+// TestFragments tests components which render a List, both when
+// rendered by their parent and when re-rendered on their own.
+func TestFragments(t *testing.T) {
+	frag := &fragmentComponent{}
+	show := true
+	root := &componentFunc{}
+	root.render = func() ComponentOrHTML {
+		var child ComponentOrHTML = Text("replaced")
+		if show {
+			child = frag
+		}
+		return Tag("body", Tag("ul", Tag("li", Text("first")), child, Tag("li", Text("last"))))
+	}
+	renderBody(root)
+	ul := global().Get("document").Call("querySelector", "ul")
+
+	// An empty fragment renders an empty text node, which retains its
+	// position for subsequent renders.
+	checkHTML(t, ul, `<li>first</li><li>last</li>`)
+	if got := ul.Get("childNodes").Get("length").Int(); got != 3 {
+		t.Fatalf("got %d child nodes, want 3", got)
+	}
+	if frag.mounted != 1 {
+		t.Fatalf("got %d mounts, want 1", frag.mounted)
+	}
+
+	// Re-render the fragment on its own.
+	frag.items = []string{"a", "b"}
+	Rerender(frag)
+	RunAnimationFrames()
+	checkHTML(t, ul, `<li>first</li><li>a</li><li>b</li><li>last</li>`)
+	a := ul.Get("childNodes").Call("item", 1)
+
+	frag.items = []string{"a", "b", "c"}
+	Rerender(frag)
+	RunAnimationFrames()
+	checkHTML(t, ul, `<li>first</li><li>a</li><li>b</li><li>c</li><li>last</li>`)
+	if !ul.Get("childNodes").Call("item", 1).Equal(a) {
+		t.Fatal("expected fragment element to be retained")
+	}
+
+	// Re-render the fragment with its parent.
+	frag.items = []string{"c"}
+	Rerender(root)
+	RunAnimationFrames()
+	checkHTML(t, ul, `<li>first</li><li>c</li><li>last</li>`)
+
+	// Switch between a fragment and a single element.
+	frag.single = true
+	Rerender(frag)
+	RunAnimationFrames()
+	checkHTML(t, ul, `<li>first</li><b>single</b><li>last</li>`)
+	frag.single = false
+	Rerender(frag)
+	RunAnimationFrames()
+	checkHTML(t, ul, `<li>first</li><li>c</li><li>last</li>`)
+	if frag.mounted != 1 || frag.unmounted != 0 {
+		t.Fatalf("got %d mounts and %d unmounts, want 1 and 0", frag.mounted, frag.unmounted)
+	}
+
+	// Replace the fragment component.
+	show = false
+	Rerender(root)
+	RunAnimationFrames()
+	checkHTML(t, ul, `<li>first</li>replaced<li>last</li>`)
+	if frag.unmounted != 1 {
+		t.Fatalf("got %d unmounts, want 1", frag.unmounted)
+	}
+
+	got := recoverStr(func() {
+		RenderBody(&componentFunc{render: func() ComponentOrHTML {
+			return List{Tag("body")}
+		}})
+	})
+	want := "vecty: Component rendered at the root illegally returned a List (the root must render a single element)"
+	if got != want {
+		t.Fatalf("got panic %q want %q", got, want)
+	}
+}
+
+// keyedFragment is a keyed component which renders a fragment of two
+// elements.
+type keyedFragment struct {
+	Core
+	key int
+}
+
+func (c *keyedFragment) Key() interface{} { return c.key }
+
+func (c *keyedFragment) Render() ComponentOrHTML {
+	id := strconv.Itoa(c.key)
+	return List{
+		Tag("i", Markup(Property("id", "i"+id))),
+		Tag("b", Markup(Property("id", "b"+id))),
+	}
+}
+
+// TestFragments_keyed tests that keyed components which render a fragment
+// are moved with all of their DOM nodes when their siblings are reordered.
+func TestFragments_keyed(t *testing.T) {
+	var keys []int
+	c := &componentFunc{render: func() ComponentOrHTML {
+		var items List
+		for _, k := range keys {
+			items = append(items, &keyedFragment{key: k})
+		}
+		return Tag("body", items)
+	}}
+	keys = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	renderBody(c)
+	nodes := map[string]jsObject{}
+	for _, keys = range [][]int{{6, 4, 2, 0, 9, 8, 1}, {1, 8, 9, 0, 2, 4, 6}, {3, 1, 6}, {6, 1, 3, 5}} {
+		var want string
+		for _, k := range keys {
+			id := strconv.Itoa(k)
+			want += `<i id="i` + id + `"></i><b id="b` + id + `"></b>`
+		}
+		rerenderHTML(t, c, want)
+		for _, k := range keys {
+			for _, tag := range []string{"i", "b"} {
+				id := tag + strconv.Itoa(k)
+				node := global().Get("document").Call("getElementById", id)
+				if prev, ok := nodes[id]; ok && !prev.Equal(node) {
+					t.Fatalf("keys %v: %s is not the retained node", keys, id)
+				}
+				nodes[id] = node
+			}
+		}
+		for id := range nodes {
+			if global().Get("document").Call("getElementById", id) == nil {
+				delete(nodes, id)
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package vecty
+
+import "strconv"
+
+// HeadResource is a handle to a resource in the head of the document, such as
+// a stylesheet, as added by AddStylesheet or AddInlineStyle.
+//
+// Resources are reference counted: adding the same resource again returns a
+// new handle to the same element, which is removed from the document once
+// every handle to it has been removed. This allows components which need a
+// resource to add it when mounted, and remove it when unmounted:
+//
+// 	func (w *Widget) Mount() {
+// 		w.stylesheet = vecty.AddStylesheet("/widget.css").OnLoad(func(err error) {
+// 			w.loaded = true // e.g. render a placeholder until the styles are loaded
+// 			vecty.Rerender(w)
+// 		})
+// 	}
+//
+// 	func (w *Widget) Unmount() {
+// 		w.stylesheet.Remove()
+// 	}
+//
+type HeadResource struct {
+	res     *headResource
+	removed bool
+}
+
+// headResource is a resource in the head of the document, shared by the
+// handles to it.
+type headResource struct {
+	key  string
+	node jsObject
+	refs int
+
+	// settled reports whether the resource has loaded or failed to load, in
+	// which case err is the error, if any.
+	settled bool
+	err     error
+	// onLoad are the callbacks registered by OnLoad which are waiting for the
+	// resource to settle.
+	onLoad []headCallback
+	// loaded and failed are the listeners waiting for the resource to settle.
+	loaded, failed jsFunc
+	// documentLoaded is the listener waiting for the document to load, for
+	// an adopted resource whose events may have fired before it was adopted.
+	documentLoaded jsFunc
+}
+
+// headState is the state of a resource in the head of the document.
+type headState int
+
+const (
+	headLoaded headState = iota
+	headLoading
+	headFailed
+)
+
+// headCallback is a callback registered by a handle's OnLoad method.
+type headCallback struct {
+	handle *HeadResource
+	fn     func(err error)
+}
+
+// headResources are the resources in the head of the document, by key.
+var headResources map[string]*headResource
+
+// StylesheetLoadError is passed to OnLoad callbacks when a stylesheet fails
+// to load.
+type StylesheetLoadError struct {
+	// URL is the URL of the stylesheet.
+	URL string
+}
+
+func (e StylesheetLoadError) Error() string {
+	return "vecty: failed to load stylesheet " + strconv.Quote(e.URL)
+}
+
+// AddStylesheet adds an external stylesheet to the document, as a <link>
+// element in its head, and returns a handle to it.
+//
+// If the stylesheet was already added, and has not been removed, or the head
+// of the document already contains a <link rel="stylesheet"> element with the
+// URL (e.g. as rendered by a server), the existing element is used. Such an
+// element which has no stylesheet once the document has loaded failed to load.
+func AddStylesheet(url string) *HeadResource {
+	return addHeadResource("link:"+url, func(document jsObject) (node jsObject, adopted bool) {
+		links := document.Get("head").Call("querySelectorAll", `link[rel="stylesheet"]`)
+		for i := 0; i < links.Get("length").Int(); i++ {
+			link := links.Call("item", i)
+			if href := link.Call("getAttribute", "href"); href != nil && href.String() == url {
+				return link, true
+			}
+		}
+		link := document.Call("createElement", "link")
+		link.Set("rel", "stylesheet")
+		link.Set("href", url)
+		return link, false
+	}, stylesheetState, StylesheetLoadError{URL: url})
+}
+
+// stylesheetState returns the state of an adopted <link> element, given
+// whether the document has loaded: stylesheets which loaded have a sheet.
+func stylesheetState(link jsObject, documentLoaded bool) headState {
+	if sheet := link.Get("sheet"); sheet != nil && sheet.Truthy() {
+		return headLoaded
+	}
+	if documentLoaded {
+		return headFailed
+	}
+	return headLoading
+}
+
+// AddInlineStyle adds CSS to the document, as a <style> element in its head,
+// and returns a handle to it. If the same CSS was already added, and has not
+// been removed, the existing element is used.
+//
+// Unlike with AddStyles, the CSS is removed from the document once every handle
+// to it has been removed. It is considered loaded immediately.
+func AddInlineStyle(css string) *HeadResource {
+	return addHeadResource("style:"+css, func(document jsObject) (jsObject, bool) {
+		style := document.Call("createElement", "style")
+		style.Set("textContent", css)
+		return style, false
+	}, nil, nil)
+}
+
+// addHeadResource returns a new handle to the resource with the given key,
+// adding it to the document if necessary. create returns the element of the
+// resource, and whether it was adopted from the document rather than created
+// (and so need not be appended to the head).
+//
+// Created resources are loading until their load or error event, unless state
+// is nil, in which case they are loaded immediately. state returns the state of
+// an adopted resource, given whether the document has loaded, since its events
+// may have fired before it was adopted. loadErr is the error of a resource
+// which fails to load.
+func addHeadResource(key string, create func(document jsObject) (node jsObject, adopted bool), state func(node jsObject, documentLoaded bool) headState, loadErr error) *HeadResource {
+	res, ok := headResources[key]
+	if !ok {
+		document := global().Get("document")
+		node, adopted := create(document)
+		s := headLoaded
+		switch {
+		case state == nil:
+		case adopted:
+			s = state(node, document.Get("readyState").String() == "complete")
+		default:
+			s = headLoading
+		}
+		res = &headResource{key: key, node: node, settled: s != headLoading}
+		if s == headFailed {
+			res.err = loadErr
+		}
+		if s == headLoading {
+			res.loaded = funcOf(func(this jsObject, args []jsObject) interface{} {
+				res.settle(nil)
+				return undefined()
+			})
+			res.failed = funcOf(func(this jsObject, args []jsObject) interface{} {
+				res.settle(loadErr)
+				return undefined()
+			})
+			node.Call("addEventListener", "load", res.loaded)
+			node.Call("addEventListener", "error", res.failed)
+		}
+		if s == headLoading && adopted {
+			res.documentLoaded = funcOf(func(this jsObject, args []jsObject) interface{} {
+				if document.Get("readyState").String() != "complete" {
+					return undefined()
+				}
+				if state(node, true) == headFailed {
+					res.settle(loadErr)
+				} else {
+					res.settle(nil)
+				}
+				return undefined()
+			})
+			document.Call("addEventListener", "readystatechange", res.documentLoaded)
+		}
+		if !adopted {
+			document.Get("head").Call("appendChild", node)
+		}
+		if headResources == nil {
+			headResources = make(map[string]*headResource)
+		}
+		headResources[key] = res
+	}
+	res.refs++
+	return &HeadResource{res: res}
+}
+
+// settle records that the resource loaded, or failed to load with err, and
+// calls the OnLoad callbacks.
+func (r *headResource) settle(err error) {
+	if r.settled {
+		return
+	}
+	r.settled, r.err = true, err
+	r.unlisten()
+	callbacks := r.onLoad
+	r.onLoad = nil
+	for _, c := range callbacks {
+		c.fn(err)
+	}
+}
+
+// unlisten removes the listeners waiting for the resource to settle, if any.
+func (r *headResource) unlisten() {
+	if r.loaded == nil {
+		return
+	}
+	r.node.Call("removeEventListener", "load", r.loaded)
+	r.node.Call("removeEventListener", "error", r.failed)
+	r.loaded.Release()
+	r.failed.Release()
+	r.loaded, r.failed = nil, nil
+	if r.documentLoaded != nil {
+		global().Get("document").Call("removeEventListener", "readystatechange", r.documentLoaded)
+		r.documentLoaded.Release()
+		r.documentLoaded = nil
+	}
+}
+
+// OnLoad registers fn to be called once the resource has loaded, or failed to
+// load, in which case err describes the failure (e.g. a StylesheetLoadError).
+// If the resource has already loaded, fn is called immediately. fn is not
+// called once the handle has been removed.
+//
+// OnLoad returns the handle, for chaining with the call which added the
+// resource.
+func (h *HeadResource) OnLoad(fn func(err error)) *HeadResource {
+	switch {
+	case h.removed:
+	case h.res.settled:
+		fn(h.res.err)
+	default:
+		h.res.onLoad = append(h.res.onLoad, headCallback{handle: h, fn: fn})
+	}
+	return h
+}
+
+// Loaded reports whether the resource has loaded, or failed to load.
+func (h *HeadResource) Loaded() bool {
+	return h.res.settled
+}
+
+// Remove releases the handle to the resource. Once every handle to it has been
+// released, the resource is removed from the document. Calling Remove more
+// than once has no effect.
+func (h *HeadResource) Remove() {
+	if h.removed {
+		return
+	}
+	h.removed = true
+	r := h.res
+	callbacks := r.onLoad[:0]
+	for _, c := range r.onLoad {
+		if c.handle != h {
+			callbacks = append(callbacks, c)
+		}
+	}
+	r.onLoad = callbacks
+	if r.refs--; r.refs > 0 {
+		return
+	}
+	r.unlisten()
+	if parent := r.node.Get("parentNode"); parent != nil {
+		parent.Call("removeChild", r.node)
+	}
+	if headResources[r.key] == r {
+		delete(headResources, r.key)
+	}
+}
@@ -0,0 +1,120 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestHeadResources tests that head resources are reference counted, that
+// existing links are adopted, and that OnLoad callbacks are called once the
+// resource has loaded or failed to load.
+func TestHeadResources(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	head := doc.Get("head")
+	dispatch := func(node jsObject, typ string) {
+		ev := doc.Call("createEvent", "Event")
+		ev.Call("initEvent", typ, false, false)
+		node.Call("dispatchEvent", ev)
+	}
+	links := func() int {
+		return head.Call("querySelectorAll", "link").Get("length").Int()
+	}
+
+	var loads []string
+	a := AddStylesheet("/a.css").OnLoad(func(err error) { loads = append(loads, "a1 "+fmt.Sprint(err)) })
+	a2 := AddStylesheet("/a.css").OnLoad(func(err error) { loads = append(loads, "a2 "+fmt.Sprint(err)) })
+	a3 := AddStylesheet("/a.css").OnLoad(func(err error) { loads = append(loads, "a3 "+fmt.Sprint(err)) })
+	if links() != 1 || a.Loaded() {
+		t.Fatalf("got %d links, want 1 loading", links())
+	}
+	a3.Remove()
+	a3.Remove()
+	link := head.Call("querySelector", "link")
+	dispatch(link, "load")
+	dispatch(link, "load")
+	AddStylesheet("/a.css").OnLoad(func(err error) { loads = append(loads, "a4 "+fmt.Sprint(err)) }).Remove()
+	b := AddStylesheet("/b.css").OnLoad(func(err error) { loads = append(loads, "b "+fmt.Sprint(err)) })
+	dispatch(head.Call("querySelector", `link[href="/b.css"]`), "error")
+
+	want := []string{"a1 <nil>", "a2 <nil>", "a4 <nil>", `b vecty: failed to load stylesheet "/b.css"`}
+	if fmt.Sprint(loads) != fmt.Sprint(want) || !a.Loaded() {
+		t.Fatalf("got  %q\nwant %q", loads, want)
+	}
+
+	// The element is removed once every handle is removed.
+	a.Remove()
+	if links() != 2 {
+		t.Fatalf("got %d links, want 2", links())
+	}
+	a2.Remove()
+	b.Remove()
+	if links() != 0 {
+		t.Fatalf("got %d links, want 0", links())
+	}
+	if a := AddStylesheet("/a.css"); links() != 1 || a.Loaded() {
+		t.Fatal("expected stylesheet to be added again")
+	}
+
+	// Existing links are adopted.
+	existing := doc.Call("createElement", "link")
+	existing.Call("setAttribute", "rel", "stylesheet")
+	existing.Call("setAttribute", "href", "/server.css")
+	head.Call("appendChild", existing)
+	existing.Set("sheet", true)
+	var err error = StylesheetLoadError{}
+	s := AddStylesheet("/server.css").OnLoad(func(e error) { err = e })
+	if links() != 2 || err != nil {
+		t.Fatalf("got %d links and error %v, want 2 and loaded", links(), err)
+	}
+	s.Remove()
+	if existing.Get("parentNode") != nil {
+		t.Fatal("expected adopted link to be removed")
+	}
+
+	// Adopted links without a stylesheet failed to load, once the document
+	// has loaded.
+	adopt := func(url string) (*HeadResource, *error) {
+		link := doc.Call("createElement", "link")
+		link.Call("setAttribute", "rel", "stylesheet")
+		link.Call("setAttribute", "href", url)
+		head.Call("appendChild", link)
+		var err error
+		return AddStylesheet(url).OnLoad(func(e error) { err = e }), &err
+	}
+	failed, err1 := adopt("/failed.css")
+	if !failed.Loaded() || *err1 != (StylesheetLoadError{URL: "/failed.css"}) {
+		t.Fatalf("got error %v, want failed stylesheet", *err1)
+	}
+	doc.Set("readyState", "interactive")
+	loading, err2 := adopt("/loading.css")
+	late, err3 := adopt("/late.css")
+	dispatch(head.Call("querySelector", `link[href="/loading.css"]`), "load")
+	if !loading.Loaded() || *err2 != nil || late.Loaded() {
+		t.Fatal("expected only the stylesheet which loaded to be loaded")
+	}
+	doc.Set("readyState", "complete")
+	dispatch(doc, "readystatechange")
+	if !late.Loaded() || *err3 != (StylesheetLoadError{URL: "/late.css"}) {
+		t.Fatalf("got error %v, want failed stylesheet", *err3)
+	}
+	for _, h := range []*HeadResource{failed, loading, late} {
+		h.Remove()
+	}
+
+	// Inline styles are loaded immediately.
+	loaded := false
+	i1 := AddInlineStyle(".x{color:red}").OnLoad(func(err error) { loaded = err == nil })
+	i2 := AddInlineStyle(".x{color:red}")
+	styles := head.Call("querySelectorAll", "style")
+	if !loaded || styles.Get("length").Int() != 1 || styles.Call("item", 0).Get("textContent").String() != ".x{color:red}" {
+		t.Fatal("expected a single loaded style element")
+	}
+	i1.Remove()
+	i2.Remove()
+	if head.Call("querySelectorAll", "style").Get("length").Int() != 0 {
+		t.Fatal("expected style element to be removed")
+	}
+}
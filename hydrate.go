@@ -0,0 +1,475 @@
+package vecty
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// DOM node types, see https://developer.mozilla.org/en-US/docs/Web/API/Node/nodeType
+const (
+	elementNode = 1
+	textNode    = 3
+	commentNode = 8
+)
+
+// HydrationMismatchError is returned when the existing DOM does not match the
+// HTML rendered by a component, such that the existing DOM nodes could not be
+// adopted.
+type HydrationMismatchError struct {
+	method, path, got, want string
+}
+
+func (e HydrationMismatchError) Error() string {
+	return "vecty: " + e.method + ": cannot hydrate " + e.path + `: expected ` + e.want + `, found ` + e.got
+}
+
+// HydrateBody is like RenderBody, except that the existing body element and its
+// children (e.g. as produced by RenderToString on a server) are adopted by the
+// component instead of being replaced. See HydrateIntoNode for details.
+//
+// Errors, including a HydrationMismatchError after which the body was rendered
+// from scratch, are passed to the handler set by OnError, or cause a panic if
+// there is none.
+//
+// Like RenderBody, this function blocks forever in order to prevent the program
+// from exiting.
+func HydrateBody(body Component) {
+	target := global().Get("document").Call("querySelector", "body")
+	if err := hydrateIntoNode("HydrateBody", target, body); err != nil {
+		handleError(err)
+	}
+	keepAlive()
+}
+
+// HydrateInto is like RenderInto, except that the existing element found by
+// the CSS selector and its children are adopted by the component instead of
+// being replaced. See HydrateIntoNode for details.
+func HydrateInto(selector string, c Component) error {
+	target := global().Get("document").Call("querySelector", selector)
+	return hydrateIntoNode("HydrateInto", target, c)
+}
+
+// hydrateIntoNode implements HydrateIntoNode.
+func hydrateIntoNode(methodName string, node jsObject, c Component) error {
+	r, err := newRoot(methodName, node)
+	if err != nil {
+		return err
+	}
+	// block batch until we're done
+	r.batch.hold()
+	c.Context().root = r
+
+	doc := global().Get("document")
+	if doc.Get("readyState").String() == "loading" {
+		// The existing markup has not been fully parsed yet, so hydrate once
+		// it has.
+		var cb jsFunc
+		cb = funcOf(func(this jsObject, args []jsObject) interface{} {
+			cb.Release()
+
+			if err := hydrateOrRender(r, methodName, c); err != nil {
+				handleError(err)
+			}
+			return undefined()
+		})
+		doc.Call("addEventListener", "DOMContentLoaded", cb)
+		return nil
+	}
+	return hydrateOrRender(r, methodName, c)
+}
+
+// hydrateOrRender hydrates the component into the existing target node of the
+// root. If the existing DOM does not match, the component is instead rendered
+// from scratch, replacing the node, and the HydrationMismatchError is returned.
+func hydrateOrRender(r *Root, methodName string, c Component) error {
+	node := r.target
+	rendering = rendering[:0]
+	hy := &hydrator{method: methodName}
+	cursor := node
+	nextHTML, pendingMounts, err := hy.component(c, node.Get("parentNode"), &cursor, toLower(node.Get("nodeName").String()))
+	if err == nil && nextHTML == nil {
+		panic("vecty: Component rendered at the root illegally returned a List (the root must render a single element)")
+	}
+	if err != nil {
+		hy.rollback()
+		if renderErr := r.render(methodName, c); renderErr != nil {
+			return renderErr
+		}
+		return err
+	}
+	pendingMounts = append(hy.commit(), pendingMounts...)
+	events.addRoot(node)
+	r.mounted(c)
+	mount(pendingMounts...)
+	if m, ok := c.(Mounter); ok {
+		mount(m)
+	}
+	r.batch.release()
+	return nil
+}
+
+// hydrateInsert is a node which did not exist in the existing DOM, and must be
+// inserted into it.
+type hydrateInsert struct {
+	parent, before jsObject
+	child          *HTML
+}
+
+// hydrator adopts existing DOM nodes for a component tree, in two passes:
+// first the tree is rendered and matched against the existing DOM without
+// modifying it, then the required DOM modifications are committed.
+type hydrator struct {
+	method string
+	// components rendered by the hydrator, for rollback on mismatch.
+	components []Component
+	// elements whose properties and event listeners must be applied.
+	elements []*HTML
+	// inserts are nodes to be inserted into the DOM.
+	inserts []hydrateInsert
+	// removals are ignored DOM nodes (comments and whitespace) to be removed.
+	removals []jsObject
+	// portals are elements whose portal content must be rendered.
+	portals []*HTML
+}
+
+// component renders the component and adopts the DOM nodes starting at cursor
+// for its render, advancing the cursor past them. If the component renders a
+// fragment, nil *HTML is returned.
+func (hy *hydrator) component(c Component, parent jsObject, cursor *jsObject, path string) (*HTML, []Mounter, error) {
+	hy.components = append(hy.components, c)
+	depth := len(rendering)
+	pushRendering(c)
+	nextRender := normalizeRender(callRender(c))
+
+	var (
+		nextHTML      *HTML
+		pendingMounts []Mounter
+		err           error
+	)
+	if _, ok := c.(ErrorBoundary); ok {
+		nextRender, nextHTML, pendingMounts, err = hy.errorBoundary(c, nextRender, parent, cursor, path, depth)
+	} else {
+		nextRender, nextHTML, pendingMounts, err = hy.render(nextRender, parent, cursor, path)
+	}
+	rendering = rendering[:depth]
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if callbacks := c.Context().afterRender; len(callbacks) > 0 {
+		// Cleared by commit, as rollback renders the component again.
+		pendingMounts = append(pendingMounts, callbacks)
+	}
+
+	// Update the context to consider this render.
+	c.Context().prevRender = nextRender
+	c.Context().prevRenderComponent = copyComponent(c)
+	c.Context().unmounted = false
+	return nextHTML, pendingMounts, nil
+}
+
+// render adopts the DOM nodes starting at cursor for the render of a
+// component, advancing the cursor past them. It returns the render, with nil
+// translated into a noscript tag.
+func (hy *hydrator) render(nextRender ComponentOrHTML, parent jsObject, cursor *jsObject, path string) (ComponentOrHTML, *HTML, []Mounter, error) {
+	var (
+		nextHTML      *HTML
+		pendingMounts []Mounter
+		err           error
+	)
+	switch v := nextRender.(type) {
+	case Component:
+		nextHTML, pendingMounts, err = hy.component(v, parent, cursor, path)
+		if m, ok := v.(Mounter); ok {
+			pendingMounts = append(pendingMounts, m)
+		}
+	case *HTML:
+		if v == nil {
+			// nil renders are translated into noscript tags.
+			v = Tag("noscript")
+			nextRender = v
+		}
+		nextHTML = v
+		pendingMounts, err = hy.node(v, parent, cursor, path)
+	case KeyedList:
+		v.html.node = parent
+		pendingMounts, err = hy.children(v.html, parent, cursor, path)
+	default:
+		panic("vecty: internal error (unexpected ComponentOrHTML type " + reflect.TypeOf(v).String() + ")")
+	}
+	return nextRender, nextHTML, pendingMounts, err
+}
+
+// errorBoundary is like render, for the render of the ErrorBoundary c. If
+// rendering its descendants panics, those hydrated so far are rolled back, and
+// the fallback returned by RenderError is hydrated in their place instead, as
+// rendered by RenderToString. If the existing DOM is instead found not to match
+// before the panic occurs, the mismatch is returned as usual, and the render
+// from scratch which follows catches the panic.
+func (hy *hydrator) errorBoundary(c Component, nextRender ComponentOrHTML, parent jsObject, cursor *jsObject, path string, depth int) (ComponentOrHTML, *HTML, []Mounter, error) {
+	saved := *hy
+	start := *cursor
+	var (
+		nextHTML      *HTML
+		pendingMounts []Mounter
+		err           error
+		panicked      *RenderPanicError
+	)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				e := recoverRenderPanic(r, c, depth+1)
+				panicked = &e
+			}
+		}()
+		nextRender, nextHTML, pendingMounts, err = hy.render(nextRender, parent, cursor, path)
+	}()
+	if panicked == nil {
+		return nextRender, nextHTML, pendingMounts, err
+	}
+	for _, d := range hy.components[len(saved.components):] {
+		d.Context().prevRender = nil
+		d.Context().prevRenderComponent = nil
+	}
+	hy.components = saved.components[:len(saved.components):len(saved.components)]
+	hy.elements = saved.elements[:len(saved.elements):len(saved.elements)]
+	hy.inserts = saved.inserts[:len(saved.inserts):len(saved.inserts)]
+	hy.removals = saved.removals[:len(saved.removals):len(saved.removals)]
+	hy.portals = saved.portals[:len(saved.portals):len(saved.portals)]
+	*cursor = start
+	fallback := normalizeRender(c.(ErrorBoundary).RenderError(*panicked))
+	return hy.render(fallback, parent, cursor, path)
+}
+
+// node adopts the DOM node at cursor for h, advancing the cursor past it.
+func (hy *hydrator) node(h *HTML, parent jsObject, cursor *jsObject, path string) ([]Mounter, error) {
+	pendingMounts, err := hy.adopt(h, parent, cursor, path)
+	if err == nil && len(h.refs) > 0 {
+		pendingMounts = append(pendingMounts, refMounter{h})
+	}
+	return pendingMounts, err
+}
+
+// adopt implements node.
+func (hy *hydrator) adopt(h *HTML, parent jsObject, cursor *jsObject, path string) ([]Mounter, error) {
+	if h.tag == "" && h.text == "" {
+		// Empty text nodes do not exist in parsed HTML, so create one.
+		h.createNode()
+		hy.inserts = append(hy.inserts, hydrateInsert{parent: parent, before: *cursor, child: h})
+		if h.portal != nil {
+			// Portal content is not server-rendered, so render it from
+			// scratch.
+			hy.portals = append(hy.portals, h)
+		}
+		return nil, nil
+	}
+	*cursor = hy.skipIgnored(*cursor, h.tag != "")
+	if *cursor == nil {
+		if h.tag == "" {
+			return nil, hy.mismatch(path, "no node", "text node")
+		}
+		return nil, hy.mismatch(path, "no node", "<"+h.tag+">")
+	}
+	node := *cursor
+	*cursor = node.Get("nextSibling")
+	return hy.html(h, node, path)
+}
+
+// html adopts the given node for h, and its children for the children of h.
+func (hy *hydrator) html(h *HTML, node jsObject, path string) ([]Mounter, error) {
+	if h.tag == "" {
+		if node.Get("nodeType").Int() != textNode {
+			return nil, hy.mismatch(path, describeNode(node), "text node")
+		}
+		if text := node.Get("nodeValue").String(); text != h.text {
+			return nil, hy.mismatch(path, `text "`+text+`"`, `text "`+h.text+`"`)
+		}
+		h.node = node
+		return nil, nil
+	}
+	if node.Get("nodeType").Int() != elementNode || toLower(node.Get("nodeName").String()) != toLower(h.tag) {
+		return nil, hy.mismatch(path, describeNode(node), "<"+h.tag+">")
+	}
+	h.node = node
+	hy.elements = append(hy.elements, h)
+	if h.innerHTML != "" {
+		// Children are controlled by UnsafeHTML, not Vecty.
+		return nil, nil
+	}
+	if h.tag == "textarea" && len(h.children) == 0 {
+		// The text of a textarea is its value, as rendered by RenderToString,
+		// which is left in place since removing it would change the value.
+		return nil, nil
+	}
+	for _, name := range []string{"textContent", "innerText", "innerHTML"} {
+		if _, ok := h.properties[name]; ok {
+			// Children are controlled by the property, as rendered by
+			// RenderToString.
+			return nil, nil
+		}
+	}
+	cursor := node.Get("firstChild")
+	pendingMounts, err := hy.children(h, node, &cursor, path)
+	if err != nil {
+		return nil, err
+	}
+	cursor = hy.skipIgnored(cursor, true)
+	if cursor != nil {
+		return nil, hy.mismatch(path, "unexpected "+describeNode(cursor), "no more children")
+	}
+	return pendingMounts, nil
+}
+
+// children adopts the DOM nodes starting at cursor for the children of h,
+// advancing the cursor past them. The parent is the DOM node which the
+// children are rendered into, which differs from h.node for lists.
+func (hy *hydrator) children(h *HTML, parent jsObject, cursor *jsObject, path string) (pendingMounts []Mounter, err error) {
+	hasKeyedChildren := false
+	for i := range h.children {
+		nextChild, _, keyed := h.prepareChild(i, hasKeyedChildren)
+		hasKeyedChildren = keyed
+		childPath := path + " > " + describeChild(nextChild, i)
+
+		switch v := nextChild.(type) {
+		case KeyedList:
+			v.html.node = parent
+			mounts, err := hy.children(v.html, parent, cursor, childPath)
+			if err != nil {
+				return nil, err
+			}
+			pendingMounts = append(pendingMounts, mounts...)
+		case *HTML:
+			mounts, err := hy.node(v, parent, cursor, childPath)
+			if err != nil {
+				return nil, err
+			}
+			pendingMounts = append(pendingMounts, mounts...)
+		case Component:
+			_, mounts, err := hy.component(v, parent, cursor, childPath)
+			if err != nil {
+				return nil, err
+			}
+			pendingMounts = append(pendingMounts, mounts...)
+			if m, ok := v.(Mounter); ok {
+				pendingMounts = append(pendingMounts, m)
+			}
+		}
+	}
+	return pendingMounts, nil
+}
+
+// skipIgnored returns the first node at or after cursor which is not a
+// comment, nor (if skipWhitespace) a whitespace-only text node. Skipped nodes
+// are scheduled for removal.
+func (hy *hydrator) skipIgnored(cursor jsObject, skipWhitespace bool) jsObject {
+	for cursor != nil {
+		switch cursor.Get("nodeType").Int() {
+		case commentNode:
+		case textNode:
+			if !skipWhitespace || !isWhitespace(cursor.Get("nodeValue").String()) {
+				return cursor
+			}
+		default:
+			return cursor
+		}
+		hy.removals = append(hy.removals, cursor)
+		cursor = cursor.Get("nextSibling")
+	}
+	return nil
+}
+
+// mismatch returns a HydrationMismatchError.
+func (hy *hydrator) mismatch(path, got, want string) error {
+	return HydrationMismatchError{method: hy.method, path: path, got: got, want: want}
+}
+
+// commit performs the DOM modifications required by the hydrated tree: event
+// listeners and properties are applied, missing nodes are inserted, ignored
+// nodes are removed and portal content is rendered. It returns the Mounters
+// of the portal content.
+func (hy *hydrator) commit() (pendingMounts []Mounter) {
+	for _, c := range hy.components {
+		c.Context().afterRender = nil
+	}
+	for _, h := range hy.elements {
+		// Attributes, classes, dataset, styles and inner HTML were rendered by
+		// the server, so only apply properties and event listeners.
+		h.reconcileProperties(&HTML{
+			node:       h.node,
+			attributes: h.attributes,
+			classes:    h.classes,
+			dataset:    h.dataset,
+			styles:     h.styles,
+			innerHTML:  h.innerHTML,
+		})
+	}
+	for _, insert := range hy.inserts {
+		if insert.before == nil {
+			insert.parent.Call("appendChild", insert.child.node)
+			continue
+		}
+		insert.parent.Call("insertBefore", insert.child.node, insert.before)
+	}
+	for _, node := range hy.removals {
+		node.Get("parentNode").Call("removeChild", node)
+	}
+	// Live properties are applied once the children of their elements are in
+	// place, children first as when rendering.
+	for i := len(hy.elements) - 1; i >= 0; i-- {
+		hy.elements[i].reconcileLiveProperties()
+	}
+	for _, h := range hy.portals {
+		pendingMounts = append(pendingMounts, h.reconcilePortal(nil)...)
+	}
+	return pendingMounts
+}
+
+// rollback resets the state of all components rendered by the hydrator, such
+// that they may be rendered from scratch.
+func (hy *hydrator) rollback() {
+	for _, c := range hy.components {
+		c.Context().prevRender = nil
+		c.Context().prevRenderComponent = nil
+	}
+}
+
+// isWhitespace reports whether s consists only of HTML whitespace characters.
+func isWhitespace(s string) bool {
+	for _, c := range s {
+		switch c {
+		case ' ', '\t', '\n', '\r', '\f':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// describeNode returns a short human readable description of a DOM node.
+func describeNode(node jsObject) string {
+	switch node.Get("nodeType").Int() {
+	case textNode:
+		return `text "` + node.Get("nodeValue").String() + `"`
+	case elementNode:
+		return "<" + toLower(node.Get("nodeName").String()) + ">"
+	default:
+		return node.Get("nodeName").String()
+	}
+}
+
+// describeChild returns a short human readable description of the child at
+// index i, for use in a HydrationMismatchError path.
+func describeChild(child ComponentOrHTML, i int) string {
+	switch v := child.(type) {
+	case *HTML:
+		if v.tag == "" {
+			return "#text[" + strconv.Itoa(i) + "]"
+		}
+		return v.tag + "[" + strconv.Itoa(i) + "]"
+	case Component:
+		return reflect.TypeOf(v).String() + "[" + strconv.Itoa(i) + "]"
+	default:
+		return "[" + strconv.Itoa(i) + "]"
+	}
+}
+
@@ -0,0 +1,429 @@
+// +build !js
+
+package vecty
+
+import (
+	"html"
+	"strings"
+	"testing"
+)
+
+// TestHydrateInto_InvalidTarget tests that HydrateInto returns an
+// InvalidTargetError when the target element is not found.
+func TestHydrateInto_InvalidTarget(t *testing.T) {
+	ts := testSuite(t)
+	defer ts.done()
+
+	ts.truthies.mock(`global.Get("document").Call("querySelector", "#app")`, false)
+
+	err := HydrateInto("#app", &componentFunc{
+		render: func() ComponentOrHTML {
+			return Tag("div")
+		},
+	})
+	want := InvalidTargetError{method: "HydrateInto"}
+	if err != want {
+		t.Fatalf("got error %v want %v", err, want)
+	}
+}
+
+func TestHydrationMismatchError(t *testing.T) {
+	err := HydrationMismatchError{
+		method: "HydrateBody",
+		path:   "body > " + describeChild(Tag("div"), 12) + " > " + describeChild(Text("a"), 0) + " > " + describeChild(&componentFunc{}, 3),
+		got:    "<span>",
+		want:   "<div>",
+	}
+	want := "vecty: HydrateBody: cannot hydrate body > div[12] > #text[0] > *vecty.componentFunc[3]: expected <div>, found <span>"
+	if err.Error() != want {
+		t.Fatalf("got %q want %q", err.Error(), want)
+	}
+}
+
+func TestIsWhitespace(t *testing.T) {
+	for s, want := range map[string]bool{
+		"":          true,
+		" \n\t\r\f": true,
+		" a ":       false,
+		"\u00a0":    false,
+	} {
+		if got := isWhitespace(s); got != want {
+			t.Errorf("isWhitespace(%q) = %v want %v", s, got, want)
+		}
+	}
+}
+
+// TestHydrate tests that hydration adopts existing DOM nodes, including text
+// nodes which the server separated with comments.
+func TestHydrate(t *testing.T) {
+	render := func(clicked *bool) ComponentOrHTML {
+		return Tag("body",
+			Tag("p", Markup(Property("id", "p")), Text("a"), Text(""), Text("b")),
+			List{Tag("button", Markup(
+				Property("id", "button"),
+				&EventListener{Name: "click", Listener: func(*Event) { *clicked = true }},
+			))},
+		)
+	}
+
+	// Build the server-rendered DOM, as the browser would parse the output of
+	// RenderToString.
+	ResetDOM()
+	doc := global().Get("document")
+	body := doc.Get("body")
+	p := doc.Call("createElement", "p")
+	p.Set("id", "p")
+	p.Call("appendChild", doc.Call("createTextNode", "a"))
+	p.Call("appendChild", doc.Call("createComment", ""))
+	p.Call("appendChild", doc.Call("createTextNode", "b"))
+	body.Call("appendChild", p)
+	body.Call("appendChild", doc.Call("createTextNode", "\n"))
+	button := doc.Call("createElement", "button")
+	button.Set("id", "button")
+	body.Call("appendChild", button)
+
+	var clicked bool
+	if err := HydrateInto("body", &componentFunc{render: func() ComponentOrHTML {
+		return render(&clicked)
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Get("body").Equal(body) || !doc.Call("getElementById", "button").Equal(button) {
+		t.Fatal("expected existing nodes to be adopted")
+	}
+	want := `<body><p id="p">ab</p><button id="button"></button></body>`
+	if got := body.Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	// The comment separating the text nodes is replaced by the empty text
+	// node, such that each text node is retained.
+	if got := p.Get("childNodes").Get("length").Int(); got != 3 {
+		t.Fatalf("got %d text nodes, want 3", got)
+	}
+	if got := p.Get("firstChild").Get("nodeValue").String(); got != "a" {
+		t.Fatalf("got first text node %q want %q", got, "a")
+	}
+	button.Call("click")
+	if !clicked {
+		t.Fatal("expected event listener to be attached")
+	}
+
+}
+
+// TestHydrate_Fragments tests hydration of components which render a
+// List.
+func TestHydrate_Fragments(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	body := doc.Get("body")
+	ul := doc.Call("createElement", "ul")
+	for _, text := range []string{"first", "a", "b", "last"} {
+		li := doc.Call("createElement", "li")
+		li.Call("appendChild", doc.Call("createTextNode", text))
+		ul.Call("appendChild", li)
+	}
+	body.Call("appendChild", ul)
+	a := ul.Get("childNodes").Call("item", 1)
+
+	frag := &fragmentComponent{items: []string{"a", "b"}}
+	if err := HydrateInto("body", &componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", Tag("ul", Tag("li", Text("first")), frag, Tag("li", Text("last"))))
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if !ul.Get("childNodes").Call("item", 1).Equal(a) {
+		t.Fatal("expected existing nodes to be adopted")
+	}
+
+	frag.items = []string{"b", "c"}
+	Rerender(frag)
+	RunAnimationFrames()
+	want := "<li>first</li><li>b</li><li>c</li><li>last</li>"
+	if got := ul.Get("innerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestHydrate_Keyed tests that keyed children are adopted, and retain their
+// DOM nodes when reordered by subsequent renders.
+func TestHydrate_Keyed(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	ul := doc.Call("createElement", "ul")
+	for _, k := range []string{"a", "b", "c"} {
+		li := doc.Call("createElement", "li")
+		li.Call("appendChild", doc.Call("createTextNode", k))
+		ul.Call("appendChild", li)
+	}
+	doc.Get("body").Call("appendChild", ul)
+	before := map[string]jsObject{}
+	for i, k := range []string{"a", "b", "c"} {
+		before[k] = ul.Get("childNodes").Call("item", i)
+	}
+
+	keys := []string{"a", "b", "c"}
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		var list List
+		for _, k := range keys {
+			list = append(list, Tag("li", Markup(Key(k)), Text(k)))
+		}
+		return Tag("body", Tag("ul", list))
+	}}
+	if err := HydrateInto("body", comp); err != nil {
+		t.Fatal(err)
+	}
+
+	keys = []string{"c", "a"}
+	Rerender(comp)
+	RunAnimationFrames()
+	want := "<li>c</li><li>a</li>"
+	if got := ul.Get("innerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	for i, k := range keys {
+		if !ul.Get("childNodes").Call("item", i).Equal(before[k]) {
+			t.Fatalf("keyed element %q was not retained", k)
+		}
+	}
+
+	// Mismatches within a list include the list in their path.
+	ResetDOM()
+	doc = global().Get("document")
+	ul = doc.Call("createElement", "ul")
+	li := doc.Call("createElement", "li")
+	li.Call("appendChild", doc.Call("createTextNode", "a"))
+	ul.Call("appendChild", li)
+	ul.Call("appendChild", doc.Call("createElement", "span"))
+	doc.Get("body").Call("appendChild", ul)
+	keys = []string{"a", "b"}
+	err := HydrateInto("body", comp)
+	wantErr := `vecty: HydrateInto: cannot hydrate body > ul[0] > [0] > li[1]: expected <li>, found <span>`
+	if err == nil || err.Error() != wantErr {
+		t.Fatalf("got error %v want %s", err, wantErr)
+	}
+}
+
+// TestHydrate_Mismatch tests that mismatched markup falls back to rendering
+// from scratch, as if the components which were rendered before the mismatch
+// was found had never been rendered.
+func TestHydrate_Mismatch(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	body := doc.Get("body")
+	b := doc.Call("createElement", "b")
+	b.Call("appendChild", doc.Call("createTextNode", "single"))
+	body.Call("appendChild", b)
+	body.Call("appendChild", doc.Call("createElement", "div"))
+
+	frag := &fragmentComponent{single: true}
+	err := HydrateInto("body", &componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", frag, Tag("p"))
+	}})
+	wantErr := `vecty: HydrateInto: cannot hydrate body > p[1]: expected <p>, found <div>`
+	if _, ok := err.(HydrationMismatchError); !ok || err.Error() != wantErr {
+		t.Fatalf("got error %v want %s", err, wantErr)
+	}
+	if doc.Get("body").Equal(body) {
+		t.Fatal("expected body to be replaced")
+	}
+	want := `<b>single</b><p></p>`
+	if got := doc.Get("body").Get("innerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if frag.mounted != 1 || frag.unmounted != 0 {
+		t.Fatalf("got %d mounts and %d unmounts, want 1 and 0", frag.mounted, frag.unmounted)
+	}
+
+	// The component re-renders within the new DOM.
+	frag.single = false
+	frag.items = []string{"x"}
+	Rerender(frag)
+	RunAnimationFrames()
+	want = `<li>x</li><p></p>`
+	if got := doc.Get("body").Get("innerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestHydrate_Errors tests that errors which cannot be returned, those of
+// HydrateBody and of hydration deferred until the document has loaded, are
+// passed to the handler set by OnError.
+func TestHydrate_Errors(t *testing.T) {
+	var errs []error
+	handler := func(err error) { errs = append(errs, err) }
+	render := func() ComponentOrHTML { return Tag("body", Tag("p")) }
+
+	ResetDOM()
+	OnError(handler)
+	defer OnError(nil)
+	doc := global().Get("document")
+	doc.Get("body").Call("appendChild", doc.Call("createElement", "div"))
+	HydrateBody(&componentFunc{render: render})
+	wantErr := `vecty: HydrateBody: cannot hydrate body > p[0]: expected <p>, found <div>`
+	if len(errs) != 1 || errs[0].Error() != wantErr {
+		t.Fatalf("got errors %v want [%s]", errs, wantErr)
+	}
+
+	ResetDOM()
+	OnError(handler)
+	errs = nil
+	doc = global().Get("document")
+	doc.Get("body").Call("appendChild", doc.Call("createElement", "div"))
+	doc.Set("readyState", "loading")
+	if err := HydrateInto("body", &componentFunc{render: render}); err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 0 {
+		t.Fatalf("got errors %v before the document loaded", errs)
+	}
+	doc.Set("readyState", "interactive")
+	ev := doc.Call("createEvent", "Event")
+	ev.Call("initEvent", "DOMContentLoaded", true, false)
+	doc.Call("dispatchEvent", ev)
+	wantErr = `vecty: HydrateInto: cannot hydrate body > p[0]: expected <p>, found <div>`
+	if len(errs) != 1 || errs[0].Error() != wantErr {
+		t.Fatalf("got errors %v want [%s]", errs, wantErr)
+	}
+	want := `<body><p></p></body>`
+	if got := doc.Get("body").Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// parseHTML appends the DOM nodes of markup rendered by RenderToString to
+// parent, as a browser would parse it, and returns the markup following the
+// end tag of parent. Only the subset of HTML which RenderToString produces is
+// supported.
+func parseHTML(parent jsObject, s string) string {
+	doc := global().Get("document")
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s, "-->")
+			parent.Call("appendChild", doc.Call("createComment", s[4:end]))
+			s = s[end+3:]
+		case strings.HasPrefix(s, "</"):
+			return s[strings.Index(s, ">")+1:]
+		case strings.HasPrefix(s, "<"):
+			end := strings.IndexAny(s, " >")
+			tag := s[1:end]
+			el := doc.Call("createElement", tag)
+			s = s[end:]
+			for s[0] != '>' {
+				s = strings.TrimLeft(s, " ")
+				end := strings.IndexAny(s, "=>")
+				if end < 0 || s[end] == '>' || strings.Contains(s[:end], " ") {
+					end = strings.IndexAny(s, " >")
+					el.Call("setAttribute", s[:end], "")
+					s = s[end:]
+					continue
+				}
+				name := s[:end]
+				s = s[end+2:]
+				end = strings.Index(s, `"`)
+				el.Call("setAttribute", name, html.UnescapeString(s[:end]))
+				s = s[end+1:]
+			}
+			s = s[1:]
+			parent.Call("appendChild", el)
+			switch {
+			case voidElements[tag]:
+			case tag == "textarea" || rawTextElements[tag]:
+				end := strings.Index(s, "</"+tag+">")
+				if text := s[:end]; text != "" {
+					if tag == "textarea" {
+						text = html.UnescapeString(text)
+					}
+					el.Call("appendChild", doc.Call("createTextNode", text))
+				}
+				s = s[end+len(tag)+3:]
+			default:
+				s = parseHTML(el, s)
+			}
+		default:
+			end := strings.Index(s, "<")
+			if end < 0 {
+				end = len(s)
+			}
+			parent.Call("appendChild", doc.Call("createTextNode", html.UnescapeString(s[:end])))
+			s = s[end:]
+		}
+	}
+	return ""
+}
+
+// TestHydrate_ServerRendered tests that the markup of form elements rendered
+// by RenderToString is hydrated, adopting its nodes, with the values of the
+// form elements retained.
+func TestHydrate_ServerRendered(t *testing.T) {
+	render := func() ComponentOrHTML {
+		return Tag("body", Tag("form",
+			Tag("textarea", Markup(Property("value", "a <b>\nc"))),
+			Tag("select", Markup(Property("value", "y")),
+				Tag("option", Markup(Property("value", "x")), Text("X")),
+				Tag("option", Markup(Property("value", "y")), Text("Y")),
+			),
+			Tag("input", Markup(Property("value", "v"), Property("checked", true), Attribute("type", "checkbox"))),
+		))
+	}
+	markup, err := RenderToString(&componentFunc{render: render})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ResetDOM()
+	doc := global().Get("document")
+	parseHTML(doc.Get("body"), strings.TrimPrefix(markup, "<body>"))
+	form := doc.Call("querySelector", "form")
+	if err := HydrateInto("body", &componentFunc{render: render}); err != nil {
+		t.Fatal(err)
+	}
+	if !doc.Call("querySelector", "form").Equal(form) {
+		t.Fatal("expected server-rendered nodes to be adopted")
+	}
+	for selector, want := range map[string]string{"textarea": "a <b>\nc", "select": "y", "input": "v"} {
+		if got := doc.Call("querySelector", selector).Get("value").String(); got != want {
+			t.Fatalf("got %s value %q want %q", selector, got, want)
+		}
+	}
+	if !doc.Call("querySelector", "input").Get("checked").Bool() {
+		t.Fatal("expected checkbox to be checked")
+	}
+}
+
+// TestHydrate_ErrorBoundary tests that a panic while hydrating the descendants
+// of an ErrorBoundary is caught by it, and that its fallback, as rendered by
+// RenderToString, is hydrated in their place.
+func TestHydrate_ErrorBoundary(t *testing.T) {
+	var boundary *errorBoundary
+	render := func() ComponentOrHTML {
+		boundary = &errorBoundary{render: func() ComponentOrHTML {
+			return &brokenComponent{broken: true}
+		}}
+		return Tag("body", Tag("main", boundary))
+	}
+	markup, err := RenderToString(&componentFunc{render: render})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ResetDOM()
+	doc := global().Get("document")
+	parseHTML(doc.Get("body"), strings.TrimPrefix(markup, "<body>"))
+	p := doc.Call("querySelector", "p")
+	if err := HydrateInto("body", &componentFunc{render: render}); err != nil {
+		t.Fatal(err)
+	}
+	if boundary.err == nil || boundary.err.Value != "broken" {
+		t.Fatalf("got error %v, want panic value broken", boundary.err)
+	}
+	if !doc.Call("querySelector", "p").Equal(p) {
+		t.Fatal("expected server-rendered fallback to be adopted")
+	}
+	if got, want := doc.Get("body").Get("innerHTML").String(), "<main><p>error</p></main>"; got != want {
+		t.Fatalf("got body %q want %q", got, want)
+	}
+	if got := boundary.Context().prevRender.(*HTML); got.tag != "p" {
+		t.Fatalf("got previous render %q, want p", got.tag)
+	}
+}
@@ -0,0 +1,228 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// reorder returns the keys 0 to n-1 reordered by the named operation.
+func reorder(op string, n int) []int {
+	keys := make([]int, n)
+	for i := range keys {
+		keys[i] = i
+	}
+	switch op {
+	case "first_to_last":
+		return append(keys[1:], 0)
+	case "last_to_first":
+		return append([]int{n - 1}, keys[:n-1]...)
+	case "swap":
+		keys[1], keys[n-2] = keys[n-2], keys[1]
+	case "reverse":
+		for i := 0; i < n/2; i++ {
+			keys[i], keys[n-1-i] = keys[n-1-i], keys[i]
+		}
+	case "shuffle":
+		// A deterministic shuffle.
+		for i := n - 1; i > 0; i-- {
+			j := (i * 7919) % (i + 1)
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	return keys
+}
+
+// keyedListComponent renders a keyed list item for each of its keys, within a
+// list element, or directly into the body as a KeyedList if fragment is set.
+type keyedListComponent struct {
+	Core
+	keys     []int
+	fragment bool
+}
+
+func (c *keyedListComponent) Render() ComponentOrHTML {
+	items := make(List, len(c.keys))
+	for i, k := range c.keys {
+		items[i] = Tag("li", Markup(Key(k), Property("id", "k"+strconv.Itoa(k))))
+	}
+	if c.fragment {
+		return Tag("body", Tag("hr"), items, Tag("hr"))
+	}
+	return Tag("body", Tag("ul", items))
+}
+
+// TestKeyedMoves tests that reordering keyed children moves the
+// fewest DOM nodes, while retaining them.
+func TestKeyedMoves(t *testing.T) {
+	cases := []struct {
+		op        string
+		wantMoves int
+	}{
+		{op: "none", wantMoves: 0},
+		{op: "first_to_last", wantMoves: 1},
+		{op: "last_to_first", wantMoves: 1},
+		{op: "swap", wantMoves: 2},
+		{op: "reverse", wantMoves: 9},
+		{op: "shuffle", wantMoves: -1},
+	}
+	for _, fragment := range []bool{false, true} {
+		for _, tst := range cases {
+			t.Run(tst.op+"_fragment_"+strconv.FormatBool(fragment), func(t *testing.T) {
+				c := &keyedListComponent{keys: reorder("none", 10), fragment: fragment}
+				renderBody(c)
+				doc := global().Get("document")
+				nodes := map[int]jsObject{}
+				for _, k := range c.keys {
+					nodes[k] = doc.Call("getElementById", "k"+strconv.Itoa(k))
+				}
+
+				c.keys = reorder(tst.op, 10)
+				moves := global().(*memWindow).moves
+				RerenderSync(c)
+				if got := global().(*memWindow).moves - moves; tst.wantMoves >= 0 && got != tst.wantMoves {
+					t.Fatalf("got %d moves, want %d", got, tst.wantMoves)
+				}
+				items := doc.Call("querySelectorAll", "li")
+				if got := items.Get("length").Int(); got != len(c.keys) {
+					t.Fatalf("got %d items, want %d", got, len(c.keys))
+				}
+				for i, k := range c.keys {
+					if !items.Call("item", i).Equal(nodes[k]) {
+						t.Fatalf("item %d is not the retained node for key %d", i, k)
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestKeyedLists tests that keyed lists are reordered, inserted and removed
+// among their siblings, retaining the DOM nodes of those which remain.
+func TestKeyedLists(t *testing.T) {
+	var keys []int
+	c := &componentFunc{render: func() ComponentOrHTML {
+		var items List
+		for _, k := range keys {
+			id := strconv.Itoa(k)
+			items = append(items, List{
+				Tag("i", Markup(Property("id", "i"+id))),
+				Tag("b", Markup(Property("id", "b"+id))),
+			}.WithKey(k))
+		}
+		return Tag("body", items)
+	}}
+	keys = []int{1, 2}
+	renderBody(c)
+	nodes := map[string]jsObject{}
+	for _, keys = range [][]int{{1, 2, 3}, {3, 1, 2}, {2}, {0, 2, 4}, {4, 2, 0}, {1, 2}} {
+		var want string
+		for _, k := range keys {
+			id := strconv.Itoa(k)
+			want += `<i id="i` + id + `"></i><b id="b` + id + `"></b>`
+		}
+		rerenderHTML(t, c, want)
+		for _, k := range keys {
+			for _, tag := range []string{"i", "b"} {
+				id := tag + strconv.Itoa(k)
+				node := global().Get("document").Call("getElementById", id)
+				if prev, ok := nodes[id]; ok && !prev.Equal(node) {
+					t.Fatalf("keys %v: %s is not the retained node", keys, id)
+				}
+				nodes[id] = node
+			}
+		}
+		for id := range nodes {
+			if global().Get("document").Call("getElementById", id) == nil {
+				delete(nodes, id)
+			}
+		}
+	}
+}
+
+// skippedItem is a keyed component which skips rendering once rendered.
+type skippedItem struct {
+	Core
+	key int
+}
+
+func (c *skippedItem) Key() interface{}          { return c.key }
+func (c *skippedItem) SkipRender(Component) bool { return true }
+
+func (c *skippedItem) Render() ComponentOrHTML {
+	return Tag("li", Markup(Property("id", "k"+strconv.Itoa(c.key))))
+}
+
+// TestKeyedSkipRender tests that keyed components which skip rendering retain
+// their DOM nodes, and are moved when their siblings are reordered.
+func TestKeyedSkipRender(t *testing.T) {
+	keys := []int{0, 1, 2}
+	c := &componentFunc{render: func() ComponentOrHTML {
+		var items List
+		for _, k := range keys {
+			items = append(items, &skippedItem{key: k})
+		}
+		return Tag("body", items)
+	}}
+	renderBody(c)
+	first := global().Get("document").Call("getElementById", "k0")
+	keys = []int{2, 0}
+	rerenderHTML(t, c, `<li id="k2"></li><li id="k0"></li>`)
+	if !global().Get("document").Call("getElementById", "k0").Equal(first) {
+		t.Fatal("expected the node of a skipped child to be retained")
+	}
+}
+
+func BenchmarkKeyedReorder(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		for _, op := range []string{"first_to_last", "swap", "reverse", "shuffle"} {
+			b.Run(op+"_"+strconv.Itoa(n), func(b *testing.B) {
+				c := &keyedListComponent{keys: reorder("none", n)}
+				renderBody(c)
+				moves := global().(*memWindow).moves
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					// Alternate between the reordered and original keys.
+					if i%2 == 0 {
+						c.keys = reorder(op, n)
+					} else {
+						c.keys = reorder("none", n)
+					}
+					RerenderSync(c)
+				}
+				b.ReportMetric(float64(global().(*memWindow).moves-moves)/float64(b.N), "moves/op")
+			})
+		}
+	}
+}
+
+// TestLongestIncreasingSubsequence tests the subsequence of keyed children
+// which are left in place when keyed children are reordered.
+func TestLongestIncreasingSubsequence(t *testing.T) {
+	cases := []struct {
+		seq  []int
+		want int
+	}{
+		{seq: nil, want: 0},
+		{seq: []int{0, 1, 2, 3}, want: 4},
+		{seq: []int{3, 2, 1, 0}, want: 1},
+		{seq: []int{1, 2, 3, 0}, want: 3},
+		{seq: []int{3, 0, 1, 2}, want: 3},
+		{seq: []int{0, 8, 4, 12, 2, 10, 6, 14, 1, 9, 5, 13, 3, 11, 7, 15}, want: 6},
+	}
+	for _, tst := range cases {
+		t.Run(fmt.Sprint(tst.seq), func(t *testing.T) {
+			lis := longestIncreasingSubsequence(tst.seq)
+			if len(lis) != tst.want {
+				t.Fatalf("got length %d want %d", len(lis), tst.want)
+			}
+			for i := 1; i < len(lis); i++ {
+				if lis[i] <= lis[i-1] || tst.seq[lis[i]] <= tst.seq[lis[i-1]] {
+					t.Fatalf("got %v, which is not an increasing subsequence", lis)
+				}
+			}
+		})
+	}
+}
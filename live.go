@@ -0,0 +1,282 @@
+package vecty
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// liveKind is the type of the value of a live property, as read from the DOM.
+type liveKind int
+
+const (
+	liveString liveKind = iota
+	liveBool
+	liveNumber
+)
+
+// liveProperties are properties which change as the user interacts with an
+// element, in the order in which they are applied. Rather than against the
+// previous render, they are reconciled against the DOM, such that e.g. an
+// input whose value was changed by the user is reset to the value rendered
+// (i.e. it is a controlled input), while a value which the user changed to the
+// value rendered is left untouched.
+//
+// The selection properties selectionStart, selectionEnd and selectionDirection
+// are also live, and are applied last.
+//
+// Properties which replace the children of an element, such as textContent and
+// innerText, are not live: they would remove the children rendered by Vecty.
+// Instead, the text nodes within a contenteditable element are live, see
+// reconcileEditableText.
+var liveProperties = []struct {
+	name string
+	kind liveKind
+}{
+	{"value", liveString},
+	{"selectedIndex", liveNumber},
+	{"checked", liveBool},
+	{"indeterminate", liveBool},
+	{"selected", liveBool},
+}
+
+// isLiveProperty reports whether the property is reconciled by
+// reconcileLiveProperties, rather than reconcileProperties.
+func isLiveProperty(name string) bool {
+	switch name {
+	case "selectionStart", "selectionEnd", "selectionDirection":
+		return true
+	}
+	for _, p := range liveProperties {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reconcileLiveProperties updates the live properties of the element to match
+// the current element. It must be called after the children of the element
+// have been reconciled, since e.g. the value of a select element can only be
+// set once its options exist.
+//
+// If the element has focus, and the rendered properties do not include its
+// selection, the selection (or caret position) is preserved when its value is
+// changed, rather than moved to the end.
+func (h *HTML) reconcileLiveProperties() {
+	if len(h.properties) == 0 {
+		return
+	}
+	_, hasSelection := h.properties["selectionStart"]
+	if _, ok := h.properties["selectionEnd"]; ok {
+		hasSelection = true
+	}
+	for _, p := range liveProperties {
+		value, ok := h.properties[p.name]
+		if !ok || p.kind.equal(h.node.Get(p.name), value) {
+			continue
+		}
+		var restore func()
+		if !hasSelection {
+			restore = h.saveSelection()
+		}
+		domOps++
+		h.node.Set(p.name, value)
+		if restore != nil {
+			restore()
+		}
+	}
+	h.reconcileSelection()
+}
+
+// reconcileSelection updates the selection of a text control, such as an
+// input or textarea, to match the selectionStart, selectionEnd and
+// selectionDirection properties of the current element.
+func (h *HTML) reconcileSelection() {
+	start, hasStart := h.properties["selectionStart"]
+	end, hasEnd := h.properties["selectionEnd"]
+	direction, hasDirection := h.properties["selectionDirection"]
+	if !hasStart && !hasEnd && !hasDirection {
+		return
+	}
+	current := [3]jsObject{h.node.Get("selectionStart"), h.node.Get("selectionEnd"), h.node.Get("selectionDirection")}
+	for _, v := range current {
+		if v == nil || v.IsUndefined() {
+			// Not a text control.
+			return
+		}
+	}
+	if (!hasStart || liveNumber.equal(current[0], start)) &&
+		(!hasEnd || liveNumber.equal(current[1], end)) &&
+		(!hasDirection || liveString.equal(current[2], direction)) {
+		return
+	}
+	args := []interface{}{start, end, direction}
+	for i, has := range []bool{hasStart, hasEnd, hasDirection} {
+		if !has {
+			args[i] = current[i]
+		}
+	}
+	domOps++
+	h.node.Call("setSelectionRange", args...)
+}
+
+// saveSelection returns a function which restores the selection within the
+// element, if it has focus, or nil otherwise.
+func (h *HTML) saveSelection() func() {
+	if !h.node.Equal(global().Get("document").Get("activeElement")) {
+		return nil
+	}
+
+	// Only text controls, such as inputs and textareas, have a selection.
+	start, end := h.node.Get("selectionStart"), h.node.Get("selectionEnd")
+	if start != nil && !start.IsUndefined() && end != nil && !end.IsUndefined() {
+		direction := h.node.Get("selectionDirection")
+		return func() {
+			// Offsets beyond the end of the new value are clamped.
+			h.node.Call("setSelectionRange", start, end, direction)
+		}
+	}
+	return nil
+}
+
+// isContentEditable reports whether the element is rendered as editable by the
+// user, via its contenteditable attribute or contentEditable property.
+func (h *HTML) isContentEditable() bool {
+	for _, v := range []interface{}{h.attributes["contenteditable"], h.properties["contentEditable"]} {
+		switch v := v.(type) {
+		case string:
+			if v != "false" && v != "inherit" {
+				return true
+			}
+		case bool:
+			if v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// saveEditableSelection returns a function which restores the selection (or
+// caret position) within a contenteditable element, if the selection is within
+// it, or nil otherwise. It must be called before the children of the element
+// are reconciled, since changing the text of a text node moves the selection
+// within it to its start.
+func (h *HTML) saveEditableSelection() func() {
+	if !h.isContentEditable() {
+		return nil
+	}
+	sel := global().Get("document").Call("getSelection")
+	if sel == nil || sel.Get("rangeCount").Int() == 0 {
+		return nil
+	}
+	anchor, focus := sel.Get("anchorNode"), sel.Get("focusNode")
+	if anchor == nil || focus == nil || !h.node.Call("contains", anchor).Bool() || !h.node.Call("contains", focus).Bool() {
+		return nil
+	}
+	anchorOffset, focusOffset := sel.Get("anchorOffset").Int(), sel.Get("focusOffset").Int()
+	return func() {
+		if !h.node.Call("contains", anchor).Bool() || !h.node.Call("contains", focus).Bool() {
+			// The selected nodes were removed.
+			return
+		}
+		domOps++
+		sel.Call("setBaseAndExtent", anchor, clampTextOffset(anchor, anchorOffset), focus, clampTextOffset(focus, focusOffset))
+	}
+}
+
+// reconcileEditableText updates the text nodes within a contenteditable
+// element to match the current element, once its children have been
+// reconciled. As the user may have changed the text, like live properties it
+// is compared against the DOM rather than the previous render. Only the data
+// of the text nodes is changed, such that the other children rendered by Vecty
+// are left in place.
+//
+// If any text was changed, the selection is restored by restore, as returned
+// by saveEditableSelection before the children were reconciled.
+func (h *HTML) reconcileEditableText(ops int, restore func()) {
+	if !h.isContentEditable() {
+		return
+	}
+	Inspect(h, func(e ComponentOrHTML) bool {
+		t, ok := e.(*HTML)
+		if !ok {
+			return true
+		}
+		if t.tag == "" && t.node != nil && !liveString.equal(t.node.Get("nodeValue"), t.text) {
+			domOps++
+			t.node.Set("nodeValue", t.text)
+		}
+		// Portal content is not within the element.
+		return t.portal == nil
+	})
+	if restore != nil && domOps != ops {
+		restore()
+	}
+}
+
+// clampTextOffset returns offset, clamped to the length of node if it is a text
+// node.
+func clampTextOffset(node jsObject, offset int) int {
+	if node.Get("nodeType").Int() != textNode {
+		return offset
+	}
+	if n := utf16Len(node.Get("nodeValue").String()); offset > n {
+		return n
+	}
+	return offset
+}
+
+// utf16Len returns the length of s in UTF-16 code units, as the length of a
+// JavaScript string.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r > 0xFFFF {
+			n++
+		}
+	}
+	return n
+}
+
+// equal reports whether the value of a live property read from the DOM, v,
+// equals the rendered value. Values of types which cannot be compared are not
+// equal.
+func (k liveKind) equal(v jsObject, value interface{}) bool {
+	if v == nil || v.IsUndefined() {
+		return false
+	}
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() {
+		return false
+	}
+	switch k {
+	case liveBool:
+		return rv.Kind() == reflect.Bool && v.Bool() == rv.Bool()
+	case liveNumber:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return v.Float() == float64(rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return v.Float() == float64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			return v.Float() == rv.Float()
+		}
+		return false
+	}
+	var s string
+	switch rv.Kind() {
+	case reflect.String:
+		s = rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Bool:
+		s = strconv.FormatBool(rv.Bool())
+	default:
+		return false
+	}
+	return v.String() == s
+}
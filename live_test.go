@@ -0,0 +1,131 @@
+// +build !js
+
+package vecty
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLiveProperties tests that the properties which the user can change are
+// reconciled against the DOM, after the children of their element, and that
+// the selection of a focused input is preserved when its value changes.
+func TestLiveProperties(t *testing.T) {
+	ResetDOM()
+	var (
+		selected = "b"
+		options  = []string{"a", "b", "c"}
+		text     = "abc"
+		start    interface{}
+	)
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		var opts List
+		for _, o := range options {
+			opts = append(opts, Tag("option", Markup(Property("value", o)), Text(o)))
+		}
+		input := Markup(Property("value", strings.ToUpper(text)))
+		if start != nil {
+			input = Markup(Property("value", strings.ToUpper(text)), Property("selectionStart", start), Property("selectionEnd", 3))
+		}
+		return Tag("body",
+			Tag("select", Markup(Property("value", selected)), opts),
+			Tag("input", input),
+		)
+	}}
+	RenderBody(comp)
+	doc := global().Get("document")
+	sel, input := doc.Call("querySelector", "select"), doc.Call("querySelector", "input")
+
+	// The value of a select is applied once its options exist.
+	if got := sel.Get("selectedIndex").Int(); got != 1 {
+		t.Fatalf("got selectedIndex %d, want 1", got)
+	}
+	options = append(options, "d")
+	selected = "d"
+	RerenderSync(comp)
+	if got := sel.Get("value").String(); got != "d" {
+		t.Fatalf("got select value %q, want d", got)
+	}
+	// A value changed by the user is reset to the rendered value.
+	sel.Set("value", "a")
+	RerenderSync(comp)
+	if got := sel.Get("value").String(); got != "d" {
+		t.Fatalf("got select value %q, want d", got)
+	}
+
+	// The caret of a focused input is preserved when its value is changed.
+	input.Call("focus")
+	input.Set("value", "ABxC")
+	input.Call("setSelectionRange", 3, 3)
+	text = "abxc"
+	RerenderSync(comp)
+	if got := input.Get("value").String(); got != "ABXC" {
+		t.Fatalf("got input value %q, want ABXC", got)
+	}
+	if s, e := input.Get("selectionStart").Int(), input.Get("selectionEnd").Int(); s != 3 || e != 3 {
+		t.Fatalf("got selection %d-%d, want 3-3", s, e)
+	}
+	start = 1
+	RerenderSync(comp)
+	if s, e := input.Get("selectionStart").Int(), input.Get("selectionEnd").Int(); s != 1 || e != 3 {
+		t.Fatalf("got selection %d-%d, want 1-3", s, e)
+	}
+}
+
+// TestLiveProperties_ContentEditable tests that the text within a
+// contenteditable element is reconciled against the DOM without replacing its
+// children, and that the caret within it is preserved.
+func TestLiveProperties_ContentEditable(t *testing.T) {
+	text := "hello"
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", Tag("div", Markup(Attribute("contenteditable", "true")),
+			Text(text),
+			Tag("b", Text("!")),
+		))
+	}}
+	renderBody(comp)
+	doc := global().Get("document")
+	div := doc.Call("querySelector", "div")
+	textNode, b := div.Get("firstChild"), div.Call("querySelector", "b")
+	sel := doc.Call("getSelection")
+
+	// Text typed by the user, and rendered in turn, is left untouched.
+	textNode.Set("data", "helxlo")
+	sel.Call("collapse", textNode, 4)
+	text = "helxlo"
+	RerenderSync(comp)
+	if got := sel.Get("anchorOffset").Int(); got != 4 {
+		t.Fatalf("got caret offset %d, want 4", got)
+	}
+
+	// Text which differs from the rendered text is reset, preserving the
+	// caret and the other children.
+	textNode.Set("data", "helxylo")
+	sel.Call("collapse", textNode, 5)
+	text = "HELXYLO"
+	RerenderSync(comp)
+	if !div.Get("firstChild").Equal(textNode) || !div.Call("querySelector", "b").Equal(b) {
+		t.Fatal("expected children to be retained")
+	}
+	checkHTML(t, div, "HELXYLO<b>!</b>")
+	if n, o := sel.Get("anchorNode"), sel.Get("anchorOffset").Int(); !n.Equal(textNode) || o != 5 {
+		t.Fatalf("got caret offset %d, want 5", o)
+	}
+	textNode.Set("data", "typed")
+	sel.Call("collapse", textNode, 5)
+	RerenderSync(comp)
+	if got := textNode.Get("data").String(); got != text {
+		t.Fatalf("got text %q, want %q", got, text)
+	}
+	if got := sel.Get("anchorOffset").Int(); got != 5 {
+		t.Fatalf("got caret offset %d, want 5", got)
+	}
+
+	// Offsets beyond the end of the new text are clamped.
+	sel.Call("collapse", textNode, 7)
+	text = "ab"
+	RerenderSync(comp)
+	if got := sel.Get("anchorOffset").Int(); got != 2 {
+		t.Fatalf("got caret offset %d, want 2", got)
+	}
+}
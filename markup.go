@@ -1,15 +1,30 @@
 package vecty
 
-import "reflect"
+import (
+	"reflect"
+	"strings"
+)
 
 // EventListener is markup that specifies a callback function to be invoked when
 // the named DOM event is fired.
+//
+// Event listeners are delegated: rather than adding a native listener to each
+// element, Vecty adds a single native listener for each event type to the
+// render root (and to the target of each Portal), which invokes the
+// EventListeners of the elements in the event's path, from the target towards
+// the root, until propagation is stopped. Events which do not bubble, such as
+// focus, are dispatched to the EventListeners of their target only.
+//
+// Listeners with options set by Passive, Capture or Once are instead added to
+// their element natively, as these options apply to native listeners.
 type EventListener struct {
-	Name                string
-	Listener            func(*Event)
-	callPreventDefault  bool
-	callStopPropagation bool
-	wrapper             jsFunc
+	Name                   string
+	Listener               func(*Event)
+	callPreventDefault     bool
+	callStopPropagation    bool
+	passive, capture, once bool
+	// native is the native listener added for a listener with options.
+	native *nativeListener
 }
 
 // PreventDefault prevents the default behavior of the event from occurring.
@@ -29,6 +44,40 @@ func (l *EventListener) StopPropagation() *EventListener {
 	return l
 }
 
+// Passive marks the listener as passive, indicating that it never prevents the
+// default behavior of the event, such that e.g. the browser may scroll the
+// page without waiting for touchmove and wheel listeners to run. PreventDefault
+// has no effect on a passive listener.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/API/EventTarget/addEventListener#passive.
+func (l *EventListener) Passive() *EventListener {
+	l.passive = true
+	return l
+}
+
+// Capture makes the listener be invoked in the capturing phase, before the
+// listeners of the elements below it in the event's path, rather than in the
+// bubbling phase. This allows e.g. an ancestor to handle the focus events of
+// its descendants, which do not bubble. Note that EventListeners without
+// options for events which do not bubble are invoked in the capturing phase of
+// the render root, and so before any listener added with Capture.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/API/EventTarget/addEventListener#capture.
+func (l *EventListener) Capture() *EventListener {
+	l.capture = true
+	return l
+}
+
+// Once makes the listener be invoked at most once, after which the browser
+// removes it. It is not added again when its element is re-rendered, unless
+// the element's DOM node is re-created.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/API/EventTarget/addEventListener#once.
+func (l *EventListener) Once() *EventListener {
+	l.once = true
+	return l
+}
+
 // Apply implements the Applyer interface.
 func (l *EventListener) Apply(h *HTML) {
 	h.eventListeners = append(h.eventListeners, l)
@@ -94,14 +143,56 @@ func Key(key interface{}) Applyer {
 	})
 }
 
+// Ref returns an Applyer which keeps *ref pointing to the element (or text
+// node) that it is applied to, such that its DOM node may be accessed later,
+// e.g. from an event listener:
+//
+// 	elem.Input(vecty.Markup(vecty.Ref(&c.input)))
+// 	...
+// 	c.input.Node().Call("focus")
+//
+// *ref is set once the element has been rendered, before any Mounter is
+// invoked, and again after each re-render. It is set to nil when the element
+// is removed or unmounted.
+func Ref(ref **HTML) Applyer {
+	return markupFunc(func(h *HTML) {
+		h.refs = append(h.refs, func(target *HTML) {
+			// Only clear the ref if it still points to this element.
+			if target != nil || *ref == h {
+				*ref = target
+			}
+		})
+	})
+}
+
+// RefFunc returns an Applyer which calls fn with the element (or text node)
+// that it is applied to once the element has been rendered, before any Mounter
+// is invoked, and again after each re-render. fn is called with nil before
+// each re-render, and when the element is removed or unmounted.
+func RefFunc(fn func(h *HTML)) Applyer {
+	return markupFunc(func(h *HTML) {
+		h.refs = append(h.refs, fn)
+	})
+}
+
 // Property returns an Applyer which applies the given JavaScript property to an
 // HTML element or text node. Generally, this function is not used directly but
 // rather the prop and style subpackages (which are type safe) should be used instead.
 //
-// To set style, use style package or Style. Property panics if key is "style".
+// Properties which the user can change by interacting with an element, such as
+// value, checked, selectedIndex and selectionStart, are compared against the
+// DOM rather than the previous render, such that the element always reflects
+// the rendered value. They are applied after the element's children, and the
+// selection of a focused element is preserved when its value changes. The
+// text rendered within a contenteditable element is likewise compared against
+// the DOM, and the caret within it preserved.
+//
+// To set style, use style package or Style. If key is "style", a
+// StylePropertyError is reported (see OnError) and the property is not applied.
 func Property(key string, value interface{}) Applyer {
 	if key == "style" {
-		panic(`vecty: Property called with key "style"; style package or Style should be used instead`)
+		handleError(StylePropertyError{Path: errorPath(nil)})
+		return markupFunc(func(h *HTML) {})
 	}
 	return markupFunc(func(h *HTML) {
 		if h.properties == nil {
@@ -140,7 +231,7 @@ func Data(key, value string) Applyer {
 // calls to this function will append additional classes. To toggle classes,
 // use ClassMap instead. Each class name must be passed as a separate argument.
 func Class(class ...string) Applyer {
-	mustValidateClassNames(class)
+	class = validateClassNames(class)
 	return markupFunc(func(h *HTML) {
 		if h.classes == nil {
 			h.classes = make(map[string]struct{})
@@ -151,14 +242,27 @@ func Class(class ...string) Applyer {
 	})
 }
 
-// mustValidateClassNames ensures no class names have spaces
-// and panics with clear instructions on how to fix this user error.
-func mustValidateClassNames(class []string) {
-	for _, name := range class {
-		if containsSpace(name) {
-			panic(`vecty: invalid argument to vecty.Class "` + name + `" (string may not contain spaces)`)
+// validateClassNames ensures no class names have spaces, reporting a
+// ClassNameError with clear instructions on how to fix this user error. If the
+// error is handled, the class names are returned with those containing spaces
+// split into the class names they contain.
+func validateClassNames(class []string) []string {
+	for i, name := range class {
+		if !containsSpace(name) {
+			continue
+		}
+		handleError(ClassNameError{Path: errorPath(nil), Class: name})
+		valid := append([]string(nil), class[:i]...)
+		for _, name := range class[i:] {
+			for _, name := range strings.Split(name, " ") {
+				if name != "" {
+					valid = append(valid, name)
+				}
+			}
 		}
+		return valid
 	}
+	return class
 }
 
 // containsSpace reports whether s contains a space character.
@@ -0,0 +1,116 @@
+package vecty
+
+import "reflect"
+
+// Memo can be embedded in a Component to have rendering skipped whenever the
+// Component is rendered by its parent with the same properties (the fields
+// tagged with `vecty:"prop"`) as its previous render, without implementing
+// RenderSkipper by hand:
+//
+// 	type MyComponent struct {
+// 		vecty.Core
+// 		vecty.Memo
+//
+// 		Name string `vecty:"prop"`
+// 	}
+//
+// Alternatively, tagging any property with `vecty:"prop,memo"` has the same
+// effect as embedding Memo.
+//
+// Properties of comparable types are compared using ==, such that e.g. pointers
+// are equal only if they point to the same value. Properties of other types
+// (e.g. slices and maps) are compared using their Equal method, if they have
+// one with a signature like:
+//
+// 	func (v T) Equal(other T) bool
+//
+// and are otherwise considered changed unless both are nil. This includes
+// functions, so a Component with non-nil function properties is never skipped.
+//
+// Memoization only applies when the Component is rendered as part of its
+// parent. Rerender always renders the Component, and a Component which
+// implements RenderSkipper uses its SkipRender method instead.
+type Memo struct{}
+
+func (Memo) isMemo() {}
+
+// memoizer is implemented by components which embed Memo.
+type memoizer interface {
+	isMemo()
+}
+
+// propTag reports whether a struct field with the given tag is a property, and
+// whether it is tagged as `vecty:"prop,memo"`.
+func propTag(tag reflect.StructTag) (prop, memo bool) {
+	switch tag.Get("vecty") {
+	case "prop":
+		return true, false
+	case "prop,memo":
+		return true, true
+	}
+	return false, false
+}
+
+// memoized reports whether rendering of the component should be skipped when
+// its properties are unchanged.
+func memoized(c Component) bool {
+	if _, ok := c.(memoizer); ok {
+		return true
+	}
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	t := v.Elem().Type()
+	for i := 0; i < t.NumField(); i++ {
+		if _, memo := propTag(t.Field(i).Tag); memo {
+			return true
+		}
+	}
+	return false
+}
+
+// propsEqual reports whether every property of the components a and b is
+// equal, as described by Memo.
+func propsEqual(a, b Component) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() || va.Kind() != reflect.Ptr || va.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	va, vb = va.Elem(), vb.Elem()
+	for i := 0; i < va.NumField(); i++ {
+		if prop, _ := propTag(va.Type().Field(i).Tag); !prop {
+			continue
+		}
+		if !propEqual(va.Field(i), vb.Field(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// propEqual reports whether the property values a and b, of the same type, are
+// equal.
+func propEqual(a, b reflect.Value) bool {
+	if a.Type().Comparable() {
+		return sameValue(a.Interface(), b.Interface())
+	}
+	switch a.Kind() {
+	case reflect.Func, reflect.Map, reflect.Slice:
+		if a.IsNil() && b.IsNil() {
+			return true
+		}
+	}
+	equal := a.MethodByName("Equal")
+	if !equal.IsValid() && a.CanAddr() {
+		equal = a.Addr().MethodByName("Equal")
+	}
+	if !equal.IsValid() {
+		return false
+	}
+	t := equal.Type()
+	if t.NumIn() != 1 || t.NumOut() != 1 || t.Out(0).Kind() != reflect.Bool || !b.Type().AssignableTo(t.In(0)) {
+		return false
+	}
+	return equal.Call([]reflect.Value{b})[0].Bool()
+}
@@ -0,0 +1,96 @@
+// +build !js
+
+package vecty
+
+import "testing"
+
+// memoComponent is memoized by embedding Memo.
+type memoComponent struct {
+	Core
+	Memo
+	Name    string   `vecty:"prop"`
+	Tags    tagList  `vecty:"prop"`
+	renders int
+}
+
+func (c *memoComponent) Render() ComponentOrHTML {
+	c.renders++
+	return Tag("p", Text(c.Name))
+}
+
+// memoTagComponent is memoized by a `vecty:"prop,memo"` tag.
+type memoTagComponent struct {
+	Core
+	Name    string `vecty:"prop,memo"`
+	OnClick func() `vecty:"prop"`
+	renders int
+}
+
+func (c *memoTagComponent) Render() ComponentOrHTML {
+	c.renders++
+	return Tag("p", Text(c.Name))
+}
+
+// tagList is a property type which is compared using its Equal method.
+type tagList []string
+
+func (l tagList) Equal(other tagList) bool {
+	if len(l) != len(other) {
+		return false
+	}
+	for i := range l {
+		if l[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestMemo tests that memoized components skip rendering when
+// rendered by their parent with unchanged properties.
+func TestMemo(t *testing.T) {
+	var (
+		name    = "a"
+		tags    = tagList{"x"}
+		onClick func()
+		memo    *memoComponent
+		tagged  *memoTagComponent
+	)
+	parent := &componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			&memoComponent{Name: name, Tags: tags},
+			&memoTagComponent{Name: name, OnClick: onClick},
+		)
+	}}
+	renderBody(parent)
+	memo = parent.Context().prevRender.(*HTML).children[0].(*memoComponent)
+	tagged = parent.Context().prevRender.(*HTML).children[1].(*memoTagComponent)
+	check := func(wantMemo, wantTagged int) {
+		t.Helper()
+		RerenderSync(parent)
+		if memo.renders != wantMemo || tagged.renders != wantTagged {
+			t.Fatalf("got %d and %d renders, want %d and %d", memo.renders, tagged.renders, wantMemo, wantTagged)
+		}
+	}
+	check(1, 1)
+
+	// A new but equal slice is compared using its Equal method.
+	tags = tagList{"x"}
+	check(1, 1)
+	tags = tagList{"x", "y"}
+	check(2, 1)
+
+	name = "b"
+	check(3, 2)
+	checkHTML(t, memBody(), "<p>b</p><p>b</p>")
+
+	// Functions are not comparable, and always considered changed.
+	onClick = func() {}
+	check(3, 3)
+
+	// Rerender always renders the component.
+	RerenderSync(memo)
+	if memo.renders != 4 {
+		t.Fatalf("got %d renders, want 4", memo.renders)
+	}
+}
@@ -0,0 +1,86 @@
+package vecty
+
+// portal is the content of a Portal, which is rendered into a target DOM node
+// rather than in place.
+type portal struct {
+	target jsObject
+	// content is the fragment rendered into the target.
+	content KeyedList
+	// removed tracks whether the content has been removed from the target.
+	removed bool
+	// listening tracks whether the portal added its target as a root of the
+	// event delegator, which is passed on to the portal whose content is
+	// reconciled in place of this one.
+	listening bool
+}
+
+// newPortal returns HTML which renders the children into the target DOM node.
+// In place, the returned HTML renders as an empty text node.
+func newPortal(methodName string, target jsObject, children []ComponentOrHTML) *HTML {
+	if target == nil || !target.Truthy() {
+		panic("vecty: " + methodName + ": invalid target node")
+	}
+	return &HTML{portal: &portal{
+		target:  target,
+		content: fragment(List(children)),
+	}}
+}
+
+// reconcilePortal reconciles the portal content of h, if any, against that of
+// the previous render.
+func (h *HTML) reconcilePortal(prev *HTML) []Mounter {
+	var prevPortal *portal
+	if prev != nil {
+		prevPortal = prev.portal
+	}
+	switch {
+	case h.portal == nil:
+		if prevPortal != nil {
+			prevPortal.remove()
+		}
+		return nil
+	case prevPortal != nil && !prevPortal.removed && prevPortal.target.Equal(h.portal.target):
+		// Reconcile the content in place of the previous content.
+		h.portal.listening, prevPortal.listening = prevPortal.listening, false
+		return h.portal.content.reconcile(renderPosition(prevPortal.content), prevPortal.content)
+	default:
+		if prevPortal != nil {
+			prevPortal.remove()
+		}
+		// Events fired within the content bubble to the target, rather than
+		// to the render root, so it must dispatch them too.
+		events.addRoot(h.portal.target)
+		h.portal.listening = true
+		return h.portal.content.reconcile(&HTML{node: h.portal.target}, nil)
+	}
+}
+
+// remove removes the portal content from the target, unmounting it.
+func (p *portal) remove() {
+	if p.removed {
+		return
+	}
+	p.removed = true
+	p.content.remove(&HTML{node: p.target})
+	p.unlisten()
+}
+
+// discard removes the DOM nodes of portal content whose render was discarded,
+// and so which was never mounted, from the target.
+func (p *portal) discard() {
+	if p.removed {
+		return
+	}
+	p.removed = true
+	discardNodes(nil, appendNodes(nil, p.content), nil)
+	p.unlisten()
+}
+
+// unlisten removes the target as a root of the event delegator, if the portal
+// added it.
+func (p *portal) unlisten() {
+	if p.listening {
+		events.removeRoot(p.target)
+		p.listening = false
+	}
+}
@@ -0,0 +1,60 @@
+// +build !js
+
+package vecty
+
+import "testing"
+
+// TestPortal tests that portal content is rendered into its target,
+// and follows the lifecycle of the portal's parent.
+func TestPortal(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	target := doc.Call("createElement", "div")
+	target.Set("id", "target")
+	doc.Get("documentElement").Call("appendChild", target)
+
+	modal := &fragmentComponent{items: []string{"a"}}
+	show := true
+	root := &componentFunc{}
+	root.render = func() ComponentOrHTML {
+		var portal ComponentOrHTML
+		if show {
+			portal = Portal(target, Tag("p", Text("modal")), modal)
+		}
+		return Tag("body", Tag("div", Markup(Class("app")), portal))
+	}
+	RenderBody(root)
+	check := func(wantTarget, wantBody string) {
+		t.Helper()
+		checkHTML(t, target, wantTarget)
+		checkHTML(t, memBody(), wantBody)
+	}
+	check(`<p>modal</p><li>a</li>`, `<div class="app"></div>`)
+	if modal.mounted != 1 {
+		t.Fatalf("got %d mounts, want 1", modal.mounted)
+	}
+
+	// Re-render components within the portal, and the portal's parent.
+	modal.items = []string{"a", "b"}
+	Rerender(modal)
+	RunAnimationFrames()
+	check(`<p>modal</p><li>a</li><li>b</li>`, `<div class="app"></div>`)
+	Rerender(root)
+	RunAnimationFrames()
+	check(`<p>modal</p><li>a</li><li>b</li>`, `<div class="app"></div>`)
+
+	// Removing the portal removes and unmounts its content.
+	show = false
+	Rerender(root)
+	RunAnimationFrames()
+	check(``, `<div class="app"></div>`)
+	if modal.unmounted != 1 {
+		t.Fatalf("got %d unmounts, want 1", modal.unmounted)
+	}
+
+	got := recoverStr(func() { Portal(nil) })
+	want := "vecty: Portal: invalid target node"
+	if got != want {
+		t.Fatalf("got panic %q want %q", got, want)
+	}
+}
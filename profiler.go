@@ -0,0 +1,157 @@
+package vecty
+
+import "strconv"
+
+// domOps is the number of DOM operations (node creation, and modifications of
+// nodes and their properties) performed by reconciliation.
+var domOps int
+
+// Profiler records how long each Component takes to render, for finding slow
+// components. Profiling is enabled by SetProfiler:
+//
+// 	p := &vecty.Profiler{}
+// 	vecty.SetProfiler(p)
+// 	... // interact with the application
+// 	vecty.SetProfiler(nil)
+// 	trace := p.Trace() // e.g. save to a file, and load in Chrome DevTools
+//
+// While profiling, each render is also recorded as a User Timing measure via
+// performance.mark and performance.measure, such that renders appear in the
+// browser's performance panel.
+type Profiler struct {
+	records []RenderProfile
+	// stack are the indexes of the records of the components being rendered,
+	// innermost last.
+	stack []int
+}
+
+// RenderProfile is the profile of a single render of a Component.
+type RenderProfile struct {
+	// Component is the type of the component, e.g. "main.PageView".
+	Component string
+
+	// Depth is the number of components which were being rendered when the
+	// render started, i.e. its nesting within the renders recorded before it.
+	Depth int
+
+	// Start is the time at which the render started, and Duration the time it
+	// took including the renders of its descendants, in milliseconds as
+	// reported by performance.now.
+	Start, Duration float64
+
+	// SelfDuration is the time the render took, excluding the renders of its
+	// descendants, in milliseconds.
+	SelfDuration float64
+
+	// Skipped reports whether rendering was skipped, e.g. by the component's
+	// SkipRender method or because it is memoized.
+	Skipped bool
+
+	// DOMOps is the number of DOM operations performed by the render (such as
+	// creating nodes and setting properties), excluding those performed by the
+	// renders of its descendants.
+	DOMOps int
+}
+
+// profiler is the Profiler in use, or nil if profiling is not enabled.
+var profiler *Profiler
+
+// SetProfiler sets the Profiler which records subsequent renders. If p is nil,
+// profiling stops.
+func SetProfiler(p *Profiler) {
+	profiler = p
+}
+
+// Records returns the profiles of the renders recorded, in the order the
+// renders started.
+func (p *Profiler) Records() []RenderProfile {
+	return p.records
+}
+
+// Reset discards the renders recorded.
+func (p *Profiler) Reset() {
+	p.records = nil
+	p.stack = nil
+}
+
+// begin records the start of a render of the component, returning the index
+// of its record.
+func (p *Profiler) begin(c Component) int {
+	i := len(p.records)
+	p.records = append(p.records, RenderProfile{
+		Component: componentName(c),
+		Depth:     len(p.stack),
+		Start:     now(),
+		DOMOps:    -domOps,
+	})
+	p.stack = append(p.stack, i)
+	if performance := global().Get("performance"); performance.Get("mark").Truthy() {
+		performance.Call("mark", p.markName(i))
+	}
+	return i
+}
+
+// end records the end of the render with the record index i.
+func (p *Profiler) end(i int, skipped bool) {
+	if len(p.stack) == 0 || p.stack[len(p.stack)-1] != i {
+		// Reset while rendering.
+		return
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+	r := &p.records[i]
+	r.Duration = now() - r.Start
+	r.SelfDuration += r.Duration
+	r.DOMOps += domOps
+	r.Skipped = skipped
+	if len(p.stack) > 0 {
+		parent := &p.records[p.stack[len(p.stack)-1]]
+		parent.SelfDuration -= r.Duration
+		parent.DOMOps -= r.DOMOps
+	}
+	if performance := global().Get("performance"); performance.Get("measure").Truthy() {
+		name := "vecty " + r.Component
+		if skipped {
+			name += " (skipped)"
+		}
+		mark := p.markName(i)
+		performance.Call("measure", name, mark)
+		performance.Call("clearMarks", mark)
+	}
+}
+
+// markName returns the name of the performance mark for the start of the
+// render with the record index i.
+func (p *Profiler) markName(i int) string {
+	return "vecty:" + strconv.Itoa(i)
+}
+
+// Trace returns the renders recorded in the Chrome Trace Event format, as
+// JSON, which can be loaded into e.g. the performance panel of Chrome DevTools
+// or https://ui.perfetto.dev. See
+// https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU.
+func (p *Profiler) Trace() []byte {
+	b := []byte(`{"displayTimeUnit":"ms","traceEvents":[`)
+	for i, r := range p.records {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, `{"name":`...)
+		b = appendJSONString(b, r.Component)
+		b = append(b, `,"cat":"vecty","ph":"X","pid":1,"tid":1,"ts":`...)
+		b = strconv.AppendFloat(b, r.Start*1000, 'f', 3, 64)
+		b = append(b, `,"dur":`...)
+		b = strconv.AppendFloat(b, r.Duration*1000, 'f', 3, 64)
+		b = append(b, `,"args":{"skipped":`...)
+		b = strconv.AppendBool(b, r.Skipped)
+		b = append(b, `,"domOps":`...)
+		b = strconv.AppendInt(b, int64(r.DOMOps), 10)
+		b = append(b, `}}`...)
+	}
+	return append(b, `]}`...)
+}
+
+// now returns the current time in milliseconds, as reported by
+// performance.now.
+func now() float64 {
+	return global().Get("performance").Call("now").Float()
+}
@@ -0,0 +1,80 @@
+// +build !js
+
+package vecty
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// TestProfiler tests that the profiler records renders, and exports
+// them as a trace.
+func TestProfiler(t *testing.T) {
+	ResetDOM()
+	p := &Profiler{}
+	SetProfiler(p)
+	defer SetProfiler(nil)
+
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", &memoComponent{Name: "a"}, &countComponent{id: "b"})
+	}}
+	RenderBody(comp)
+	RerenderSync(comp)
+
+	type record struct {
+		Component string
+		Depth     int
+		Skipped   bool
+		DOMOps    int
+	}
+	var got []record
+	for _, r := range p.Records() {
+		if r.Duration < 0 || r.SelfDuration < 0 || r.SelfDuration > r.Duration {
+			t.Fatalf("invalid durations %+v", r)
+		}
+		got = append(got, record{r.Component, r.Depth, r.Skipped, r.DOMOps})
+	}
+	want := []record{
+		// Initial render: nodes are created and appended, and the id set.
+		{"vecty.componentFunc", 0, false, 3},
+		{"vecty.memoComponent", 1, false, 3},
+		{"vecty.countComponent", 1, false, 4},
+		// Re-render: only the count changes.
+		{"vecty.componentFunc", 0, false, 0},
+		{"vecty.memoComponent", 1, true, 0},
+		{"vecty.countComponent", 1, false, 1},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got  %v\nwant %v", got, want)
+	}
+
+	measures := global().(*memWindow).measures
+	wantMeasures := []string{"vecty vecty.memoComponent", "vecty vecty.countComponent", "vecty vecty.componentFunc"}
+	if len(measures) != 6 || fmt.Sprint(measures[:3]) != fmt.Sprint(wantMeasures) || measures[3] != "vecty vecty.memoComponent (skipped)" {
+		t.Fatalf("got measures %q", measures)
+	}
+
+	var trace struct {
+		TraceEvents []struct {
+			Name string
+			Ph   string
+			Ts   float64
+			Dur  float64
+			Args struct {
+				Skipped bool
+				DOMOps  int
+			}
+		}
+	}
+	if err := json.Unmarshal(p.Trace(), &trace); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.TraceEvents) != 6 {
+		t.Fatalf("got %d trace events, want 6", len(trace.TraceEvents))
+	}
+	e := trace.TraceEvents[4]
+	if e.Name != "vecty.memoComponent" || e.Ph != "X" || !e.Args.Skipped {
+		t.Fatalf("got trace event %+v", e)
+	}
+}
@@ -0,0 +1,102 @@
+// +build !js
+
+package vecty
+
+import (
+	"reflect"
+	"testing"
+)
+
+// refComponent renders an element of the given tag, if show is set, which is
+// referenced by input and passed to a RefFunc, and records the ref at Mount.
+type refComponent struct {
+	Core
+	show       bool
+	tag        string
+	input      *HTML
+	mountInput *HTML
+	calls      []*HTML
+}
+
+func (c *refComponent) Render() ComponentOrHTML {
+	var input ComponentOrHTML
+	if c.show {
+		input = Tag(c.tag, Markup(Ref(&c.input), RefFunc(func(h *HTML) {
+			c.calls = append(c.calls, h)
+		})))
+	}
+	return Tag("body", input)
+}
+
+func (c *refComponent) Mount() { c.mountInput = c.input }
+
+// TestRef tests that refs are kept up to date with the rendered
+// element, and cleared when it is removed.
+func TestRef(t *testing.T) {
+	c := &refComponent{show: true, tag: "input"}
+	renderBody(c)
+	if c.mountInput == nil || c.mountInput.Node() != memBody().Get("firstChild") {
+		t.Fatal("want ref set to the input before Mount")
+	}
+	first := c.input
+
+	// Re-rendering reuses the DOM node, but updates the ref.
+	Rerender(c)
+	RunAnimationFrames()
+	if c.input == first || c.input.Node() != first.Node() {
+		t.Fatal("want ref updated to the re-rendered input")
+	}
+
+	// Changing the element creates a new DOM node.
+	c.tag = "textarea"
+	Rerender(c)
+	RunAnimationFrames()
+	if c.input == nil || c.input.Node() != memBody().Get("firstChild") || c.input.Node().Get("localName").String() != "textarea" {
+		t.Fatal("want ref set to the textarea")
+	}
+
+	// Removing the element clears the ref.
+	c.show = false
+	Rerender(c)
+	RunAnimationFrames()
+	if c.input != nil {
+		t.Fatal("want ref cleared after removal")
+	}
+	var got []bool
+	for _, h := range c.calls {
+		got = append(got, h != nil)
+	}
+	if want := []bool{true, false, true, false, true, false}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got RefFunc calls (non-nil) %v want %v", got, want)
+	}
+
+	// Refs are set before the Mount methods of the components rendered within
+	// or before the referenced elements.
+	var (
+		form, input *HTML
+		mounted     []bool
+	)
+	renderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			Tag("form", Markup(Ref(&form)), &mountFunc{mount: func() {
+				mounted = append(mounted, form != nil && input != nil)
+			}}),
+			&mountFunc{mount: func() {
+				mounted = append(mounted, form != nil && input != nil)
+			}},
+			Tag("input", Markup(Ref(&input))),
+		)
+	}})
+	if want := []bool{true, true}; !reflect.DeepEqual(mounted, want) {
+		t.Fatalf("got refs set in Mount %v want %v", mounted, want)
+	}
+}
+
+// mountFunc is a component which calls mount when mounted.
+type mountFunc struct {
+	Core
+	mount func()
+}
+
+func (c *mountFunc) Render() ComponentOrHTML { return Tag("span") }
+func (c *mountFunc) Mount()                  { c.mount() }
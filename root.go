@@ -0,0 +1,215 @@
+package vecty
+
+// Root is a mount point for a component tree, such as for a widget embedded in
+// a page which is otherwise not rendered by Vecty:
+//
+// 	root, err := vecty.NewRoot("#widget")
+// 	if err != nil {
+// 		panic(err)
+// 	}
+// 	if err := root.Render(&Widget{}); err != nil {
+// 		panic(err)
+// 	}
+// 	...
+// 	root.Unmount() // e.g. when the page removes the widget
+//
+// Each Root batches and schedules the re-renders of its components
+// independently of other roots, such that Unmount discards those pending.
+type Root struct {
+	method string
+	// target is the element which the rendered component replaces.
+	target jsObject
+	// component is the component currently rendered, if any.
+	component Component
+	// batch holds the pending re-renders of the root's components.
+	batch *batchRenderer
+	// loading is the pending DOMContentLoaded listener, if the document was
+	// still loading when rendered.
+	loading jsFunc
+}
+
+// roots are the mounted roots.
+var roots []*Root
+
+// NewRoot returns a Root which renders into the existing HTML element found by
+// the CSS selector (e.g. "#id", ".class-name"), by replacing it. Nothing is
+// rendered until Render is called.
+//
+// If there is more than one element found, the first is used. If no element is
+// found, an error of type InvalidTargetError is returned.
+//
+// Unlike RenderBody, NewRoot does not block. The program must be kept running
+// for components to re-render themselves, e.g. by the page which embeds it.
+func NewRoot(selector string) (*Root, error) {
+	target := global().Get("document").Call("querySelector", selector)
+	return newRoot("NewRoot", target)
+}
+
+// newRoot returns a Root which renders into the target node.
+func newRoot(methodName string, target jsObject) (*Root, error) {
+	if target == nil || !target.Truthy() {
+		return nil, InvalidTargetError{method: methodName}
+	}
+	return &Root{method: methodName, target: target, batch: &batchRenderer{}}, nil
+}
+
+// Render renders the given component into the root.
+//
+// If a component of the same type is already rendered, its properties are
+// updated and it is re-rendered, as when a parent renders a child component.
+// Otherwise, the component replaces any component already rendered, which is
+// unmounted.
+//
+// If the Component's Render method does not return an element of the same type
+// as the root's target element, an error of type ElementMismatchError is
+// returned. A component which replaces another is then not rendered, leaving
+// the other in place, while an updated component has already been re-rendered,
+// and so is unmounted as if by Unmount.
+func (r *Root) Render(c Component) error {
+	if c == nil {
+		panic("vecty: Root.Render illegally called with a nil Component argument")
+	}
+	return r.render(r.method, c)
+}
+
+// render implements Render, as well as RenderInto and friends.
+func (r *Root) render(methodName string, c Component) error {
+	if prev := r.component; prev != nil && sameType(c, prev) {
+		// Update the previous instance in place, as its parent would.
+		if len(rendering) > 0 {
+			panic("vecty: " + methodName + " illegally called while rendering")
+		}
+		mount(renderInPlace(prev, func(parent *HTML) (*HTML, bool, []Mounter) {
+			return renderComponent(c, prev, parent)
+		})...)
+		if err := r.checkElement(methodName, extractHTML(prev)); err != nil {
+			r.Unmount()
+			return err
+		}
+		return nil
+	}
+
+	// Block the batch until we're done.
+	r.batch.hold()
+	c.Context().root = r
+	rendering = rendering[:0]
+	nextRender, skip, pendingMounts := renderComponent(c, nil, nil)
+	if skip {
+		panic("vecty: " + methodName + ": Component.SkipRender illegally returned true")
+	}
+	if err := r.checkElement(methodName, nextRender); err != nil {
+		r.batch.reschedule()
+		return err
+	}
+	commit := func() {
+		if prev := r.component; prev != nil {
+			prevNode := firstNode(prev)
+			replaceNode(nextRender.node, prevNode)
+			events.replaceRoot(prevNode, nextRender.node)
+			unmount(prev)
+		} else {
+			replaceNode(nextRender.node, r.target)
+			events.addRoot(nextRender.node)
+			roots = append(roots, r)
+		}
+		r.component = c
+		mount(pendingMounts...)
+		if m, ok := c.(Mounter); ok {
+			mount(m)
+		}
+		r.batch.release()
+	}
+	doc := global().Get("document")
+	if doc.Get("readyState").String() == "loading" {
+		r.cancelLoading()
+		r.loading = funcOf(func(this jsObject, args []jsObject) interface{} {
+			r.loading.Release()
+			r.loading = nil
+			commit()
+			return undefined()
+		})
+		doc.Call("addEventListener", "DOMContentLoaded", r.loading)
+		return nil
+	}
+	commit()
+	return nil
+}
+
+// checkElement returns an ElementMismatchError if h, the render of the root's
+// component, is not an element of the same type as the target element.
+func (r *Root) checkElement(methodName string, h *HTML) error {
+	var got string
+	if h != nil {
+		got = h.tag
+	}
+	if want := toLower(r.target.Get("nodeName").String()); got != want {
+		return ElementMismatchError{method: methodName, got: got, want: want}
+	}
+	return nil
+}
+
+// mounted registers the root as mounted, once the component c has been
+// hydrated into its target.
+func (r *Root) mounted(c Component) {
+	r.component = c
+	roots = append(roots, r)
+}
+
+// cancelLoading removes the pending DOMContentLoaded listener, if any.
+func (r *Root) cancelLoading() {
+	if r.loading == nil {
+		return
+	}
+	global().Get("document").Call("removeEventListener", "DOMContentLoaded", r.loading)
+	r.loading.Release()
+	r.loading = nil
+}
+
+// Unmount unmounts the rendered component, if any, calling the Unmount method
+// of every component that implements Unmounter, discarding pending re-renders
+// and removing all event listeners added by the root. The rendered DOM is
+// replaced by the target element it replaced, such that the page is left as it
+// was before rendering (a hydrated target element is left in place, but its
+// components are unmounted).
+//
+// The root may be rendered into again after it has been unmounted.
+func (r *Root) Unmount() {
+	r.cancelLoading()
+	c := r.component
+	if c == nil {
+		return
+	}
+	if len(rendering) > 0 {
+		panic("vecty: Root.Unmount illegally called while rendering")
+	}
+	node := firstNode(c)
+	r.component = nil
+	unmount(c)
+	r.batch.discard()
+	events.removeRoot(node)
+	replaceNode(r.target, node)
+	for i, root := range roots {
+		if root == r {
+			roots = append(roots[:i:i], roots[i+1:]...)
+			break
+		}
+	}
+}
+
+// batchOf returns the batch which queues re-renders of the component, that of
+// the root it was rendered by.
+func batchOf(c Component) *batchRenderer {
+	if r := c.Context().root; r != nil {
+		return r.batch
+	}
+	return batch
+}
+
+// allBatches calls fn with the batch of each mounted root, and the batch of
+// components rendered without a root.
+func allBatches(fn func(b *batchRenderer)) {
+	fn(batch)
+	for _, r := range append([]*Root(nil), roots...) {
+		fn(r.batch)
+	}
+}
@@ -0,0 +1,168 @@
+// +build !js
+
+package vecty
+
+import (
+	"strconv"
+	"testing"
+)
+
+// countComponent renders the number of times it has been rendered.
+type countComponent struct {
+	Core
+	id      string
+	renders int
+}
+
+func (c *countComponent) Render() ComponentOrHTML {
+	c.renders++
+	return Tag("p", Markup(Property("id", c.id)), Text(strconv.Itoa(c.renders)))
+}
+
+// rootComponent renders a div with a click listener, and records when it is
+// unmounted.
+type rootComponent struct {
+	Core
+	Label     string `vecty:"prop"`
+	Tag       string `vecty:"prop"`
+	clicks    int
+	unmounted bool
+}
+
+func (c *rootComponent) Render() ComponentOrHTML {
+	tag := c.Tag
+	if tag == "" {
+		tag = "div"
+	}
+	return Tag(tag,
+		Markup(
+			Property("id", "widget"),
+			&EventListener{Name: "click", Listener: func(*Event) { c.clicks++ }},
+		),
+		Text(c.Label),
+	)
+}
+
+func (c *rootComponent) Unmount() { c.unmounted = true }
+
+// TestRoot tests that roots render, swap and unmount their
+// components, and batch their re-renders independently.
+func TestRoot(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	target := doc.Call("createElement", "div")
+	target.Set("id", "widget")
+	memBody().Call("appendChild", target)
+	other := doc.Call("createElement", "p")
+	other.Set("id", "other")
+	memBody().Call("appendChild", other)
+
+	if _, err := NewRoot("#missing"); err == nil {
+		t.Fatal("expected InvalidTargetError")
+	}
+	root, err := NewRoot("#widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &rootComponent{Label: "a"}
+	if err := root.Render(first); err != nil {
+		t.Fatal(err)
+	}
+	doc.Call("getElementById", "widget").Call("click")
+	if first.clicks != 1 {
+		t.Fatalf("got %d clicks, want 1", first.clicks)
+	}
+
+	// Rendering a component of the same type updates the previous instance.
+	if err := root.Render(&rootComponent{Label: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<body><div id="widget">b</div><p id="other"></p></body>`
+	if got := memBody().Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	doc.Call("getElementById", "widget").Call("click")
+	if first.clicks != 2 || first.unmounted {
+		t.Fatalf("got %d clicks and unmounted %v, want 2 and false", first.clicks, first.unmounted)
+	}
+
+	// Rendering an element of the wrong type leaves the component in place.
+	if err := root.Render(&componentFunc{render: func() ComponentOrHTML { return Tag("span") }}); err == nil {
+		t.Fatal("expected ElementMismatchError")
+	}
+	if got := memBody().Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Roots batch re-renders independently, such that unmounting one discards
+	// its pending re-renders only.
+	otherRoot, err := NewRoot("#other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter := &countComponent{id: "other"}
+	if err := otherRoot.Render(counter); err != nil {
+		t.Fatal(err)
+	}
+	Rerender(first)
+	Rerender(counter)
+	root.Unmount()
+	RunAnimationFrames()
+	if !first.unmounted {
+		t.Fatal("expected component to be unmounted")
+	}
+	if counter.renders != 2 {
+		t.Fatalf("got %d renders, want 2", counter.renders)
+	}
+
+	// Unmounting restores the target and removes the root's listeners.
+	want = `<body><div id="widget"></div><p id="other">2</p></body>`
+	if got := memBody().Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if !doc.Call("getElementById", "widget").Equal(target) {
+		t.Fatal("expected target element to be restored")
+	}
+	if got := len(target.(*memNode).listeners); got != 0 {
+		t.Fatalf("got %d native listeners on target, want 0", got)
+	}
+	root.Unmount()
+
+	// Unmounted roots may be rendered into again.
+	if err := root.Render(&rootComponent{Label: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	want = `<body><div id="widget">c</div><p id="other">2</p></body>`
+	if got := memBody().Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Updating the component such that it renders another element unmounts
+	// it.
+	third := root.component.(*rootComponent)
+	err = root.Render(&rootComponent{Label: "c", Tag: "p"})
+	if want := (ElementMismatchError{method: "NewRoot", got: "p", want: "div"}); err != want {
+		t.Fatalf("got error %v want %v", err, want)
+	}
+	if !third.unmounted || root.component != nil || !doc.Call("getElementById", "widget").Equal(target) {
+		t.Fatal("expected root to be unmounted")
+	}
+	if err := root.Render(&rootComponent{Label: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rendering a component of another type unmounts the previous one.
+	third = root.component.(*rootComponent)
+	if err := root.Render(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("div", Text("d"))
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if !third.unmounted {
+		t.Fatal("expected replaced component to be unmounted")
+	}
+	want = `<body><div>d</div><p id="other">2</p></body>`
+	if got := memBody().Get("outerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,280 @@
+package vecty
+
+// Priority is the priority of a re-render, which determines when the Scheduler
+// performs it.
+type Priority int
+
+const (
+	// PriorityUrgent is for re-renders in response to user input, which are
+	// performed as soon as possible, ahead of any other work. It is the
+	// priority used by Rerender when called from an EventListener.
+	PriorityUrgent Priority = iota
+
+	// PriorityNormal is the default priority of re-renders, which are
+	// performed on the next animation frame.
+	PriorityNormal
+
+	// PriorityIdle is for re-renders which are not time sensitive, such as of
+	// off-screen content, which are performed when the browser is idle.
+	PriorityIdle
+
+	// priorityCount is the number of priorities.
+	priorityCount
+)
+
+// Deadline reports how much time remains for performing scheduled work.
+type Deadline interface {
+	// TimeRemaining returns the number of milliseconds remaining before the
+	// work should yield, e.g. to allow the browser to paint the next frame.
+	TimeRemaining() float64
+}
+
+// Scheduler decides when re-renders queued by Rerender are performed. The
+// Scheduler in use is set by SetScheduler.
+type Scheduler interface {
+	// Schedule requests that work be called once, at a time appropriate for
+	// the given priority. Work which is not completed by the deadline it is
+	// called with schedules the remainder again.
+	Schedule(p Priority, work func(d Deadline))
+}
+
+// scheduler is the Scheduler in use.
+var scheduler Scheduler = &browserScheduler{}
+
+// SetScheduler sets the Scheduler used to perform re-renders. If s is nil, the
+// default Scheduler is used, which:
+//
+// - performs urgent re-renders in a microtask, before the browser next paints.
+//
+// - performs normal re-renders on the next animation frame, within a time
+// budget of one frame as measured from the display's refresh rate (e.g. ~16ms
+// at 60Hz and ~8ms at 120Hz), deferring the remainder to the following frame.
+//
+// - performs idle re-renders via requestIdleCallback when the browser is idle,
+// or as normal re-renders if requestIdleCallback is not supported.
+//
+// - pauses normal and idle re-renders while the page is hidden (see the
+// visibilitychange event), resuming them once it becomes visible again.
+//
+// Re-renders which are already queued are rescheduled with s.
+func SetScheduler(s Scheduler) {
+	if s == nil {
+		s = &browserScheduler{}
+	}
+	if prev, ok := scheduler.(*browserScheduler); ok && prev != s {
+		prev.stop()
+	}
+	scheduler = s
+	allBatches((*batchRenderer).reschedule)
+}
+
+// scheduledWork is work passed to Scheduler.Schedule.
+type scheduledWork struct {
+	p    Priority
+	work func(d Deadline)
+}
+
+// unlimited is the time remaining reported by a Deadline without a time
+// budget.
+const unlimited = 1e9
+
+// noDeadline is a Deadline without a time budget.
+type noDeadline struct{}
+
+// TimeRemaining implements the Deadline interface.
+func (noDeadline) TimeRemaining() float64 { return unlimited }
+
+// frameDeadline is the Deadline for work performed in an animation frame.
+type frameDeadline struct {
+	// start is the time at which the frame started, and budget the duration
+	// of the frame, in milliseconds.
+	start, budget float64
+}
+
+// TimeRemaining implements the Deadline interface.
+func (d frameDeadline) TimeRemaining() float64 {
+	return d.budget - (global().Get("performance").Call("now").Float() - d.start)
+}
+
+// idleDeadline is the Deadline for work performed via requestIdleCallback.
+type idleDeadline struct {
+	d jsObject
+}
+
+// TimeRemaining implements the Deadline interface.
+func (d idleDeadline) TimeRemaining() float64 {
+	return d.d.Call("timeRemaining").Float()
+}
+
+// browserScheduler is the default Scheduler, see SetScheduler.
+type browserScheduler struct {
+	// frameInterval is the estimated duration of a frame, and lastFrame the
+	// start time of the last animation frame, in milliseconds.
+	frameInterval, lastFrame float64
+	// visibilityChange is the visibilitychange listener, once added.
+	visibilityChange jsFunc
+	// paused is work deferred until the page becomes visible.
+	paused []scheduledWork
+}
+
+// Schedule implements the Scheduler interface.
+func (s *browserScheduler) Schedule(p Priority, work func(d Deadline)) {
+	if p == PriorityUrgent {
+		s.microtask(func() { work(noDeadline{}) })
+		return
+	}
+	if s.hidden() {
+		s.paused = append(s.paused, scheduledWork{p: p, work: work})
+		return
+	}
+	if p == PriorityIdle && global().Get("requestIdleCallback").Truthy() {
+		var cb jsFunc
+		cb = funcOf(func(this jsObject, args []jsObject) interface{} {
+			cb.Release()
+			work(idleDeadline{d: args[0]})
+			return undefined()
+		})
+		global().Call("requestIdleCallback", cb)
+		return
+	}
+	requestAnimationFrame(func(timestamp float64) {
+		work(s.frameDeadline(timestamp))
+	})
+}
+
+// microtask calls fn in a microtask if supported, or otherwise on the next
+// animation frame.
+func (s *browserScheduler) microtask(fn func()) {
+	if !global().Get("queueMicrotask").Truthy() {
+		requestAnimationFrame(func(float64) { fn() })
+		return
+	}
+	var cb jsFunc
+	cb = funcOf(func(this jsObject, args []jsObject) interface{} {
+		cb.Release()
+		fn()
+		return undefined()
+	})
+	global().Call("queueMicrotask", cb)
+}
+
+// minFrameInterval is the shortest frame interval assumed by the default
+// Scheduler, in milliseconds, i.e. that of a 240Hz display.
+const minFrameInterval = 1000.0 / 240
+
+// frameDeadline returns the deadline for work performed in the animation frame
+// which started at timestamp, adapting the frame budget to the display's
+// refresh rate as measured between consecutive frames.
+func (s *browserScheduler) frameDeadline(timestamp float64) Deadline {
+	if s.frameInterval == 0 {
+		s.frameInterval = 1000 / 60
+	}
+	if delta := timestamp - s.lastFrame; s.lastFrame > 0 && delta > 0 {
+		switch {
+		case delta < s.frameInterval:
+			// A faster display than previously measured, up to 240Hz.
+			s.frameInterval = delta
+			if s.frameInterval < minFrameInterval {
+				s.frameInterval = minFrameInterval
+			}
+		case delta < s.frameInterval*1.5:
+			// Consecutive frames, smooth out any jitter.
+			s.frameInterval += (delta - s.frameInterval) / 8
+		}
+	}
+	s.lastFrame = timestamp
+	return frameDeadline{start: timestamp, budget: s.frameInterval}
+}
+
+// hidden reports whether the page is hidden, adding a visibilitychange
+// listener to resume paused work if it has not already been added.
+func (s *browserScheduler) hidden() bool {
+	doc := global().Get("document")
+	if s.visibilityChange == nil {
+		s.visibilityChange = funcOf(func(this jsObject, args []jsObject) interface{} {
+			s.resume()
+			return undefined()
+		})
+		doc.Call("addEventListener", "visibilitychange", s.visibilityChange)
+	}
+	return doc.Get("visibilityState").String() == "hidden"
+}
+
+// stop removes and releases the visibilitychange listener, once the scheduler
+// has been replaced by SetScheduler. Its paused work is rescheduled along with
+// the other queued re-renders.
+func (s *browserScheduler) stop() {
+	if s.visibilityChange == nil {
+		return
+	}
+	global().Get("document").Call("removeEventListener", "visibilitychange", s.visibilityChange)
+	s.visibilityChange.Release()
+	s.visibilityChange = nil
+	s.paused = nil
+}
+
+// resume schedules paused work if the page has become visible.
+func (s *browserScheduler) resume() {
+	if s.hidden() {
+		return
+	}
+	paused := s.paused
+	s.paused = nil
+	for _, w := range paused {
+		s.Schedule(w.p, w.work)
+	}
+}
+
+// ManualScheduler is a Scheduler which performs scheduled work only when Run
+// is called, such that tests can deterministically control when re-renders
+// occur:
+//
+// 	s := &vecty.ManualScheduler{}
+// 	vecty.SetScheduler(s)
+// 	...
+// 	vecty.Rerender(c)
+// 	s.Run() // c is re-rendered
+//
+// Work performed by a ManualScheduler has no time budget.
+type ManualScheduler struct {
+	pending []scheduledWork
+}
+
+// Schedule implements the Scheduler interface.
+func (s *ManualScheduler) Schedule(p Priority, work func(d Deadline)) {
+	s.pending = append(s.pending, scheduledWork{p: p, work: work})
+}
+
+// Pending returns the number of scheduled units of work which have not been
+// performed yet.
+func (s *ManualScheduler) Pending() int { return len(s.pending) }
+
+// Run performs all currently scheduled work, most urgent first and otherwise
+// in the order it was scheduled, and returns the number of units of work
+// performed. Work scheduled while running is deferred to the next call.
+func (s *ManualScheduler) Run() int {
+	n := 0
+	for p := PriorityUrgent; p < priorityCount; p++ {
+		n += s.RunPriority(p)
+	}
+	return n
+}
+
+// RunPriority is like Run, except that only work of the given priority is
+// performed.
+func (s *ManualScheduler) RunPriority(p Priority) int {
+	var run []scheduledWork
+	remaining := s.pending[:0:0]
+	for _, w := range s.pending {
+		if w.p == p {
+			run = append(run, w)
+		} else {
+			remaining = append(remaining, w)
+		}
+	}
+	s.pending = remaining
+	for _, w := range run {
+		w.work(noDeadline{})
+	}
+	return len(run)
+}
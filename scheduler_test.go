@@ -0,0 +1,132 @@
+// +build !js
+
+package vecty
+
+import "testing"
+
+// TestScheduler tests that the default Scheduler renders re-renders
+// from event listeners immediately, and pauses others while the page is
+// hidden.
+func TestScheduler(t *testing.T) {
+	a := &countComponent{id: "a"}
+	renderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			Tag("button", Markup(&EventListener{Name: "click", Listener: func(e *Event) { Rerender(a) }})),
+			a,
+		)
+	}})
+	RunAnimationFrames()
+	doc := global().Get("document")
+
+	doc.Call("querySelector", "button").Call("click")
+	if a.renders != 2 {
+		t.Fatalf("got %d renders, want 2", a.renders)
+	}
+
+	doc.Set("visibilityState", "hidden")
+	Rerender(a)
+	RerenderWithPriority(a, PriorityIdle)
+	if got := RunAnimationFrames(); got != 0 {
+		t.Fatalf("got %d animation frames, want 0", got)
+	}
+	if a.renders != 2 {
+		t.Fatalf("got %d renders, want 2", a.renders)
+	}
+
+	doc.Set("visibilityState", "visible")
+	ev := doc.Call("createEvent", "Event")
+	ev.Call("initEvent", "visibilitychange", false, false)
+	doc.Call("dispatchEvent", ev)
+	RunAnimationFrames()
+	if a.renders != 3 {
+		t.Fatalf("got %d renders, want 3", a.renders)
+	}
+	if got := doc.Call("querySelector", "#a").Get("textContent").String(); got != "3" {
+		t.Fatalf("got %q, want %q", got, "3")
+	}
+}
+
+// TestSetScheduler_release tests that replacing the default scheduler removes
+// its visibilitychange listener, and reschedules its paused work.
+func TestSetScheduler_release(t *testing.T) {
+	a := &countComponent{id: "a"}
+	renderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", a)
+	}})
+	doc := global().Get("document")
+	doc.Set("visibilityState", "hidden")
+	Rerender(a)
+	listeners := func() int {
+		n := 0
+		for _, l := range doc.(*memNode).listeners {
+			if l.typ == "visibilitychange" {
+				n++
+			}
+		}
+		return n
+	}
+	if got := listeners(); got != 1 {
+		t.Fatalf("got %d visibilitychange listeners, want 1", got)
+	}
+
+	s := &ManualScheduler{}
+	SetScheduler(s)
+	if got := listeners(); got != 0 {
+		t.Fatalf("got %d visibilitychange listeners, want 0", got)
+	}
+	s.Run()
+	if a.renders != 2 {
+		t.Fatalf("got %d renders, want 2", a.renders)
+	}
+}
+
+// TestManualScheduler tests that a ManualScheduler performs
+// re-renders only when run, most urgent first.
+func TestManualScheduler(t *testing.T) {
+	ResetDOM()
+	s := &ManualScheduler{}
+	SetScheduler(s)
+	a, b := &countComponent{id: "a"}, &countComponent{id: "b"}
+	RenderBody(&componentFunc{render: func() ComponentOrHTML {
+		return Tag("body", a, b)
+	}})
+	for s.Run() > 0 {
+	}
+	if s.Pending() != 0 {
+		t.Fatalf("got %d pending, want 0", s.Pending())
+	}
+
+	Rerender(a)
+	RerenderWithPriority(b, PriorityIdle)
+	if got := RunAnimationFrames(); got != 0 {
+		t.Fatalf("got %d animation frames, want 0", got)
+	}
+	if got := s.RunPriority(PriorityNormal); got != 1 {
+		t.Fatalf("got %d units of work, want 1", got)
+	}
+	if a.renders != 2 || b.renders != 1 {
+		t.Fatalf("got %d and %d renders, want 2 and 1", a.renders, b.renders)
+	}
+	s.RunPriority(PriorityIdle)
+	if b.renders != 2 {
+		t.Fatalf("got %d renders, want 2", b.renders)
+	}
+
+	// A component queued more than once renders once, with the most urgent
+	// priority.
+	RerenderWithPriority(a, PriorityIdle)
+	RerenderWithPriority(a, PriorityUrgent)
+	RerenderWithPriority(a, PriorityNormal)
+	s.RunPriority(PriorityUrgent)
+	for s.Run() > 0 {
+	}
+	if a.renders != 3 {
+		t.Fatalf("got %d renders, want 3", a.renders)
+	}
+	checkHTML(t, memBody(), `<p id="a">3</p><p id="b">2</p>`)
+
+	got := recoverStr(func() { RerenderWithPriority(a, priorityCount) })
+	if want := "vecty: RerenderWithPriority illegally called with an invalid Priority"; got != want {
+		t.Fatalf("got panic %q want %q", got, want)
+	}
+}
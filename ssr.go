@@ -0,0 +1,510 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RenderToString renders the given component to an HTML string, for example
+// in order to serve server-side rendered pages or emails from a native Go
+// program.
+//
+// Unlike RenderBody and RenderInto, RenderToString does not require a DOM and
+// does not retain any state: the Render method of every component in the tree
+// is invoked exactly once, SkipRender is never consulted, and Mounter,
+// Unmounter and event listeners are ignored.
+//
+// RenderToString may be called concurrently, provided that the components
+// themselves allow it. A component which calls Provide or Consume must not be
+// rendered by concurrent calls, as its values cannot be told apart.
+func RenderToString(c Component) (string, error) {
+	var b strings.Builder
+	if err := RenderToWriter(&b, c); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// RenderToWriter is like RenderToString, except the HTML is written to w.
+//
+// If writing to w fails, the first write error is returned and rendering
+// stops.
+func RenderToWriter(w io.Writer, c Component) error {
+	if c == nil {
+		panic("vecty: RenderToWriter illegally called with a nil Component argument")
+	}
+	s := &stringRenderer{w: w}
+	defer s.popTo(0)
+	s.renderChild(c)
+	return s.err
+}
+
+// ServerRenderError is returned when a component tree cannot be rendered to
+// an HTML string.
+type ServerRenderError struct {
+	msg string
+}
+
+func (e ServerRenderError) Error() string {
+	return "vecty: RenderToString: " + e.msg
+}
+
+// voidElements are HTML elements which may not have children nor a closing
+// tag.
+//
+// See https://html.spec.whatwg.org/multipage/syntax.html#void-elements
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements are HTML elements whose text content is not escaped.
+var rawTextElements = map[string]bool{
+	"script": true,
+	"style":  true,
+}
+
+// propertyAttributes maps the JavaScript properties which are reflected by
+// HTML attributes to the names of those attributes. Other properties, such as
+// defaultValue, have no equivalent attribute and are not rendered.
+var propertyAttributes = map[string]string{
+	"accept": "accept", "acceptCharset": "accept-charset", "accessKey": "accesskey",
+	"action": "action", "allowFullscreen": "allowfullscreen", "alt": "alt",
+	"async": "async", "autocomplete": "autocomplete", "autofocus": "autofocus",
+	"autoplay": "autoplay", "charset": "charset", "checked": "checked",
+	"cite": "cite", "className": "class", "colSpan": "colspan", "cols": "cols",
+	"content": "content", "contentEditable": "contenteditable", "controls": "controls",
+	"crossOrigin": "crossorigin", "dateTime": "datetime", "defer": "defer",
+	"dir": "dir", "disabled": "disabled", "download": "download",
+	"draggable": "draggable", "enctype": "enctype", "headers": "headers",
+	"height": "height", "hidden": "hidden", "href": "href", "hreflang": "hreflang",
+	"htmlFor": "for", "httpEquiv": "http-equiv", "id": "id",
+	"integrity": "integrity", "label": "label", "lang": "lang",
+	"loading": "loading", "loop": "loop", "max": "max", "maxLength": "maxlength",
+	"media": "media", "method": "method", "min": "min", "minLength": "minlength",
+	"multiple": "multiple", "name": "name", "noValidate": "novalidate",
+	"open": "open", "pattern": "pattern", "placeholder": "placeholder",
+	"poster": "poster", "preload": "preload", "readOnly": "readonly",
+	"referrerPolicy": "referrerpolicy", "rel": "rel", "required": "required",
+	"reversed": "reversed", "rowSpan": "rowspan", "rows": "rows",
+	"sandbox": "sandbox", "scope": "scope", "selected": "selected",
+	"size": "size", "sizes": "sizes", "slot": "slot", "span": "span",
+	"spellcheck": "spellcheck", "src": "src", "srcdoc": "srcdoc",
+	"srclang": "srclang", "srcset": "srcset", "start": "start", "step": "step",
+	"tabIndex": "tabindex", "target": "target", "title": "title",
+	"translate": "translate", "type": "type", "value": "value",
+	"width": "width", "wrap": "wrap",
+}
+
+// stringRenderer renders components and HTML into an io.Writer.
+type stringRenderer struct {
+	w   io.Writer
+	err error
+	// selectValue is the value of the nearest enclosing select element, used
+	// to mark the matching option as selected.
+	selectValue *string
+	// rendering is the stack of components being rendered, innermost last,
+	// and provided the values provided by each of them, if any.
+	rendering []Component
+	provided  []map[interface{}]interface{}
+}
+
+// serverRenders maps the Core of each component being rendered by
+// RenderToString to the renderers rendering it, innermost last. The ancestry
+// and provided values of components are recorded by the renderer rather than
+// in their Core, which may be shared by concurrent renders.
+var (
+	serverRendersMu sync.Mutex
+	serverRenders   = make(map[*Core][]*stringRenderer)
+)
+
+// push pushes c onto the stack of components being rendered.
+func (s *stringRenderer) push(c Component) {
+	s.rendering = append(s.rendering, c)
+	s.provided = append(s.provided, nil)
+	serverRendersMu.Lock()
+	serverRenders[c.Context()] = append(serverRenders[c.Context()], s)
+	serverRendersMu.Unlock()
+}
+
+// popTo pops components from the stack of components being rendered, until
+// depth remain.
+func (s *stringRenderer) popTo(depth int) {
+	serverRendersMu.Lock()
+	for _, c := range s.rendering[depth:] {
+		renderers := serverRenders[c.Context()]
+		for i := len(renderers) - 1; i >= 0; i-- {
+			if renderers[i] == s {
+				renderers = append(renderers[:i], renderers[i+1:]...)
+				break
+			}
+		}
+		if len(renderers) == 0 {
+			delete(serverRenders, c.Context())
+		} else {
+			serverRenders[c.Context()] = renderers
+		}
+	}
+	serverRendersMu.Unlock()
+	s.rendering = s.rendering[:depth]
+	s.provided = s.provided[:depth]
+}
+
+// serverRenderer returns the renderer rendering the component whose Core is
+// c, and the component's index in its stack, or nil if there is none.
+func serverRenderer(c *Core) (*stringRenderer, int) {
+	serverRendersMu.Lock()
+	renderers := serverRenders[c]
+	serverRendersMu.Unlock()
+	if len(renderers) == 0 {
+		return nil, 0
+	}
+	s := renderers[len(renderers)-1]
+	for i := len(s.rendering) - 1; i >= 0; i-- {
+		if s.rendering[i].Context() == c {
+			return s, i
+		}
+	}
+	return nil, 0
+}
+
+// serverProvide implements Provide for a component being rendered by
+// RenderToString, reporting false if it is not.
+func serverProvide(c *Core, key, value interface{}) bool {
+	s, i := serverRenderer(c)
+	if s == nil {
+		return false
+	}
+	if s.provided[i] == nil {
+		s.provided[i] = make(map[interface{}]interface{})
+	}
+	s.provided[i][key] = value
+	return true
+}
+
+// serverConsume implements Consume for a component being rendered by
+// RenderToString, reporting false if it is not.
+func serverConsume(c *Core, key interface{}) (interface{}, bool) {
+	s, i := serverRenderer(c)
+	if s == nil {
+		return nil, false
+	}
+	for i--; i >= 0; i-- {
+		if value, ok := s.provided[i][key]; ok {
+			return value, true
+		}
+	}
+	return nil, true
+}
+
+// write writes s to the underlying writer, unless an error has already
+// occurred.
+func (s *stringRenderer) write(str ...string) {
+	for _, str := range str {
+		if s.err != nil {
+			return
+		}
+		_, s.err = io.WriteString(s.w, str)
+	}
+}
+
+// fail records err, unless an error has already occurred.
+func (s *stringRenderer) fail(msg string) {
+	if s.err == nil {
+		s.err = ServerRenderError{msg: msg}
+	}
+}
+
+// renderChildren renders a list of sibling children. Adjacent text nodes are
+// separated by an empty comment, such that a browser parses them into
+// distinct DOM nodes.
+func (s *stringRenderer) renderChildren(children []ComponentOrHTML) {
+	prevText := false
+	for _, child := range children {
+		prevText = s.renderSibling(child, prevText)
+	}
+}
+
+// renderSibling renders a single child, returning whether the last DOM node
+// it rendered was a text node.
+func (s *stringRenderer) renderSibling(child ComponentOrHTML, prevText bool) bool {
+	switch v := child.(type) {
+	case nil:
+		return prevText
+	case List:
+		for _, c := range v {
+			prevText = s.renderSibling(c, prevText)
+		}
+		return prevText
+	case KeyedList:
+		for _, c := range v.html.children {
+			prevText = s.renderSibling(c, prevText)
+		}
+		return prevText
+	case Component:
+		depth := len(s.rendering)
+		s.push(v)
+		r := v.Render()
+		if h, ok := r.(*HTML); r == nil || ok && h == nil {
+			// nil renders are translated into noscript tags.
+			r = Tag("noscript")
+		}
+		if _, ok := v.(ErrorBoundary); ok {
+			prevText = s.renderErrorBoundary(v, r, prevText)
+		} else {
+			prevText = s.renderSibling(r, prevText)
+		}
+		s.popTo(depth)
+		return prevText
+	case *HTML:
+		if v == nil {
+			return prevText
+		}
+		if v.tag == "" {
+			if v.text == "" {
+				// Empty text nodes do not exist in parsed HTML.
+				return prevText
+			}
+			if prevText {
+				s.write("<!---->")
+			}
+			s.renderHTML(v)
+			return true
+		}
+		s.renderHTML(v)
+		return false
+	default:
+		panic("vecty: internal error (unexpected ComponentOrHTML type " + reflect.TypeOf(child).String() + ")")
+	}
+}
+
+// renderChild renders a single child.
+func (s *stringRenderer) renderChild(child ComponentOrHTML) {
+	s.renderSibling(child, false)
+}
+
+// renderErrorBoundary renders r, as rendered by the ErrorBoundary c. The
+// output is buffered, such that if rendering panics, the fallback is rendered
+// in its place instead.
+func (s *stringRenderer) renderErrorBoundary(c Component, r ComponentOrHTML, prevText bool) bool {
+	var buf strings.Builder
+	depth := len(s.rendering)
+	sub := &stringRenderer{w: &buf, selectValue: s.selectValue, rendering: s.rendering, provided: s.provided}
+	nextText, err, panicked := prevText, RenderPanicError{}, false
+	func() {
+		defer func() {
+			if v := recover(); v != nil {
+				failing := sub.rendering[len(sub.rendering)-1]
+				err = RenderPanicError{Value: v, Component: failing, ComponentType: reflect.TypeOf(failing)}
+				panicked = true
+				sub.popTo(depth)
+			}
+		}()
+		nextText = sub.renderSibling(r, prevText)
+	}()
+	if panicked {
+		fallback := c.(ErrorBoundary).RenderError(err)
+		if h, ok := fallback.(*HTML); fallback == nil || ok && h == nil {
+			// nil renders are translated into noscript tags.
+			fallback = Tag("noscript")
+		}
+		return s.renderSibling(fallback, prevText)
+	}
+	s.write(buf.String())
+	if s.err == nil {
+		s.err = sub.err
+	}
+	return nextText
+}
+
+// renderHTML renders an element or text node.
+func (s *stringRenderer) renderHTML(h *HTML) {
+	switch {
+	case h.tag != "" && h.text != "":
+		panic("vecty: internal error (only one of HTML.tag or HTML.text may be set)")
+	case h.tag == "" && h.innerHTML != "":
+		s.fail("only HTML may have UnsafeHTML attribute")
+		return
+	case h.tag == "":
+		s.write(html.EscapeString(h.text))
+		return
+	}
+	h.tinyGoCannotIterateNilMaps()
+
+	attrs := h.serverAttributes()
+	var text string
+	hasText := false
+	for _, name := range []string{"textContent", "innerText"} {
+		if v, ok := h.properties[name]; ok {
+			// The text replaces the children of the element.
+			text, hasText = fmt.Sprint(v), true
+		}
+	}
+	if h.tag == "textarea" {
+		// The value of a textarea is its text content.
+		if v, ok := h.properties["value"]; ok {
+			text, hasText = fmt.Sprint(v), true
+		}
+	}
+	innerHTML := h.innerHTML
+	if v, ok := h.properties["innerHTML"]; ok && innerHTML == "" {
+		innerHTML = fmt.Sprint(v)
+	}
+	if h.tag == "option" && s.selectValue != nil {
+		value, ok := attrs["value"]
+		if !ok {
+			value = h.textContent()
+		}
+		if value == *s.selectValue {
+			attrs["selected"] = ""
+		}
+	}
+
+	s.write("<", h.tag)
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s.write(" ", name)
+		if v := attrs[name]; v != "" {
+			s.write(`="`, html.EscapeString(v), `"`)
+		}
+	}
+
+	if voidElements[h.tag] && h.namespace == "" {
+		s.write(">")
+		if len(h.children) > 0 || innerHTML != "" || hasText {
+			s.fail("void element " + h.tag + " may not have children")
+		}
+		return
+	}
+	if h.namespace != "" && len(h.children) == 0 && innerHTML == "" && !hasText {
+		s.write("/>")
+		return
+	}
+	s.write(">")
+
+	switch {
+	case innerHTML != "":
+		s.write(innerHTML)
+	case hasText:
+		s.write(html.EscapeString(text))
+	case rawTextElements[h.tag]:
+		content := h.textContent()
+		if strings.Contains(strings.ToLower(content), "</"+h.tag) {
+			s.fail("text content of " + h.tag + " element may not contain </" + h.tag)
+			return
+		}
+		s.write(content)
+	case h.tag == "select":
+		prevSelectValue := s.selectValue
+		if v, ok := h.properties["value"]; ok {
+			value := fmt.Sprint(v)
+			s.selectValue = &value
+		}
+		s.renderChildren(h.children)
+		s.selectValue = prevSelectValue
+	default:
+		s.renderChildren(h.children)
+	}
+	s.write("</", h.tag, ">")
+}
+
+// serverAttributes returns the HTML attributes equivalent to the attributes,
+// properties, classes, styles and dataset of the element. An empty value
+// represents a boolean attribute.
+func (h *HTML) serverAttributes() map[string]string {
+	attrs := make(map[string]string, len(h.attributes)+len(h.properties))
+	classes := make([]string, 0, len(h.classes))
+	for name := range h.classes {
+		classes = append(classes, name)
+	}
+
+	for name, value := range h.properties {
+		if h.tag == "textarea" && name == "value" || h.tag == "select" && name == "value" {
+			// Rendered as content, or as the selected option.
+			continue
+		}
+		attr, ok := propertyAttributes[name]
+		if !ok {
+			// Not reflected by an attribute, or rendered as content.
+			continue
+		}
+		switch v := value.(type) {
+		case bool:
+			if v {
+				attrs[attr] = ""
+			}
+		case string:
+			if attr == "class" {
+				classes = append(classes, strings.Fields(v)...)
+				continue
+			}
+			attrs[attr] = v
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			attrs[attr] = fmt.Sprint(v)
+		}
+	}
+	for name, value := range h.attributes {
+		attrs[name] = fmt.Sprint(value)
+	}
+
+	if len(classes) > 0 {
+		sort.Strings(classes)
+		attrs["class"] = strings.Join(classes, " ")
+	}
+	if len(h.styles) > 0 {
+		names := make([]string, 0, len(h.styles))
+		for name := range h.styles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		decls := make([]string, len(names))
+		for i, name := range names {
+			decls[i] = name + ": " + h.styles[name]
+		}
+		attrs["style"] = strings.Join(decls, "; ") + ";"
+	}
+	for name, value := range h.dataset {
+		attrs["data-"+datasetAttribute(name)] = value
+	}
+	return attrs
+}
+
+// textContent returns the concatenated text of the element's text node
+// children.
+func (h *HTML) textContent() string {
+	var b strings.Builder
+	for _, child := range h.children {
+		if c, ok := child.(*HTML); ok && c != nil && c.tag == "" {
+			b.WriteString(c.text)
+		}
+	}
+	return b.String()
+}
+
+// datasetAttribute converts a camel-cased dataset key into the dash-separated
+// form used by data-* attributes.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/API/HTMLElement/dataset#name_conversion
+func datasetAttribute(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('-')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,281 @@
+// +build !js
+
+package vecty
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRenderToString tests that RenderToString produces the expected HTML.
+func TestRenderToString(t *testing.T) {
+	cases := []struct {
+		name   string
+		render ComponentOrHTML
+		want   string
+	}{
+		{
+			name:   "element",
+			render: Tag("div"),
+			want:   `<div></div>`,
+		},
+		{
+			name:   "nil",
+			render: nil,
+			want:   `<noscript></noscript>`,
+		},
+		{
+			name:   "text_escaped",
+			render: Tag("p", Text(`<a href="x">&</a>`)),
+			want:   `<p>&lt;a href=&#34;x&#34;&gt;&amp;&lt;/a&gt;</p>`,
+		},
+		{
+			name:   "adjacent_text",
+			render: Tag("p", Text("a"), Text(""), Text("b"), Tag("br"), Text("c")),
+			want:   `<p>a<!---->b<br>c</p>`,
+		},
+		{
+			name: "markup",
+			render: Tag("div", Markup(
+				Attribute("role", "button"),
+				Attribute("aria-label", `say "hi"`),
+				Property("id", "x"),
+				Property("htmlFor", "y"),
+				Property("disabled", true),
+				Property("hidden", false),
+				Property("tabIndex", 3),
+				Class("b", "a"),
+				Style("color", "red"),
+				Style("background", "blue"),
+				Data("fooBar", "baz"),
+			)),
+			want: `<div aria-label="say &#34;hi&#34;" class="a b" data-foo-bar="baz" disabled for="y" id="x" role="button" style="background: blue; color: red;" tabindex="3"></div>`,
+		},
+		{
+			name:   "inner_html",
+			render: Tag("div", Markup(UnsafeHTML("<b>raw</b>"))),
+			want:   `<div><b>raw</b></div>`,
+		},
+		{
+			name:   "content_properties",
+			render: Tag("div", Tag("p", Markup(Property("textContent", "<a>"))), Tag("p", Markup(Property("innerHTML", "<b>raw</b>")))),
+			want:   `<div><p>&lt;a&gt;</p><p><b>raw</b></p></div>`,
+		},
+		{
+			name:   "unreflected_properties",
+			render: Tag("input", Markup(Property("defaultValue", "d"), Property("selectionStart", 1), Property("readOnly", true))),
+			want:   `<input readonly>`,
+		},
+		{
+			name:   "void",
+			render: Tag("div", Tag("input", Markup(Property("value", "v"), Property("checked", true)))),
+			want:   `<div><input checked value="v"></div>`,
+		},
+		{
+			name:   "textarea",
+			render: Tag("textarea", Markup(Property("value", "<x>"))),
+			want:   `<textarea>&lt;x&gt;</textarea>`,
+		},
+		{
+			name: "select",
+			render: Tag("select", Markup(Property("value", "b")),
+				Tag("option", Markup(Property("value", "a")), Text("A")),
+				Tag("option", Text("b")),
+			),
+			want: `<select><option value="a">A</option><option selected>b</option></select>`,
+		},
+		{
+			name:   "style_raw",
+			render: Tag("style", Text("a > b { color: red; }")),
+			want:   `<style>a > b { color: red; }</style>`,
+		},
+		{
+			name:   "namespace",
+			render: Tag("svg", Markup(Namespace("http://www.w3.org/2000/svg")), Tag("path", Markup(Namespace("http://www.w3.org/2000/svg"), Attribute("d", "M0")))),
+			want:   `<svg><path d="M0"/></svg>`,
+		},
+		{
+			name: "lists",
+			render: Tag("ul",
+				List{Tag("li", Text("1")), nil, Tag("li", Text("2"))},
+				List{Tag("li", Text("3"))}.WithKey("k"),
+			),
+			want: `<ul><li>1</li><li>2</li><li>3</li></ul>`,
+		},
+		{
+			name: "nested_components",
+			render: &componentFunc{render: func() ComponentOrHTML {
+				return Tag("body", &componentFunc{render: func() ComponentOrHTML {
+					return nil
+				}})
+			}},
+			want: `<body><noscript></noscript></body>`,
+		},
+		{
+			name: "fragments",
+			render: Tag("p",
+				Text("a"),
+				&componentFunc{render: func() ComponentOrHTML {
+					return List{Text("b"), Tag("br")}
+				}},
+				&componentFunc{render: func() ComponentOrHTML {
+					return List{}
+				}},
+				Text("c"),
+			),
+			want: `<p>a<!---->b<br>c</p>`,
+		},
+		{
+			name: "error_boundary",
+			render: Tag("div",
+				Text("a"),
+				&errorBoundary{render: func() ComponentOrHTML {
+					return List{Text("b"), &brokenComponent{broken: true}}
+				}},
+				&errorBoundary{render: func() ComponentOrHTML {
+					return &brokenComponent{}
+				}},
+			),
+			want: `<div>a<p>error</p><span>ok</span></div>`,
+		},
+	}
+	for _, tst := range cases {
+		t.Run(tst.name, func(t *testing.T) {
+			got, err := RenderToString(&componentFunc{render: func() ComponentOrHTML {
+				return tst.render
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tst.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tst.want)
+			}
+		})
+	}
+}
+
+// TestRenderToString_Context tests that values provided by an ancestor are
+// consumed by descendants when rendering to a string.
+func TestRenderToString_Context(t *testing.T) {
+	consumer := &componentFunc{}
+	consumer.render = func() ComponentOrHTML {
+		return Text(consumer.Consume(themeKey{}).(string))
+	}
+	provider := &componentFunc{}
+	provider.render = func() ComponentOrHTML {
+		provider.Provide(themeKey{}, "dark")
+		return Tag("p", &componentFunc{render: func() ComponentOrHTML { return consumer }})
+	}
+	got, err := RenderToString(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<p>dark</p>`; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+// TestRenderToString_Concurrent tests that RenderToString does not modify the
+// components it renders, such that the same components may be rendered by
+// concurrent calls, each with its own provided values.
+func TestRenderToString_Concurrent(t *testing.T) {
+	shared := &componentFunc{render: func() ComponentOrHTML { return Tag("hr") }}
+	render := func(theme string) (string, error) {
+		consumer := &componentFunc{}
+		consumer.render = func() ComponentOrHTML {
+			return Text(consumer.Consume(themeKey{}).(string))
+		}
+		provider := &componentFunc{}
+		provider.render = func() ComponentOrHTML {
+			provider.Provide(themeKey{}, theme)
+			return Tag("p", shared, consumer)
+		}
+		return RenderToString(provider)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		theme := strconv.Itoa(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := render(theme)
+			if want := "<p><hr>" + theme + "</p>"; err == nil && got != want {
+				err = fmt.Errorf("got %q want %q", got, want)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if shared.component != nil || shared.parent != nil || shared.provisions != nil {
+		t.Fatal("expected the Core of a rendered component to be left unmodified")
+	}
+	if len(serverRenders) != 0 {
+		t.Fatalf("got %d components still recorded as rendering, want none", len(serverRenders))
+	}
+}
+
+// TestRenderToString_Error tests that RenderToString returns an error for
+// markup which cannot be rendered.
+func TestRenderToString_Error(t *testing.T) {
+	cases := []struct {
+		name   string
+		render ComponentOrHTML
+		want   string
+	}{
+		{
+			name:   "text_inner_html",
+			render: Tag("div", Text("a", Markup(UnsafeHTML("b")))),
+			want:   "vecty: RenderToString: only HTML may have UnsafeHTML attribute",
+		},
+		{
+			name:   "raw_text_close",
+			render: Tag("script", Text("</script>")),
+			want:   "vecty: RenderToString: text content of script element may not contain </script",
+		},
+	}
+	for _, tst := range cases {
+		t.Run(tst.name, func(t *testing.T) {
+			_, err := RenderToString(&componentFunc{render: func() ComponentOrHTML {
+				return tst.render
+			}})
+			if _, ok := err.(ServerRenderError); !ok {
+				t.Fatalf("got error %T, want ServerRenderError", err)
+			}
+			if err.Error() != tst.want {
+				t.Fatalf("got error %q want %q", err, tst.want)
+			}
+		})
+	}
+}
+
+type errWriter struct{ n int }
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.n += len(p); w.n > 5 {
+		return 0, errors.New("write failed")
+	}
+	return len(p), nil
+}
+
+// TestRenderToWriter_WriteError tests that RenderToWriter returns the first
+// error produced by the writer.
+func TestRenderToWriter_WriteError(t *testing.T) {
+	err := RenderToWriter(&errWriter{}, &componentFunc{render: func() ComponentOrHTML {
+		return Tag("div", Text(strings.Repeat("x", 10)))
+	}})
+	if err == nil || err.Error() != "write failed" {
+		t.Fatalf("got error %v, want write failed", err)
+	}
+}
@@ -0,0 +1,97 @@
+package vecty
+
+import "strings"
+
+// StylesElementID is the ID of the <style> element managed by AddStyles.
+const StylesElementID = "vecty-styles"
+
+// styles is the <style> element managed by AddStyles, and addedStyles the set
+// of CSS rules it contains, as well as of the CSS passed to AddStyles.
+var (
+	styles      jsObject
+	addedStyles map[string]bool
+)
+
+// AddStyles adds CSS to a <style> element in the head of the document which is
+// managed by Vecty, except for the rules it contains which were already added.
+// It is used by the css package to inject the rules it generates.
+//
+// If the document already contains a <style> element with the ID
+// StylesElementID, e.g. as rendered by a server, the CSS is added to it, and
+// the rules it already contains are not added again.
+func AddStyles(css string) {
+	if addedStyles[css] {
+		return
+	}
+	document := global().Get("document")
+	if styles == nil {
+		addedStyles = make(map[string]bool)
+		styles = document.Call("getElementById", StylesElementID)
+		if styles != nil {
+			for _, rule := range splitRules(styles.Get("textContent").String()) {
+				addedStyles[rule] = true
+			}
+		} else {
+			styles = document.Call("createElement", "style")
+			styles.Set("id", StylesElementID)
+			document.Get("head").Call("appendChild", styles)
+		}
+	}
+	var added strings.Builder
+	for _, rule := range splitRules(css) {
+		if !addedStyles[rule] {
+			addedStyles[rule] = true
+			added.WriteString(rule)
+		}
+	}
+	addedStyles[css] = true
+	if added.Len() > 0 {
+		styles.Call("appendChild", document.Call("createTextNode", added.String()))
+	}
+}
+
+// splitRules splits CSS into its top-level rules, e.g. ".a{color:red}" and
+// "@media print{.a{color:black}}", without surrounding whitespace.
+func splitRules(css string) []string {
+	var (
+		rules []string
+		start int
+		depth int
+		quote byte
+	)
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && strings.HasPrefix(css[i:], "/*"):
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				i = len(css)
+				break
+			}
+			i += end + 3
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		}
+		if depth == 0 && (c == '}' || c == ';') {
+			if rule := strings.TrimSpace(css[start : i+1]); rule != "" && rule != ";" {
+				rules = append(rules, rule)
+			}
+			start = i + 1
+		}
+	}
+	if rule := strings.TrimSpace(css[start:]); rule != "" {
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -0,0 +1,56 @@
+// +build !js
+
+package vecty
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestAddStyles tests that AddStyles adds each rule to the managed <style>
+// element only once.
+func TestAddStyles(t *testing.T) {
+	ResetDOM()
+	doc := global().Get("document")
+	server := doc.Call("createElement", "style")
+	server.Set("id", StylesElementID)
+	server.Set("textContent", ".a{color:red}\n@media print{.c{color:black}}")
+	doc.Get("head").Call("appendChild", server)
+
+	AddStyles(".a{color:red}")
+	AddStyles(".b{color:blue}")
+	AddStyles(".b{color:blue}")
+	// Rules are added unless the same rule was added, even if they are part
+	// of other rules.
+	AddStyles(`.c{color:black}.d{content:"}"}.a{color:red}`)
+	want := ".a{color:red}\n@media print{.c{color:black}}" + ".b{color:blue}" + `.c{color:black}.d{content:"}"}`
+	if got := server.Get("textContent").String(); got != want {
+		t.Fatalf("got  %q\nwant %q", got, want)
+	}
+	if got := doc.Call("querySelectorAll", "style").Get("length").Int(); got != 1 {
+		t.Fatalf("got %d style elements, want 1", got)
+	}
+}
+
+// TestSplitRules tests that CSS is split into its top-level rules.
+func TestSplitRules(t *testing.T) {
+	cases := []struct {
+		css  string
+		want []string
+	}{
+		{css: "", want: nil},
+		{css: ".a{color:red}", want: []string{".a{color:red}"}},
+		{css: " .a{color:red}\n.b{color:blue} ", want: []string{".a{color:red}", ".b{color:blue}"}},
+		{css: "@media print{.a{color:red}}.a{color:red}", want: []string{"@media print{.a{color:red}}", ".a{color:red}"}},
+		{css: `@import "a.css";.a{content:"}{;"}`, want: []string{`@import "a.css";`, `.a{content:"}{;"}`}},
+		{css: `.a{content:'\'}'}/* } */.b{}`, want: []string{`.a{content:'\'}'}`, "/* } */.b{}"}},
+	}
+	for _, tst := range cases {
+		t.Run(tst.css, func(t *testing.T) {
+			got := splitRules(tst.css)
+			if !reflect.DeepEqual(got, tst.want) {
+				t.Fatalf("got %q want %q", got, tst.want)
+			}
+		})
+	}
+}
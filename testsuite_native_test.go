@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os/exec"
 	"reflect"
+	"testing"
 )
 
 func commandOutput(command string, args ...string) (string, error) {
@@ -19,7 +20,10 @@ func init() {
 		if h.node == nil {
 			panic("vecty: cannot call (*HTML).Node() before DOM node creation / component mount")
 		}
-		return h.node.(wrappedObject).j
+		if w, ok := h.node.(wrappedObject); ok {
+			return w.j
+		}
+		return h.node
 	}
 	funcOfImpl = func(fn func(this jsObject, args []jsObject) interface{}) jsFunc {
 		return &jsFuncImpl{
@@ -27,7 +31,11 @@ func init() {
 		}
 	}
 	valueOfImpl = func(v interface{}) jsObject {
-		ts := global().(*objectRecorder).ts
+		recorder, ok := global().(*objectRecorder)
+		if !ok {
+			return memValueOf(v)
+		}
+		ts := recorder.ts
 		name := fmt.Sprintf("valueOf(%v)", v)
 		r := &objectRecorder{ts: ts, name: name}
 		switch reflect.ValueOf(v).Kind() {
@@ -44,3 +52,30 @@ func init() {
 		return r
 	}
 }
+
+// memBody returns the body of the in-memory document.
+func memBody() jsObject {
+	return global().Get("document").Get("body")
+}
+
+// renderBody renders c into the body of a fresh in-memory DOM.
+func renderBody(c Component) {
+	ResetDOM()
+	RenderBody(c)
+}
+
+// checkHTML fails the test unless the inner HTML of node is want.
+func checkHTML(t testing.TB, node jsObject, want string) {
+	t.Helper()
+	if got := node.Get("innerHTML").String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// rerenderHTML re-renders c synchronously, and fails the test unless the inner
+// HTML of the body is then want.
+func rerenderHTML(t testing.TB, c Component, want string) {
+	t.Helper()
+	RerenderSync(c)
+	checkHTML(t, memBody(), want)
+}
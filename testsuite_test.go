@@ -32,8 +32,13 @@ type componentFunc struct {
 	skipRender func(prev Component) bool
 }
 
-func (c *componentFunc) Render() ComponentOrHTML        { return c.render() }
-func (c *componentFunc) SkipRender(prev Component) bool { return c.skipRender(prev) }
+func (c *componentFunc) Render() ComponentOrHTML { return c.render() }
+func (c *componentFunc) SkipRender(prev Component) bool {
+	if c.skipRender == nil {
+		return false
+	}
+	return c.skipRender(prev)
+}
 
 func TestMain(m *testing.M) {
 	// Try to remove all testdata/*.got.txt files now.
@@ -60,9 +65,27 @@ func testSuite(t *testing.T) *testSuiteT {
 		ts:   ts,
 		name: "global",
 	}
+	scheduler = frameScheduler{}
+	events = &delegator{}
+	// Development mode diagnostics use browser APIs which are not recorded.
+	devMode = nil
+	devtools = nil
+	styles, addedStyles = nil, nil
+	headResources = nil
 	return ts
 }
 
+// frameScheduler is a Scheduler which performs all work on the next animation
+// frame, with a fixed 60fps budget, such that recorded invocations do not
+// depend on the default Scheduler's use of other browser APIs.
+type frameScheduler struct{}
+
+func (frameScheduler) Schedule(p Priority, work func(d Deadline)) {
+	requestAnimationFrame(func(timestamp float64) {
+		work(frameDeadline{start: timestamp, budget: 1000 / 60})
+	})
+}
+
 // mockedValue represents a mocked value.
 type mockedValue struct {
 	invocation string
@@ -0,0 +1,69 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"testing"
+)
+
+// updateComponent records its update lifecycle events.
+type updateComponent struct {
+	Core
+	Value  string `vecty:"prop"`
+	events *[]string
+}
+
+func (c *updateComponent) Render() ComponentOrHTML {
+	*c.events = append(*c.events, "render "+c.Value)
+	return Tag("p", Markup(Property("id", "value")), Text(c.Value))
+}
+
+func (c *updateComponent) BeforeUpdate(prev Component) {
+	*c.events = append(*c.events, "before "+prev.(*updateComponent).Value+" -> "+c.Value)
+}
+
+func (c *updateComponent) AfterUpdate() {
+	text := global().Get("document").Call("getElementById", "value").Get("textContent").String()
+	*c.events = append(*c.events, "after "+text)
+}
+
+func (c *updateComponent) SkipRender(prev Component) bool {
+	return c.Value == "skip"
+}
+
+// TestUpdateHooks tests that BeforeUpdate and AfterUpdate are called
+// around re-renders, but not the initial render or skipped renders.
+func TestUpdateHooks(t *testing.T) {
+	var (
+		got   []string
+		value = "a"
+		child *updateComponent
+	)
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		child = &updateComponent{Value: value, events: &got}
+		return Tag("body", child)
+	}}
+	renderBody(comp)
+	want := []string{"render a"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	// Updated by its parent, with the new properties.
+	got = nil
+	value = "b"
+	RerenderSync(comp)
+	want = []string{"before a -> b", "render b", "after b"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	// Skipped renders are not updates.
+	got = nil
+	value = "skip"
+	RerenderSync(comp)
+	if len(got) != 0 {
+		t.Fatalf("got %q want none", got)
+	}
+}
@@ -0,0 +1,265 @@
+// +build !js
+
+// Package vectytest provides utilities for unit testing Vecty components
+// under plain 'go test', using Vecty's in-memory DOM.
+//
+// A typical test mounts a component, simulates user interaction and asserts on
+// the rendered result:
+//
+// 	func TestCounter(t *testing.T) {
+// 		s := vectytest.Mount(&Counter{})
+// 		s.Query("button").Click()
+// 		if got := s.Query(".count").Text(); got != "1" {
+// 			t.Fatalf("got count %q want %q", got, "1")
+// 		}
+// 	}
+//
+// Simulated events are dispatched to the DOM, such that they invoke the
+// component's vecty.EventListener handlers, and any resulting calls to
+// vecty.Rerender are then flushed synchronously with vecty.Flush.
+package vectytest
+
+import (
+	"strings"
+
+	"github.com/hexops/vecty"
+)
+
+// root is the component rendered by Mount, which renders the mounted
+// component as the only child of the document body.
+type root struct {
+	vecty.Core
+	child vecty.Component
+	body  *vecty.HTML
+}
+
+// Render implements the vecty.Component interface.
+func (r *root) Render() vecty.ComponentOrHTML {
+	r.body = vecty.Tag("body", r.child)
+	return r.body
+}
+
+// Screen is a component mounted into the in-memory DOM by Mount.
+type Screen struct {
+	// Element is the document body, which the component is rendered into.
+	*Element
+	root *vecty.Root
+}
+
+// Mount discards the current in-memory DOM, and renders the component into
+// the body of a new document with a vecty.Root. Mount also runs the
+// component's Mounter, if it implements it.
+func Mount(c vecty.Component) *Screen {
+	vecty.ResetDOM()
+	r, err := vecty.NewRoot("body")
+	if err != nil {
+		panic(err)
+	}
+	b := &root{child: c}
+	if err := r.Render(b); err != nil {
+		panic(err)
+	}
+	vecty.Flush()
+	return &Screen{Element: &Element{node: b.body.Node()}, root: r}
+}
+
+// Unmount unmounts the mounted component with vecty.Root.Unmount, running any
+// Unmounter implemented by it or its descendants, and removing its event
+// listeners and head resources. The screen's element is then the empty body
+// of the document.
+func (s *Screen) Unmount() {
+	s.root.Unmount()
+	s.Element = &Element{node: s.node.Get("ownerDocument").Get("body")}
+}
+
+// Element is an element in the in-memory DOM.
+type Element struct {
+	node vecty.SyscallJSValue
+}
+
+// newElement returns the element for node, or nil if node is null.
+func newElement(node vecty.SyscallJSValue) *Element {
+	if node == nil || node.IsUndefined() {
+		return nil
+	}
+	return &Element{node: node}
+}
+
+// Node returns the underlying in-memory DOM node.
+func (e *Element) Node() vecty.SyscallJSValue {
+	return e.node
+}
+
+// Tag returns the lower-case tag name of the element.
+func (e *Element) Tag() string {
+	return e.node.Get("localName").String()
+}
+
+// Text returns the text content of the element and its descendants.
+func (e *Element) Text() string {
+	return e.node.Get("textContent").String()
+}
+
+// HTML returns the HTML serialization of the element and its descendants.
+func (e *Element) HTML() string {
+	return e.node.Get("outerHTML").String()
+}
+
+// Attribute returns the named attribute of the element, and whether it
+// exists.
+func (e *Element) Attribute(name string) (string, bool) {
+	v := e.node.Call("getAttribute", name)
+	if v == nil {
+		return "", false
+	}
+	return v.String(), true
+}
+
+// Property returns the named JavaScript property of the element.
+func (e *Element) Property(name string) vecty.SyscallJSValue {
+	return e.node.Get(name)
+}
+
+// Value returns the value property of a form element.
+func (e *Element) Value() string {
+	return e.node.Get("value").String()
+}
+
+// Checked returns the checked property of a checkbox or radio button.
+func (e *Element) Checked() bool {
+	return e.node.Get("checked").Truthy()
+}
+
+// HasClass reports whether the element has the named class.
+func (e *Element) HasClass(name string) bool {
+	return e.node.Get("classList").Call("contains", name).Bool()
+}
+
+// Focused reports whether the element is the document's active element.
+func (e *Element) Focused() bool {
+	return e.node.Get("ownerDocument").Get("activeElement").Equal(e.node)
+}
+
+// Query returns the first descendant element matching the CSS selector, or
+// nil if there is none.
+func (e *Element) Query(selector string) *Element {
+	return newElement(e.node.Call("querySelector", selector))
+}
+
+// QueryAll returns all descendant elements matching the CSS selector, in
+// document order.
+func (e *Element) QueryAll(selector string) []*Element {
+	list := e.node.Call("querySelectorAll", selector)
+	elems := make([]*Element, list.Get("length").Int())
+	for i := range elems {
+		elems[i] = &Element{node: list.Call("item", i)}
+	}
+	return elems
+}
+
+// ByTag returns all descendant elements with the given tag name.
+func (e *Element) ByTag(tag string) []*Element {
+	return e.QueryAll(tag)
+}
+
+// ByClass returns all descendant elements with the given class.
+func (e *Element) ByClass(class string) []*Element {
+	return e.QueryAll("." + class)
+}
+
+// ByText returns the descendant elements whose text content, ignoring leading
+// and trailing whitespace, equals text. Only the innermost matching elements
+// are returned, e.g. for <p><b>x</b></p> only the b element matches "x".
+func (e *Element) ByText(text string) []*Element {
+	var matches []*Element
+	for _, elem := range e.QueryAll("*") {
+		if strings.TrimSpace(elem.Text()) != text {
+			continue
+		}
+		// Replace an ancestor match with this innermost match.
+		if n := len(matches); n > 0 && matches[n-1].node.Call("contains", elem.node).Bool() {
+			matches[n-1] = elem
+			continue
+		}
+		matches = append(matches, elem)
+	}
+	return matches
+}
+
+// Dispatch dispatches an event of the given type to the element, with the
+// given additional event properties (e.g. "key"), and then flushes any
+// resulting re-renders. It returns false if the event was cancelable and an
+// event listener prevented its default action.
+func (e *Element) Dispatch(typ string, bubbles, cancelable bool, props map[string]interface{}) bool {
+	ev := e.node.Get("ownerDocument").Call("createEvent", "Event")
+	ev.Call("initEvent", typ, bubbles, cancelable)
+	for name, value := range props {
+		ev.Set(name, value)
+	}
+	notPrevented := e.node.Call("dispatchEvent", ev).Bool()
+	vecty.Flush()
+	return notPrevented
+}
+
+// Click simulates a mouse click on the element, toggling checkboxes and radio
+// buttons as a browser would, and then flushes any resulting re-renders.
+func (e *Element) Click() {
+	e.node.Call("click")
+	vecty.Flush()
+}
+
+// DoubleClick simulates a mouse double click on the element, and then flushes
+// any resulting re-renders.
+func (e *Element) DoubleClick() {
+	e.Dispatch("dblclick", true, true, nil)
+}
+
+// Input sets the value of a form element, as if the user typed it, and
+// dispatches an input event. Any resulting re-renders are flushed.
+func (e *Element) Input(value string) {
+	e.node.Set("value", value)
+	e.Dispatch("input", true, false, nil)
+}
+
+// Change sets the value of a form element and dispatches a change event, as
+// when the user commits a new value. Any resulting re-renders are flushed.
+func (e *Element) Change(value string) {
+	e.node.Set("value", value)
+	e.Dispatch("change", true, false, nil)
+}
+
+// KeyDown dispatches a keydown event for the given key (e.g. "Enter", see
+// https://developer.mozilla.org/en-US/docs/Web/API/KeyboardEvent/key), and
+// then flushes any resulting re-renders. It returns false if an event listener
+// prevented its default action.
+func (e *Element) KeyDown(key string) bool {
+	return e.Dispatch("keydown", true, true, map[string]interface{}{"key": key})
+}
+
+// KeyUp dispatches a keyup event for the given key, and then flushes any
+// resulting re-renders. It returns false if an event listener prevented its
+// default action.
+func (e *Element) KeyUp(key string) bool {
+	return e.Dispatch("keyup", true, true, map[string]interface{}{"key": key})
+}
+
+// Submit dispatches a submit event to a form element, and then flushes any
+// resulting re-renders. It returns false if an event listener prevented its
+// default action.
+func (e *Element) Submit() bool {
+	return e.Dispatch("submit", true, true, nil)
+}
+
+// Focus focuses the element, dispatching focus events, and then flushes any
+// resulting re-renders.
+func (e *Element) Focus() {
+	e.node.Call("focus")
+	vecty.Flush()
+}
+
+// Blur removes focus from the element, dispatching blur events, and then
+// flushes any resulting re-renders.
+func (e *Element) Blur() {
+	e.node.Call("blur")
+	vecty.Flush()
+}
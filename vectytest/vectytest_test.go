@@ -0,0 +1,151 @@
+// +build !js
+
+package vectytest
+
+import (
+	"testing"
+
+	"github.com/hexops/vecty"
+)
+
+type counter struct {
+	vecty.Core
+	count     int
+	mounted   bool
+	unmounted bool
+}
+
+func (c *counter) Mount()   { c.mounted = true }
+func (c *counter) Unmount() { c.unmounted = true }
+
+func (c *counter) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("div",
+		vecty.Tag("span", vecty.Markup(vecty.Class("count")), vecty.Text(itoa(c.count))),
+		vecty.Tag("button",
+			vecty.Markup(&vecty.EventListener{Name: "click", Listener: func(*vecty.Event) {
+				c.count++
+				vecty.Rerender(c)
+			}}),
+			vecty.Text("Increment"),
+		),
+	)
+}
+
+func itoa(i int) string {
+	return string(rune('0' + i))
+}
+
+func TestMount(t *testing.T) {
+	c := &counter{}
+	s := Mount(c)
+	if !c.mounted {
+		t.Fatal("expected component to be mounted")
+	}
+	want := `<body><div><span class="count">0</span><button>Increment</button></div></body>`
+	if got := s.HTML(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	s.ByText("Increment")[0].Click()
+	s.Query("button").Click()
+	if got := s.Query(".count").Text(); got != "2" {
+		t.Fatalf("got count %q want %q", got, "2")
+	}
+
+	s.Unmount()
+	if !c.unmounted {
+		t.Fatal("expected component to be unmounted")
+	}
+	if got := s.HTML(); got != "<body></body>" {
+		t.Fatalf("got %s after unmount, want an empty body", got)
+	}
+}
+
+type form struct {
+	vecty.Core
+	value     string
+	submitted []string
+}
+
+func (f *form) Render() vecty.ComponentOrHTML {
+	return vecty.Tag("form",
+		vecty.Markup((&vecty.EventListener{Name: "submit", Listener: func(*vecty.Event) {
+			f.submitted = append(f.submitted, f.value)
+			f.value = ""
+			vecty.Rerender(f)
+		}}).PreventDefault()),
+		vecty.Tag("input", vecty.Markup(
+			vecty.Property("value", f.value),
+			&vecty.EventListener{Name: "input", Listener: func(e *vecty.Event) {
+				f.value = e.Target.Get("value").String()
+				vecty.Rerender(f)
+			}},
+			&vecty.EventListener{Name: "keydown", Listener: func(e *vecty.Event) {
+				if e.Value.Get("key").String() == "Escape" {
+					f.value = ""
+					vecty.Rerender(f)
+				}
+			}},
+		)),
+		vecty.Tag("p", vecty.Markup(vecty.Class("preview")), vecty.Text(f.value)),
+	)
+}
+
+func TestElement_Events(t *testing.T) {
+	f := &form{}
+	s := Mount(f)
+	input := s.Query("input")
+
+	input.Input("hello")
+	if got := s.Query(".preview").Text(); got != "hello" {
+		t.Fatalf("got preview %q want %q", got, "hello")
+	}
+	input.KeyDown("Escape")
+	if got := input.Value(); got != "" {
+		t.Fatalf("got value %q want empty", got)
+	}
+
+	input.Input("world")
+	if s.Query("form").Submit() {
+		t.Fatal("expected submit default action to be prevented")
+	}
+	if len(f.submitted) != 1 || f.submitted[0] != "world" {
+		t.Fatalf("got submitted %q want [world]", f.submitted)
+	}
+
+	input.Focus()
+	if !input.Focused() {
+		t.Fatal("expected input to be focused")
+	}
+	input.Blur()
+	if input.Focused() {
+		t.Fatal("expected input not to be focused")
+	}
+}
+
+func TestElement_ByText(t *testing.T) {
+	s := Mount(&componentFunc{render: func() vecty.ComponentOrHTML {
+		return vecty.Tag("div",
+			vecty.Tag("p", vecty.Tag("b", vecty.Text(" x "))),
+			vecty.Tag("p", vecty.Text("x")),
+			vecty.Tag("p", vecty.Text("y")),
+		)
+	}})
+	got := s.ByText("x")
+	if len(got) != 2 || got[0].Tag() != "b" || got[1].Tag() != "p" {
+		t.Fatalf("got %d elements, want <b> and <p>", len(got))
+	}
+	if got := s.ByTag("p"); len(got) != 3 {
+		t.Fatalf("got %d <p> elements, want 3", len(got))
+	}
+	if got := s.Query("ul"); got != nil {
+		t.Fatal("expected nil for no match")
+	}
+}
+
+type componentFunc struct {
+	vecty.Core
+	render func() vecty.ComponentOrHTML
+}
+
+func (c *componentFunc) Render() vecty.ComponentOrHTML { return c.render() }
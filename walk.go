@@ -0,0 +1,179 @@
+package vecty
+
+import "sort"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If the
+// result visitor w is not nil, Walk visits each of the children of node with
+// the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node ComponentOrHTML) (w Visitor)
+}
+
+// Walk traverses a tree of components and HTML in depth-first order: it starts
+// by calling v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk is invoked recursively with visitor w for each
+// of the non-nil children of node, followed by a call of w.Visit(nil).
+//
+// The children of a Component are what it rendered last, i.e. a single
+// Component, *HTML or KeyedList, such that walking a rendered Component visits
+// the Components it rendered and the HTML they produced. A Component which has
+// not been rendered, such as an instance created by a parent's Render method
+// whose properties were copied into the instance persisted across renders, has
+// no children.
+//
+// The children of an *HTML are its child Components, *HTML and Lists, or for
+// an *HTML created by Portal, the content rendered into its target. The
+// children of a List or KeyedList are its elements.
+//
+// Walk does not modify the tree, and visitors must not modify it either.
+func Walk(v Visitor, node ComponentOrHTML) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range walkChildren(node) {
+		if child != nil {
+			Walk(v, child)
+		}
+	}
+	v.Visit(nil)
+}
+
+// inspector implements Visitor for Inspect.
+type inspector func(ComponentOrHTML) bool
+
+func (f inspector) Visit(node ComponentOrHTML) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a tree of components and HTML in depth-first order, as
+// with Walk: it starts by calling f(node); node must not be nil. If f returns
+// true, Inspect invokes f recursively for each of the non-nil children of
+// node, followed by a call of f(nil).
+func Inspect(node ComponentOrHTML, f func(ComponentOrHTML) bool) {
+	Walk(inspector(f), node)
+}
+
+// walkChildren returns the children of node, as described by Walk.
+func walkChildren(node ComponentOrHTML) []ComponentOrHTML {
+	switch v := node.(type) {
+	case Component:
+		if r := v.Context().prevRender; r != nil {
+			return []ComponentOrHTML{r}
+		}
+	case *HTML:
+		if v == nil {
+			return nil
+		}
+		if v.portal != nil {
+			return []ComponentOrHTML{v.portal.content}
+		}
+		return v.children
+	case List:
+		return v
+	case KeyedList:
+		if v.html != nil {
+			return v.html.children
+		}
+	}
+	return nil
+}
+
+// Tag returns the tag name of the element, e.g. "div", or the empty string if
+// h is a text node.
+func (h *HTML) Tag() string {
+	return h.tag
+}
+
+// Namespace returns the namespace URI of the element, as set by Namespace,
+// or the empty string if it has the default HTML namespace.
+func (h *HTML) Namespace() string {
+	return h.namespace
+}
+
+// Text returns the text content of a text node, as created by Text, or the
+// empty string if h is an element.
+func (h *HTML) Text() string {
+	return h.text
+}
+
+// InnerHTML returns the HTML set by UnsafeHTML, if any.
+func (h *HTML) InnerHTML() string {
+	return h.innerHTML
+}
+
+// Attributes returns a copy of the attributes of the element, as set by
+// Attribute.
+func (h *HTML) Attributes() map[string]interface{} {
+	return copyValues(h.attributes)
+}
+
+// Properties returns a copy of the properties of the element, as set by
+// Property.
+func (h *HTML) Properties() map[string]interface{} {
+	return copyValues(h.properties)
+}
+
+// Classes returns the classes of the element, as set by Class and ClassMap, in
+// sorted order.
+func (h *HTML) Classes() []string {
+	classes := make([]string, 0, len(h.classes))
+	for class := range h.classes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// Styles returns a copy of the styles of the element, as set by Style.
+func (h *HTML) Styles() map[string]string {
+	return copyStrings(h.styles)
+}
+
+// Dataset returns a copy of the data attributes of the element, as set by
+// Data.
+func (h *HTML) Dataset() map[string]string {
+	return copyStrings(h.dataset)
+}
+
+// EventTypes returns the event types of the EventListeners of the element, in
+// the order they were applied.
+func (h *HTML) EventTypes() []string {
+	types := make([]string, len(h.eventListeners))
+	for i, l := range h.eventListeners {
+		types[i] = l.Name
+	}
+	return types
+}
+
+// Children returns a copy of the children of the element. See Walk for
+// traversing them.
+func (h *HTML) Children() []ComponentOrHTML {
+	return append([]ComponentOrHTML(nil), h.children...)
+}
+
+// copyValues returns a copy of m, or nil if m is empty.
+func copyValues(m map[string]interface{}) map[string]interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	cpy := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		cpy[k] = v
+	}
+	return cpy
+}
+
+// copyStrings returns a copy of m, or nil if m is empty.
+func copyStrings(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	cpy := make(map[string]string, len(m))
+	for k, v := range m {
+		cpy[k] = v
+	}
+	return cpy
+}
@@ -0,0 +1,94 @@
+// +build !js
+
+package vecty
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestWalk tests that Inspect visits a rendered tree of components and HTML in
+// depth-first order, and that the read-only accessors of HTML return copies of
+// the rendered markup.
+func TestWalk(t *testing.T) {
+	ResetDOM()
+	comp := &componentFunc{render: func() ComponentOrHTML {
+		return Tag("body",
+			Markup(Class("b", "a"), Style("color", "red"), Data("x", "1"), Attribute("lang", "en"), Property("id", "app")),
+			Tag("main", List{
+				&memoComponent{Name: "one"},
+				Tag("span", Text("two")),
+			}.WithKey("list")),
+			Tag("input", Markup(&EventListener{Name: "input", Listener: func(*Event) {}})),
+		)
+	}}
+	RenderBody(comp)
+
+	var got []string
+	depth := 0
+	Inspect(comp, func(n ComponentOrHTML) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+		s := strings.Repeat(" ", depth)
+		switch n := n.(type) {
+		case Component:
+			s += componentName(n)
+		case KeyedList:
+			s += "KeyedList " + fmt.Sprint(n.Key())
+		case *HTML:
+			if n.Tag() == "" {
+				s += strconv.Quote(n.Text())
+			} else {
+				s += "<" + n.Tag() + ">"
+			}
+		}
+		got = append(got, s)
+		depth++
+		return true
+	})
+	want := []string{
+		`vecty.componentFunc`,
+		` <body>`,
+		`  <main>`,
+		`   KeyedList list`,
+		`    vecty.memoComponent`,
+		`     <p>`,
+		`      "one"`,
+		`    <span>`,
+		`     "two"`,
+		`  <input>`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	body := comp.Context().prevRender.(*HTML)
+	if got := fmt.Sprint(body.Classes()); got != "[a b]" {
+		t.Fatalf("got classes %s", got)
+	}
+	attrs := body.Attributes()
+	if fmt.Sprint(attrs, body.Properties(), body.Styles(), body.Dataset()) != "map[lang:en] map[id:app] map[color:red] map[x:1]" {
+		t.Fatal("unexpected markup", attrs, body.Properties(), body.Styles(), body.Dataset())
+	}
+	attrs["lang"] = "fr"
+	children := body.Children()
+	children[0] = nil
+	if body.Attributes()["lang"] != "en" || body.Children()[0] == nil {
+		t.Fatal("accessors returned shared state")
+	}
+	input := children[1].(*HTML)
+	if fmt.Sprint(input.EventTypes()) != "[input]" || input.Attributes() != nil {
+		t.Fatal("unexpected input", input.EventTypes(), input.Attributes())
+	}
+
+	// Walking an unrendered component visits only the component.
+	n := 0
+	Inspect(&memoComponent{}, func(ComponentOrHTML) bool { n++; return true })
+	if n != 2 {
+		t.Fatalf("got %d visits, want 2", n)
+	}
+}